@@ -0,0 +1,264 @@
+package wpaclient
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// HashPrefix pins the server certificate by hash instead of a CA,
+// ca_cert set to HashPrefix+CertHash(cert) trusts just that certificate
+const HashPrefix = "hash://server/sha256/"
+
+// ErrBlobTooLarge returned when a blob does not fit in a control interface request
+var ErrBlobTooLarge = constError("blob exceeds control interface request size")
+
+// maxRequest is the request buffer size of wpa_supplicant control interface,
+// CTRL_IFACE_MAX_LEN in ctrl_iface_unix.c, longer requests are truncated
+const maxRequest = 8192
+
+// SetBlob executes "SET blob <name> <hex>" command, data is kept in memory
+// and can be used as certificate or key with BlobPrefix+name. Hex encoded
+// data has to fit in a request, ErrBlobTooLarge is returned otherwise.
+func (c *Client) SetBlob(name string, data []byte) error {
+	if name == "" || strings.ContainsAny(name, " \t\n") {
+		return fmt.Errorf("invalid blob name %q", name)
+	}
+
+	n := len(CmdSet+" blob "+name+" ") + hex.EncodedLen(len(data))
+	if c.ifname != "" {
+		n += len(CmdIfname + "=" + c.ifname + " ")
+	}
+	if n > maxRequest {
+		return fmt.Errorf("%w: %s is %d bytes, request limit is %d", ErrBlobTooLarge, name, n, maxRequest)
+	}
+
+	_, err := c.Execute(CmdSet, "blob", name, hex.EncodeToString(data))
+	return err
+}
+
+// RemoveBlob replaces blob name with an empty one,
+// control interface has no command to delete a blob
+func (c *Client) RemoveBlob(name string) error {
+	return c.SetBlob(name, nil)
+}
+
+// SetCertBlob sets certs as blob name, see SetBlob. A single certificate is
+// set in DER format, which is half the size of PEM, a chain in PEM format.
+func (c *Client) SetCertBlob(name string, certs ...*x509.Certificate) error {
+	if len(certs) == 0 {
+		return errors.New("no certificate")
+	}
+	if len(certs) == 1 {
+		return c.SetBlob(name, certs[0].Raw)
+	}
+
+	b := []byte{}
+	for _, cert := range certs {
+		b = append(b, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})...)
+	}
+
+	return c.SetBlob(name, b)
+}
+
+// ParseCertificates parses PEM encoded certificates, or a single DER encoded one
+func ParseCertificates(b []byte) ([]*x509.Certificate, error) {
+	certs := []*x509.Certificate{}
+	for rest := b; ; {
+		var blk *pem.Block
+		if blk, rest = pem.Decode(rest); blk == nil {
+			break
+		}
+		if blk.Type != "CERTIFICATE" {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(blk.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+
+	if len(certs) > 0 {
+		return certs, nil
+	}
+
+	cert, err := x509.ParseCertificate(b)
+	if err != nil {
+		return nil, err
+	}
+
+	return []*x509.Certificate{cert}, nil
+}
+
+// CertHash returns SHA256 hash of cert in hex, as reported in peer certificate events
+func CertHash(cert *x509.Certificate) string {
+	h := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(h[:])
+}
+
+// PeerCert represents "CTRL-EVENT-EAP-PEER-CERT" event, the certificate
+// of the authentication server at Depth 0 and its CAs above
+type PeerCert struct {
+	Depth   int
+	Subject string
+	// AltSubject is filled from "CTRL-EVENT-EAP-PEER-ALT" events, see AddAlt
+	AltSubject []string
+	Hash       string
+	// Cert is reported if cert_in_cb is enabled, nil otherwise
+	Cert *x509.Certificate
+}
+
+// ParsePeerCert parses a "CTRL-EVENT-EAP-PEER-CERT" event
+func ParsePeerCert(ev Event) (*PeerCert, error) {
+	ps, err := certEventParams(ev, WpaEventEapPeerCert)
+	if err != nil {
+		return nil, err
+	}
+
+	pc := &PeerCert{Subject: ps["subject"], Hash: ps["hash"]}
+	if pc.Depth, err = strconv.Atoi(ps["depth"]); err != nil {
+		return nil, fmt.Errorf("parse depth: %w", err)
+	}
+
+	if s := ps["cert"]; s != "" {
+		b, err := hex.DecodeString(s)
+		if err != nil {
+			return nil, fmt.Errorf("parse cert: %w", err)
+		}
+
+		if pc.Cert, err = x509.ParseCertificate(b); err != nil {
+			return nil, fmt.Errorf("parse cert: %w", err)
+		}
+	}
+
+	return pc, nil
+}
+
+// AddAlt adds the alternative subject of a "CTRL-EVENT-EAP-PEER-ALT" event
+// of the same depth, like "DNS:radius.example.com"
+func (pc *PeerCert) AddAlt(ev Event) error {
+	if !strings.HasPrefix(ev.Message, WpaEventEapPeerAlt) {
+		return fmt.Errorf("not a %s event", strings.TrimSpace(WpaEventEapPeerAlt))
+	}
+
+	fs := strings.SplitN(strings.TrimPrefix(ev.Message, WpaEventEapPeerAlt), " ", 2)
+	if len(fs) != 2 || fs[0] != "depth="+strconv.Itoa(pc.Depth) {
+		return fmt.Errorf("depth mismatch: %s", fs[0])
+	}
+
+	pc.AltSubject = append(pc.AltSubject, fs[1])
+	return nil
+}
+
+// CertErrorReason is the reason of a certificate error, tls_fail_reason in wpa_supplicant
+type CertErrorReason int
+
+// Certificate error reasons
+const (
+	CertErrorUnspecified CertErrorReason = iota
+	CertErrorUntrusted
+	CertErrorRevoked
+	CertErrorNotYetValid
+	CertErrorExpired
+	CertErrorSubjectMismatch
+	CertErrorAltSubjectMismatch
+	CertErrorBadCertificate
+	CertErrorServerChainProbe
+	CertErrorDomainSuffixMismatch
+	CertErrorDomainMismatch
+	CertErrorInsufficientKeyLen
+)
+
+var certErrorReasons = []string{"unspecified", "untrusted", "revoked", "not yet valid", "expired",
+	"subject mismatch", "altsubject mismatch", "bad certificate", "server chain probe",
+	"domain suffix mismatch", "domain mismatch", "insufficient key length"}
+
+func (r CertErrorReason) String() string {
+	if r < 0 || int(r) >= len(certErrorReasons) {
+		return "reason " + strconv.Itoa(int(r))
+	}
+
+	return certErrorReasons[r]
+}
+
+// CertError represents "CTRL-EVENT-EAP-TLS-CERT-ERROR" event,
+// the server certificate at Depth is rejected
+type CertError struct {
+	Reason  CertErrorReason
+	Depth   int
+	Subject string
+	Err     string
+}
+
+func (ce *CertError) Error() string {
+	return fmt.Sprintf("certificate %s at depth %d: %s: %s", ce.Subject, ce.Depth, ce.Reason, ce.Err)
+}
+
+// ParseCertError parses a "CTRL-EVENT-EAP-TLS-CERT-ERROR" event
+func ParseCertError(ev Event) (*CertError, error) {
+	ps, err := certEventParams(ev, WpaEventEapTLSCertError)
+	if err != nil {
+		return nil, err
+	}
+
+	ce := &CertError{Subject: ps["subject"], Err: ps["err"]}
+	r, err := strconv.Atoi(ps["reason"])
+	if err != nil {
+		return nil, fmt.Errorf("parse reason: %w", err)
+	}
+	ce.Reason = CertErrorReason(r)
+
+	if ce.Depth, err = strconv.Atoi(ps["depth"]); err != nil {
+		return nil, fmt.Errorf("parse depth: %w", err)
+	}
+
+	return ce, nil
+}
+
+// certEventParams returns key=value parameters of ev, values can be single quoted
+func certEventParams(ev Event, name string) (map[string]string, error) {
+	if !strings.HasPrefix(ev.Message, name) {
+		return nil, fmt.Errorf("not a %s event", strings.TrimSpace(name))
+	}
+
+	return parseQuotedParams(strings.TrimPrefix(ev.Message, name)), nil
+}
+
+// parseQuotedParams parses space separated key=value parameters,
+// single quoted values can hold spaces
+func parseQuotedParams(s string) map[string]string {
+	ps := map[string]string{}
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimSpace(s) {
+		i := strings.IndexByte(s, '=')
+		if i < 0 {
+			break
+		}
+		k, v := s[:i], s[i+1:]
+
+		if strings.HasPrefix(v, "'") {
+			v = v[1:]
+			j := strings.Index(v, "' ")
+			if j < 0 {
+				ps[k] = strings.TrimSuffix(v, "'")
+				break
+			}
+			ps[k], s = v[:j], v[j+1:]
+			continue
+		}
+
+		j := strings.IndexByte(v, ' ')
+		if j < 0 {
+			j = len(v)
+		}
+		ps[k], s = v[:j], v[j:]
+	}
+
+	return ps
+}
@@ -0,0 +1,179 @@
+package wpaclient
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testCert returns a self signed certificate for cn
+func testCert(t *testing.T, cn string) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		DNSNames:     []string{cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cert
+}
+
+func TestParseCertificates(t *testing.T) {
+	ca, srv := testCert(t, "ca.example.com"), testCert(t, "radius.example.com")
+
+	b := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: []byte{1}})
+	b = append(b, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Raw})...)
+	b = append(b, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw})...)
+
+	certs, err := ParseCertificates(b)
+	if err != nil {
+		t.Fatalf("ParseCertificates not expect an error, got %v", err)
+	}
+
+	if len(certs) != 2 || !certs[0].Equal(srv) || !certs[1].Equal(ca) {
+		t.Errorf("ParseCertificates expected PEM certificates, got %v", certs)
+	}
+
+	if certs, err = ParseCertificates(ca.Raw); err != nil || len(certs) != 1 || !certs[0].Equal(ca) {
+		t.Errorf("ParseCertificates expected DER certificate, got %v, %v", certs, err)
+	}
+
+	if _, err := ParseCertificates([]byte("nope")); err == nil {
+		t.Error("ParseCertificates expect an error")
+	}
+}
+
+func TestParseCertEvents(t *testing.T) {
+	cert := testCert(t, "radius.example.com")
+	hash := CertHash(cert)
+
+	ev := parseEvent([]byte("<2>" + WpaEventEapPeerCert + "depth=0 subject='/C=US/O=Example Inc/CN=radius.example.com' hash=" +
+		hash + " cert=" + hex.EncodeToString(cert.Raw)))
+	pc, err := ParsePeerCert(*ev)
+	if err != nil {
+		t.Fatalf("ParsePeerCert not expect an error, got %v", err)
+	}
+
+	if pc.Depth != 0 || pc.Subject != "/C=US/O=Example Inc/CN=radius.example.com" || pc.Hash != hash {
+		t.Errorf("ParsePeerCert unexpected result %+v", pc)
+	}
+
+	if pc.Cert == nil || !pc.Cert.Equal(cert) || CertHash(pc.Cert) != pc.Hash {
+		t.Errorf("ParsePeerCert expected certificate to be parsed")
+	}
+
+	for _, alt := range []string{"DNS:radius.example.com", "EMAIL:admin@example.com"} {
+		if err := pc.AddAlt(*parseEvent([]byte("<2>" + WpaEventEapPeerAlt + "depth=0 " + alt))); err != nil {
+			t.Errorf("AddAlt not expect an error, got %v", err)
+		}
+	}
+
+	if exp := []string{"DNS:radius.example.com", "EMAIL:admin@example.com"}; !reflect.DeepEqual(pc.AltSubject, exp) {
+		t.Errorf("AddAlt expected %v, got %v", exp, pc.AltSubject)
+	}
+
+	if err := pc.AddAlt(*parseEvent([]byte("<2>" + WpaEventEapPeerAlt + "depth=1 DNS:ca.example.com"))); err == nil {
+		t.Error("AddAlt expect an error for another depth")
+	}
+
+	pc, err = ParsePeerCert(*parseEvent([]byte("<2>" + WpaEventEapPeerCert + "depth=1 subject='/CN=Example CA'")))
+	if err != nil || pc.Depth != 1 || pc.Subject != "/CN=Example CA" || pc.Cert != nil {
+		t.Errorf("ParsePeerCert unexpected result %+v, %v", pc, err)
+	}
+
+	ce, err := ParseCertError(*parseEvent([]byte("<2>" + WpaEventEapTLSCertError +
+		"reason=1 depth=1 subject='/CN=Example CA' err='unable to get local issuer certificate'")))
+	if err != nil {
+		t.Fatalf("ParseCertError not expect an error, got %v", err)
+	}
+
+	exp := &CertError{Reason: CertErrorUntrusted, Depth: 1, Subject: "/CN=Example CA", Err: "unable to get local issuer certificate"}
+	if !reflect.DeepEqual(ce, exp) {
+		t.Errorf("ParseCertError expected %+v, got %+v", exp, ce)
+	}
+
+	if !strings.Contains(ce.Error(), "untrusted") {
+		t.Errorf("expected reason in error, got %s", ce)
+	}
+
+	if _, err := ParseCertError(*ev); err == nil {
+		t.Error("ParseCertError expect an error for another event")
+	}
+}
+
+func TestSetBlob(t *testing.T) {
+	ts, cleanup := newTestServer(t)
+	defer cleanup()
+
+	ts.setCmd(CmdSet, "OK")
+
+	c, err := New(ts.addr())
+	if err != nil {
+		t.Fatalf("New Client failed, %v", err)
+	}
+	defer c.Close()
+
+	if err := c.SetBlob("key", []byte{0xde, 0xad}); err != nil || ts.lastCmd() != "SET blob key dead" {
+		t.Errorf("SetBlob expected to send hex data, got %s, %v", ts.lastCmd(), err)
+	}
+
+	if err := c.RemoveBlob("key"); err != nil || ts.lastCmd() != "SET blob key " {
+		t.Errorf("RemoveBlob expected to send empty blob, got %q, %v", ts.lastCmd(), err)
+	}
+
+	if err := c.SetBlob("a b", nil); err == nil {
+		t.Error("SetBlob expect an error for invalid name")
+	}
+
+	cert := testCert(t, "ca.example.com")
+	if err := c.SetCertBlob("ca", cert); err != nil {
+		t.Fatalf("SetCertBlob not expect an error, got %v", err)
+	}
+
+	b, _ := hex.DecodeString(strings.TrimPrefix(ts.lastCmd(), "SET blob ca "))
+	if !bytes.Equal(b, cert.Raw) {
+		t.Errorf("SetCertBlob expected DER certificate, got %x", b)
+	}
+
+	inter := testCert(t, "inter.example.com")
+	if err := c.SetCertBlob("chain", cert, inter); err != nil {
+		t.Fatalf("SetCertBlob not expect an error, got %v", err)
+	}
+
+	b, _ = hex.DecodeString(strings.TrimPrefix(ts.lastCmd(), "SET blob chain "))
+	if certs, err := ParseCertificates(b); err != nil || len(certs) != 2 || !certs[0].Equal(cert) ||
+		!certs[1].Equal(inter) {
+		t.Errorf("SetCertBlob expected PEM certificates, got %v, %v", certs, err)
+	}
+
+	if err := c.SetBlob("big", make([]byte, maxRequest/2)); !errors.Is(err, ErrBlobTooLarge) {
+		t.Errorf("SetBlob expect error %v, got %v", ErrBlobTooLarge, err)
+	}
+}
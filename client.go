@@ -19,6 +19,8 @@ type handlers struct {
 	sync.RWMutex
 	cm  map[string]chan Event
 	evm map[string]map[string]struct{}
+	// interface name filter of channels subscribed through global control interface
+	ifm map[string]string
 }
 
 // Client represends wpa_supplicant client
@@ -26,11 +28,11 @@ type Client struct {
 	// socket connection address
 	addr string
 
-	// primary socket to run commands
-	cmdsock *socket
+	// primary transport to run commands
+	cmdsock Transport
 
-	// socket to get events
-	evsock *socket
+	// transport to get events
+	evsock Transport
 
 	// channel to push events
 	evch chan Event
@@ -46,18 +48,80 @@ type Client struct {
 
 	// mutex for protecting command execution
 	cmdmut *sync.Mutex
+
+	// records exchanges, can be nil
+	rec *EventRecorder
+
+	// called for every exchange and event
+	hooks []Hook
+
+	// answers credential requests, can be nil
+	prompter CredentialPrompter
+
+	// interface name commands are routed to through global control interface
+	ifname string
+
+	// global client owning the sockets, set for interface clients
+	global *Client
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithRecorder records every command/response exchange and event datagram to r
+func WithRecorder(r *EventRecorder) Option {
+	return func(c *Client) {
+		c.rec = r
+	}
 }
 
 // New returns a new Client object, returns error if dialing socket fails
-func New(addr string) (*Client, error) {
+func New(addr string, opts ...Option) (*Client, error) {
 	cs, err := dial(addr)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Client{addr: addr, cmdsock: cs, evch: make(chan Event, 10),
+	c := &Client{addr: addr, cmdsock: cs, evch: make(chan Event, 10),
 
-		amut: &sync.RWMutex{}, cmdmut: &sync.Mutex{}, hand: &handlers{}}, nil
+		amut: &sync.RWMutex{}, cmdmut: &sync.Mutex{}, hand: &handlers{}}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	// credential requests are received as events
+	if c.prompter != nil {
+		if err := c.monitor(); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// NewWithTransport returns a new Client running commands over cmd and
+// receiving events over events. events can be nil if Notify is not going to be used
+// and no CredentialPrompter is set. Client owns the transports, Close closes them,
+// returns error if attaching for credential requests fails.
+func NewWithTransport(cmd, events Transport, opts ...Option) (*Client, error) {
+	c := &Client{cmdsock: cmd, evsock: events, evch: make(chan Event, 10),
+		amut: &sync.RWMutex{}, cmdmut: &sync.Mutex{}, hand: &handlers{}}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	// credential requests are received as events
+	if c.prompter != nil {
+		if err := c.monitor(); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+
+	return c, nil
 }
 
 // Execute send a commad with its args to wpa_supplicant and reads the response
@@ -68,18 +132,25 @@ func (c *Client) Execute(cmd string, args ...string) ([]byte, error) {
 	defer c.cmdmut.Unlock()
 
 	b := []byte(cmd)
+	if c.ifname != "" {
+		b = []byte(fmt.Sprintf("%s=%s %s", CmdIfname, c.ifname, cmd))
+	}
+
 	if len(args) > 0 {
 		a := " " + strings.Join(args, " ")
 		b = append(b, []byte(a)...)
 	}
 
-	buf, err := c.cmdsock.execute(b)
+	start := time.Now()
+	buf, err := execute(c.cmdsock, b)
+	c.rec.command(b, buf, err)
 
-	if err != nil {
-		return nil, err
+	if err == nil {
+		err = validate(cmd, buf)
 	}
+	c.onCommand(cmd, args, time.Since(start), buf, err)
 
-	if err := validate(cmd, buf); err != nil {
+	if err != nil {
 		return nil, err
 	}
 
@@ -90,9 +161,12 @@ func (c *Client) dispacher() {
 	for ev := range c.evch {
 		c.hand.RLock()
 		for ca, ch := range c.hand.cm {
+			if ifn := c.hand.ifm[ca]; ifn != "" && ev.Err == nil && ev.Ifname != ifn {
+				continue
+			}
+
 			evm := c.hand.evm[ca]
-			_, ok := evm[ev.Message]
-			if ev.Err != nil || len(evm) == 0 || ok {
+			if ev.Err != nil || len(evm) == 0 || match(evm, ev.Message) {
 				select {
 				case ch <- ev:
 				default:
@@ -110,20 +184,48 @@ func (c *Client) dispacher() {
 	}
 	c.hand.cm = nil
 	c.hand.evm = nil
+	c.hand.ifm = nil
 
 	c.hand.Unlock()
 }
 
+// match reports whether msg is one of the events in evm,
+// events carry their parameters after the event name
+func match(evm map[string]struct{}, msg string) bool {
+	if _, ok := evm[msg]; ok {
+		return true
+	}
+
+	for ev := range evm {
+		if strings.HasPrefix(msg, ev) {
+			return true
+		}
+	}
+
+	return false
+}
+
 // Notify returns a receive only event channel.
 // If no events are provided, all incoming events will be relayed to channel.
 // Otherwise, just the provided events will.
 func (c *Client) Notify(evs ...string) (<-chan Event, error) {
+	if c.global != nil {
+		return c.global.notify(c.ifname, evs...)
+	}
+
+	return c.notify("", evs...)
+}
+
+// notify subscribes to evs, if ifname is not empty
+// just the events of that interface are relayed
+func (c *Client) notify(ifname string, evs ...string) (<-chan Event, error) {
 	c.hand.Lock()
 	defer c.hand.Unlock()
 
 	if c.hand.cm == nil {
 		c.hand.cm = make(map[string]chan Event)
 		c.hand.evm = make(map[string]map[string]struct{})
+		c.hand.ifm = make(map[string]string)
 	}
 
 	ch := make(chan Event, 5)
@@ -135,24 +237,39 @@ func (c *Client) Notify(evs ...string) (<-chan Event, error) {
 		evm[ev] = struct{}{}
 	}
 	c.hand.evm[ca] = evm
+	c.hand.ifm[ca] = ifname
+
+	if err := c.monitor(); err != nil {
+		return nil, err
+	}
 
+	return ch, nil
+}
+
+// monitor attaches and starts relaying events if not attached yet
+func (c *Client) monitor() error {
 	c.amut.RLock()
 	a := c.attached
 	c.amut.RUnlock()
 
 	if !a {
 		if err := c.attach(); err != nil {
-			return nil, fmt.Errorf("attach failed: %w", err)
+			return fmt.Errorf("attach failed: %w", err)
 		}
 
 		go c.dispacher()
 	}
 
-	return ch, nil
+	return nil
 }
 
 // Stop causes client to stop relaying incoming events to ch.
 func (c *Client) Stop(ch <-chan Event) {
+	if c.global != nil {
+		c.global.Stop(ch)
+		return
+	}
+
 	c.hand.Lock()
 	defer c.hand.Unlock()
 
@@ -166,6 +283,7 @@ func (c *Client) Stop(ch <-chan Event) {
 	close(chn)
 	delete(c.hand.cm, ca)
 	delete(c.hand.evm, ca)
+	delete(c.hand.ifm, ca)
 }
 
 // attach attaches on second socket and receives events
@@ -179,6 +297,10 @@ func (c *Client) attach() error {
 	}
 
 	if c.evsock == nil {
+		if c.addr == "" {
+			return errors.New("no event transport")
+		}
+
 		s, err := dial(c.addr)
 		if err != nil {
 			return err
@@ -187,7 +309,7 @@ func (c *Client) attach() error {
 		c.evsock = s
 	}
 
-	res, err := c.evsock.execute([]byte(cmdAttach))
+	res, err := execute(c.evsock, []byte(cmdAttach))
 	if err != nil {
 		return err
 	}
@@ -201,9 +323,9 @@ func (c *Client) attach() error {
 	go func() {
 		ucn := "use of closed network connection"
 		for {
-			b, err := c.evsock.receive()
+			b, err := c.evsock.Receive()
 			if err != nil {
-				if err == io.EOF || strings.Contains(err.Error(), ucn) {
+				if errors.Is(err, io.EOF) || errors.Is(err, io.ErrClosedPipe) || strings.Contains(err.Error(), ucn) {
 					return
 				}
 			}
@@ -213,10 +335,25 @@ func (c *Client) attach() error {
 				return
 			}
 
-			select {
-			case c.evch <- *parseEvent(b):
-			default:
+			if err == nil {
+				c.rec.event(b)
+			}
+
+			ev := parseEvent(b)
+			c.onEvent(*ev)
+			if ev.AuthReq != nil {
+				go c.prompt(*ev)
 			}
+
+			// evch is closed on detach
+			c.amut.RLock()
+			if c.attached {
+				select {
+				case c.evch <- *ev:
+				default:
+				}
+			}
+			c.amut.RUnlock()
 		}
 	}()
 
@@ -233,19 +370,24 @@ func (c *Client) detach() error {
 	if c.attached {
 		c.attached = false
 		close(c.evch)
-		return c.evsock.send([]byte(cmdDetach))
+		return c.evsock.Send([]byte(cmdDetach))
 	}
 
 	return nil
 }
 
-// Close closes cmd and event sockets
+// Close closes cmd and event transports.
+// Sockets of an interface client are owned by its Global, so it does nothing.
 func (c *Client) Close() error {
 	var err error
 
+	if c.global != nil {
+		return nil
+	}
+
 	if c.cmdsock != nil {
 
-		if e := c.cmdsock.close(); e != nil {
+		if e := c.cmdsock.Close(); e != nil {
 
 			if err != nil {
 				err = fmt.Errorf(err.Error()+": %w", e)
@@ -262,7 +404,7 @@ func (c *Client) Close() error {
 			err = e
 		}
 
-		if e := c.evsock.close(); e != nil {
+		if e := c.evsock.Close(); e != nil {
 			if err != nil {
 				err = fmt.Errorf(err.Error()+": %w", e)
 			}
@@ -296,21 +438,7 @@ func (c *Client) Scan() ([]AP, error) {
 		return nil
 	}
 
-	scanRes := func() ([]AP, error) {
-		res, err := c.Execute(CmdScanResults)
-		if err != nil {
-			return nil, err
-		}
-
-		aps, err := parseAP(res)
-		if err != nil {
-			return nil, err
-		}
-
-		return aps, nil
-	}
-
-	aps, err := scanRes()
+	aps, err := c.ScanResults()
 	if err != nil {
 		return nil, err
 	}
@@ -321,7 +449,18 @@ func (c *Client) Scan() ([]AP, error) {
 		}
 	}
 
-	return scanRes()
+	return c.ScanResults()
+}
+
+// ScanResults executes "SCAN_RESULTS" command and returns the results
+// of the last scan without scanning
+func (c *Client) ScanResults() ([]AP, error) {
+	res, err := c.Execute(CmdScanResults)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseAP(res)
 }
 
 // ListNetworks executes "LIST_NETWORK" command and returns Networks
@@ -43,7 +43,7 @@ func TestExecute(t *testing.T) {
 	for i, tt := range tests {
 		t.Run(tt.cmd, func(t *testing.T) {
 			if i == 1 {
-				ts.cmdMap[CmdPing] = "BOOM"
+				ts.setCmd(CmdPing, "BOOM")
 			}
 
 			b, err := c.Execute(tt.cmd, tt.args...)
@@ -72,11 +72,11 @@ func TestAttachDetach(t *testing.T) {
 		t.Errorf("Detach not expect an error, got %v", err)
 	}
 
-	ts.cmdMap[cmdAttach] = "FAIL"
+	ts.setCmd(cmdAttach, "FAIL")
 	if err := c.attach(); err == nil {
 		t.Errorf("Attach expect an error, got %v", err)
 	}
-	delete(ts.cmdMap, cmdAttach)
+	ts.delCmd(cmdAttach)
 
 	for i := 0; i < 2; i++ {
 		if err := c.attach(); err != nil {
@@ -97,7 +97,7 @@ func TestAttachDetach(t *testing.T) {
 	// lets wait for go ruting to exit
 	time.Sleep(time.Millisecond)
 
-	c.evsock.close()
+	c.evsock.Close()
 
 	if err := c.attach(); err == nil {
 		t.Errorf("Attach expect an error, got %v", err)
@@ -220,6 +220,10 @@ func TestScan(t *testing.T) {
 	}
 	defer c.Close()
 
+	if aps, err := c.ScanResults(); err != nil || len(aps) != 0 {
+		t.Errorf("ScanResults expected no results before scan, got %v, %v", aps, err)
+	}
+
 	expAps := []AP{
 		{
 			BSSID:          net.HardwareAddr{0xd0, 0x7a, 0xb5, 0x31, 0x23, 0xa0},
@@ -255,6 +259,9 @@ func TestScan(t *testing.T) {
 		}
 	}
 
+	if aps, err := c.ScanResults(); err != nil || !reflect.DeepEqual(aps, expAps) {
+		t.Errorf("ScanResults expected %#v\ngot %#v, %v", expAps, aps, err)
+	}
 }
 
 func TestNetworkCmd(t *testing.T) {
@@ -318,3 +325,23 @@ func TestNetworkCmd(t *testing.T) {
 	}
 
 }
+
+func TestMatch(t *testing.T) {
+	evm := map[string]struct{}{WpaEventConnected: {}, WpaEventEapSuccess: {}}
+
+	tests := []struct {
+		msg string
+		exp bool
+	}{
+		{msg: WpaEventConnected, exp: true},
+		{msg: WpaEventConnected + "- Connection to 00:1f:1f:37:42:d9 completed", exp: true},
+		{msg: WpaEventEapSuccess2 + "EAP authentication completed successfully"},
+		{msg: WpaEventDisconnected + "bssid=00:1f:1f:37:42:d9 reason=3"},
+	}
+
+	for _, tt := range tests {
+		if m := match(evm, tt.msg); m != tt.exp {
+			t.Errorf("match(%q) expected %v, got %v", tt.msg, tt.exp, m)
+		}
+	}
+}
@@ -64,9 +64,10 @@ const (
 	CmdGetCapability        = "GET_CAPABILITY"
 	CmdReconfigure          = "RECONFIGURE"
 	CmdTerminate            = "TERMINATE"
-	CmdInterfaceAdd         = "InterfaceAdd"
+	CmdInterfaceAdd         = "INTERFACE_ADD"
 	CmdInterfaceRemove      = "INTERFACE_REMOVE"
 	CmdInterfaceList        = "INTERFACE_LIST"
+	CmdInterfaces           = "INTERFACES"
 	CmdApScan               = "AP_SCAN"
 	CmdScanInterval         = "SCAN_INTERVAL"
 	CmdBssExpireAge         = "BSS_EXPIRE_AGE"
@@ -79,6 +80,7 @@ const (
 	CmdWpsCheckPin          = "WPS_CHECK_PIN"
 	CmdWpsReg               = "WPS_REG"
 	CmdWpsApPin             = "WPS_AP_PIN"
+	CmdWpsCancel            = "WPS_CANCEL"
 	CmdWpsErStart           = "WPS_ER_START"
 	CmdWpsErStop            = "WPS_ER_STOP"
 	CmdWpsErPin             = "WPS_ER_PIN"
@@ -89,6 +91,8 @@ const (
 	CmdIbssRsn              = "IBSS_RSN"
 	CmdSta                  = "STA"
 	CmdAllSta               = "ALL_STA"
+	CmdStaFirst             = "STA-FIRST"
+	CmdStaNext              = "STA-NEXT"
 	CmdDeauthenticate       = "DEAUTHENTICATE"
 	CmdDisassociate         = "DISASSOCIATE"
 	CmdChanSwitch           = "CHAN_SWITCH"
@@ -154,3 +158,21 @@ const (
 	CmdP2pLoStart           = "P2P_LO_START"
 	CmdP2pLoStop            = "P2P_LO_STOP"
 )
+
+// DPP command constants
+const (
+	CmdDppQrCode             = "DPP_QR_CODE"
+	CmdDppBootstrapGen       = "DPP_BOOTSTRAP_GEN"
+	CmdDppBootstrapRemove    = "DPP_BOOTSTRAP_REMOVE"
+	CmdDppBootstrapGetUri    = "DPP_BOOTSTRAP_GET_URI"
+	CmdDppBootstrapInfo      = "DPP_BOOTSTRAP_INFO"
+	CmdDppAuthInit           = "DPP_AUTH_INIT"
+	CmdDppListen             = "DPP_LISTEN"
+	CmdDppStopListen         = "DPP_STOP_LISTEN"
+	CmdDppConfiguratorAdd    = "DPP_CONFIGURATOR_ADD"
+	CmdDppConfiguratorRemove = "DPP_CONFIGURATOR_REMOVE"
+	CmdDppConfiguratorSign   = "DPP_CONFIGURATOR_SIGN"
+	CmdDppConfiguratorGetKey = "DPP_CONFIGURATOR_GET_KEY"
+	CmdDppPkexAdd            = "DPP_PKEX_ADD"
+	CmdDppPkexRemove         = "DPP_PKEX_REMOVE"
+)
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"regexp"
+	"strings"
+	"syscall"
+
+	"github.com/brlbil/wpaclient"
+)
+
+// idRe matches network id and id_str in CONNECTED event message
+var idRe = regexp.MustCompile(`\[id=(\d+) id_str=([^\]]*)\]`)
+
+// runAction runs script with interface name and "CONNECTED" or "DISCONNECTED"
+// like wpa_cli -a does, until wpa_supplicant terminates or a signal is received.
+// WPA_CTRL_DIR is set, also WPA_ID and WPA_ID_STR on connection.
+func (cl *cli) runAction(script, ctrlPath string) error {
+	ch, err := cl.c.Notify(wpaclient.WpaEventConnected, wpaclient.WpaEventDisconnected,
+		wpaclient.WpaEventTerminating)
+	if err != nil {
+		return err
+	}
+	defer cl.c.Stop(ch)
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sig)
+
+	for {
+		select {
+		case ev, ok := <-ch:
+			if !ok {
+				return nil
+			}
+
+			if ev.Err != nil {
+				continue
+			}
+
+			if strings.HasPrefix(ev.Message, wpaclient.WpaEventTerminating) {
+				return nil
+			}
+
+			if err := cl.action(script, ctrlPath, ev); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+		case <-sig:
+			return nil
+		}
+	}
+}
+
+func (cl *cli) action(script, ctrlPath string, ev wpaclient.Event) error {
+	ifname := cl.ifname
+	if ev.Ifname != "" {
+		ifname = ev.Ifname
+	}
+
+	state := "DISCONNECTED"
+	env := append(os.Environ(), "WPA_CTRL_DIR="+ctrlPath)
+	if strings.HasPrefix(ev.Message, wpaclient.WpaEventConnected) {
+		state = "CONNECTED"
+		if m := idRe.FindStringSubmatch(ev.Message); m != nil {
+			env = append(env, "WPA_ID="+m[1], "WPA_ID_STR="+m[2])
+		}
+	}
+
+	cmd := exec.Command(script, ifname, state)
+	cmd.Env = env
+	cmd.Stdout, cmd.Stderr = cl.out, os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("action script failed: %w", err)
+	}
+
+	return nil
+}
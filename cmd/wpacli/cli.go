@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"strings"
+
+	"github.com/brlbil/wpaclient"
+)
+
+type cli struct {
+	c *wpaclient.Client
+	// set when connected through global control interface
+	g      *wpaclient.Global
+	ifname string
+
+	json bool
+	out  io.Writer
+}
+
+// parser executes a command with a typed result
+type parser func(cl *cli, args []string) (interface{}, error)
+
+// parsers are the commands printed as JSON with --json
+var parsers = map[string]parser{
+	wpaclient.CmdStatus: func(cl *cli, args []string) (interface{}, error) {
+		return cl.c.Status()
+	},
+	wpaclient.CmdSignalPoll: func(cl *cli, args []string) (interface{}, error) {
+		return cl.c.SignalPoll()
+	},
+	wpaclient.CmdListNetworks: func(cl *cli, args []string) (interface{}, error) {
+		return cl.c.ListNetworks()
+	},
+	wpaclient.CmdScanResults: func(cl *cli, args []string) (interface{}, error) {
+		return cl.c.ScanResults()
+	},
+	wpaclient.CmdSta: func(cl *cli, args []string) (interface{}, error) {
+		if len(args) != 1 {
+			return nil, errors.New("usage: sta <addr>")
+		}
+		return cl.c.Station(args[0])
+	},
+	wpaclient.CmdAllSta: func(cl *cli, args []string) (interface{}, error) {
+		return cl.c.Stations()
+	},
+	wpaclient.CmdInterfaceList: func(cl *cli, args []string) (interface{}, error) {
+		if cl.g == nil {
+			return nil, errors.New("interface_list needs global control interface, use -g")
+		}
+		return cl.g.InterfaceList()
+	},
+}
+
+// run executes args, first one is the command and
+// is sent in upper case like wpa_cli does
+func (cl *cli) run(args []string) error {
+	cmd := strings.ToUpper(args[0])
+
+	if p, ok := parsers[cmd]; ok && cl.json {
+		v, err := p(cl, args[1:])
+		if err != nil {
+			return err
+		}
+
+		b, err := json.MarshalIndent(jsonValue(reflect.ValueOf(v)), "", "  ")
+		if err != nil {
+			return err
+		}
+
+		_, err = fmt.Fprintf(cl.out, "%s\n", b)
+		return err
+	}
+
+	res, err := cl.c.Execute(cmd, args[1:]...)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(cl.out, strings.TrimSuffix(string(res), "\n"))
+	return err
+}
+
+// close closes the clients once, c is not closed again if it is the global one
+func (cl *cli) close() {
+	if cl.g != nil {
+		if cl.c == cl.g.Client {
+			cl.c = nil
+		}
+		cl.g.Close()
+		cl.g = nil
+	}
+
+	if cl.c != nil {
+		cl.c.Close()
+		cl.c = nil
+	}
+}
+
+var hwAddrType = reflect.TypeOf(net.HardwareAddr{})
+
+// jsonValue converts v to be marshaled with hardware addresses as strings
+// and without the raw Params maps
+func jsonValue(v reflect.Value) interface{} {
+	if v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	switch {
+	case v.Type() == hwAddrType:
+		if v.Len() == 0 {
+			return ""
+		}
+		return v.Interface().(net.HardwareAddr).String()
+	case v.Kind() == reflect.Struct:
+		m := map[string]interface{}{}
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			if f.PkgPath != "" || f.Name == "Params" {
+				continue
+			}
+			m[f.Name] = jsonValue(v.Field(i))
+		}
+		return m
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8:
+		s := []interface{}{}
+		for i := 0; i < v.Len(); i++ {
+			s = append(s, jsonValue(v.Index(i)))
+		}
+		return s
+	}
+
+	return v.Interface()
+}
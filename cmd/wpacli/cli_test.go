@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"go/ast"
+	goparser "go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brlbil/wpaclient"
+	"github.com/brlbil/wpaclient/wpatest"
+)
+
+func newCli(t *testing.T) (*wpatest.Server, *cli, *bytes.Buffer, func()) {
+	s, err := wpatest.NewServer()
+	if err != nil {
+		t.Fatalf("NewServer failed, %v", err)
+	}
+
+	c, err := wpaclient.New(s.Addr())
+	if err != nil {
+		s.Close()
+		t.Fatalf("New failed, %v", err)
+	}
+
+	out := &bytes.Buffer{}
+	return s, &cli{c: c, ifname: "wlan0", out: out}, out, func() {
+		c.Close()
+		s.Close()
+	}
+}
+
+func TestRun(t *testing.T) {
+	s, cl, out, cleanup := newCli(t)
+	defer cleanup()
+
+	if err := cl.run([]string{"ping"}); err != nil || out.String() != "PONG\n" {
+		t.Errorf("run ping expected PONG, got %q, %v", out, err)
+	}
+
+	out.Reset()
+	cl.json = true
+	if err := cl.run([]string{"status"}); err != nil {
+		t.Fatalf("run status not expect an error, got %v", err)
+	}
+
+	st := map[string]interface{}{}
+	if err := json.Unmarshal(out.Bytes(), &st); err != nil {
+		t.Fatalf("expected JSON output, got %q, %v", out, err)
+	}
+
+	if st["WpaState"] != wpatest.StateDisconnected || st["Address"] != wpatest.Address {
+		t.Errorf("unexpected status %v", st)
+	}
+
+	// scan_results prints the last results without scanning
+	scanned := false
+	s.Handle(wpaclient.CmdScan, func([]string) string { scanned = true; return "OK" })
+	s.SetScanResults(wpatest.BSS{BSSID: "00:1f:1f:37:42:d9", SSID: "home", Freq: 2442, Signal: -50})
+
+	out.Reset()
+	if err := cl.run([]string{"scan_results"}); err != nil {
+		t.Fatalf("run scan_results not expect an error, got %v", err)
+	}
+
+	aps := []map[string]interface{}{}
+	if err := json.Unmarshal(out.Bytes(), &aps); err != nil || len(aps) != 1 || aps[0]["SSID"] != "home" {
+		t.Errorf("unexpected scan results %q, %v", out, err)
+	}
+	if scanned {
+		t.Error("run scan_results expected not to scan")
+	}
+
+	s.SetResponse(wpaclient.CmdSignalPoll, "FAIL")
+	if err := cl.run([]string{"signal_poll"}); err != wpaclient.ErrCmdFailed {
+		t.Errorf("run signal_poll expect error %v, got %v", wpaclient.ErrCmdFailed, err)
+	}
+}
+
+func TestCompletions(t *testing.T) {
+	s, cl, _, cleanup := newCli(t)
+	defer cleanup()
+
+	s.SetScanResults(wpatest.BSS{BSSID: "00:1f:1f:37:42:d9", SSID: "home", Freq: 2442, Signal: -50})
+	cl.run([]string{"add_network"})
+
+	tests := []struct {
+		line string
+		exp  []string
+	}{
+		{line: "signal_", exp: []string{"signal_monitor", "signal_poll"}},
+		{line: "SIGNAL_P", exp: []string{"signal_poll"}},
+		{line: "select_network ", exp: []string{"0"}},
+		{line: "bss 00:1f", exp: []string{"00:1f:1f:37:42:d9"}},
+		{line: "nothing_like_this", exp: []string{}},
+	}
+
+	for _, tt := range tests {
+		if cs := cl.completions(tt.line); !reflect.DeepEqual(cs, tt.exp) {
+			t.Errorf("completions(%q) expected %v, got %v", tt.line, tt.exp, cs)
+		}
+	}
+}
+
+func TestEditor(t *testing.T) {
+	complete := func(l string) []string {
+		return filterPrefix([]string{"status", "scan", "scan_results"}, l)
+	}
+
+	in := "sta\t\r" + // completed to status
+		"sc\t_\t\r" + // scan_results
+		"\x1b[A\x1b[A\r" + // history, status
+		"xy\x1b[Dz\r" + // cursor left, xzy
+		"\x04"
+
+	ed := newEditor(strings.NewReader(in), ioutil.Discard, "> ", complete)
+
+	lines := []string{}
+	for {
+		l, err := ed.readLine()
+		if err != nil {
+			break
+		}
+		lines = append(lines, l)
+	}
+
+	exp := []string{"status ", "scan_results ", "status ", "xzy"}
+	if !reflect.DeepEqual(lines, exp) {
+		t.Errorf("expected lines %q, got %q", exp, lines)
+	}
+}
+
+func TestAction(t *testing.T) {
+	s, cl, _, cleanup := newCli(t)
+	defer cleanup()
+
+	dir, err := ioutil.TempDir("", "wpacli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	res := filepath.Join(dir, "res")
+	script := filepath.Join(dir, "action.sh")
+	ioutil.WriteFile(script, []byte("#!/bin/sh\necho \"$1 $2 $WPA_ID\" >> "+res+"\n"), 0755)
+
+	done := make(chan error)
+	go func() { done <- cl.runAction(script, dir) }()
+
+	// wait for monitor to be attached
+	for i := 0; s.Monitors() == 0 && i < 100; i++ {
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	s.SendEvent(wpatest.LevelInfo, wpaclient.WpaEventConnected+"- Connection to 00:1f:1f:37:42:d9 completed [id=3 id_str=]")
+	s.SendEvent(wpatest.LevelInfo, wpaclient.WpaEventDisconnected+"bssid=00:1f:1f:37:42:d9 reason=3")
+	s.SendEvent(wpatest.LevelInfo, wpaclient.WpaEventTerminating)
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("runAction not expect an error, got %v", err)
+		}
+	case <-time.After(time.Second * 2):
+		t.Fatal("runAction not returned on terminating")
+	}
+
+	b, _ := ioutil.ReadFile(res)
+	if exp := "wlan0 CONNECTED 3\nwlan0 DISCONNECTED \n"; string(b) != exp {
+		t.Errorf("expected action script calls %q, got %q", exp, b)
+	}
+}
+
+func TestClose(t *testing.T) {
+	s, err := wpatest.NewServer()
+	if err != nil {
+		t.Fatalf("NewServer failed, %v", err)
+	}
+	defer s.Close()
+
+	for _, ifname := range []string{"", "wlan0"} {
+		cl, err := connect("", ifname, s.Addr())
+		if err != nil {
+			t.Fatalf("connect not expect an error, got %v", err)
+		}
+
+		if (cl.c == cl.g.Client) != (ifname == "") {
+			t.Errorf("%q unexpected client %v", ifname, cl.c)
+		}
+
+		// closing again is a no-op
+		cl.close()
+		cl.close()
+		if cl.c != nil || cl.g != nil {
+			t.Errorf("%q expected clients to be released, got %v %v", ifname, cl.c, cl.g)
+		}
+	}
+}
+
+func TestCommandsGenerated(t *testing.T) {
+	f, err := goparser.ParseFile(token.NewFileSet(), "../../cmd.go", nil, 0)
+	if err != nil {
+		t.Fatalf("ParseFile not expect an error, got %v", err)
+	}
+
+	exp := map[string]bool{}
+	for _, d := range f.Decls {
+		g, ok := d.(*ast.GenDecl)
+		if !ok || g.Tok != token.CONST {
+			continue
+		}
+
+		for _, s := range g.Specs {
+			vs := s.(*ast.ValueSpec)
+			for i, n := range vs.Names {
+				if strings.HasPrefix(n.Name, "Cmd") && n.Name != "CmdIfname" {
+					v, _ := strconv.Unquote(vs.Values[i].(*ast.BasicLit).Value)
+					exp[v] = true
+				}
+			}
+		}
+	}
+
+	got := map[string]bool{}
+	for _, c := range commands {
+		got[c] = true
+	}
+
+	if !reflect.DeepEqual(got, exp) {
+		t.Errorf("commands_gen.go is out of date with cmd.go, run go generate")
+	}
+}
@@ -0,0 +1,172 @@
+// Code generated by gen_commands.go from cmd.go; DO NOT EDIT.
+
+package main
+
+import "github.com/brlbil/wpaclient"
+
+// commands are the wpaclient command constants completed in the first
+// word of a command line
+var commands = []string{
+	wpaclient.CmdStatus,
+	wpaclient.CmdPing,
+	wpaclient.CmdRelog,
+	wpaclient.CmdNote,
+	wpaclient.CmdMib,
+	wpaclient.CmdHelp,
+	wpaclient.CmdInterface,
+	wpaclient.CmdLevel,
+	wpaclient.CmdLicense,
+	wpaclient.CmdQuit,
+	wpaclient.CmdSet,
+	wpaclient.CmdDump,
+	wpaclient.CmdGet,
+	wpaclient.CmdDriverFlags,
+	wpaclient.CmdLogon,
+	wpaclient.CmdLogoff,
+	wpaclient.CmdPmksa,
+	wpaclient.CmdPmksaFlush,
+	wpaclient.CmdReassociate,
+	wpaclient.CmdReattach,
+	wpaclient.CmdPreauthenticate,
+	wpaclient.CmdIdentity,
+	wpaclient.CmdPassword,
+	wpaclient.CmdNewPassword,
+	wpaclient.CmdPin,
+	wpaclient.CmdOtp,
+	wpaclient.CmdPassphrase,
+	wpaclient.CmdSim,
+	wpaclient.CmdBssid,
+	wpaclient.CmdBlacklist,
+	wpaclient.CmdLogLevel,
+	wpaclient.CmdListNetworks,
+	wpaclient.CmdSelectNetwork,
+	wpaclient.CmdEnableNetwork,
+	wpaclient.CmdDisableNetwork,
+	wpaclient.CmdAddNetwork,
+	wpaclient.CmdRemoveNetwork,
+	wpaclient.CmdSetNetwork,
+	wpaclient.CmdGetNetwork,
+	wpaclient.CmdDupNetwork,
+	wpaclient.CmdListCreds,
+	wpaclient.CmdAddCred,
+	wpaclient.CmdRemoveCred,
+	wpaclient.CmdSetCred,
+	wpaclient.CmdGetCred,
+	wpaclient.CmdSaveConfig,
+	wpaclient.CmdDisconnect,
+	wpaclient.CmdReconnect,
+	wpaclient.CmdScan,
+	wpaclient.CmdScanResults,
+	wpaclient.CmdAbortScan,
+	wpaclient.CmdBss,
+	wpaclient.CmdGetCapability,
+	wpaclient.CmdReconfigure,
+	wpaclient.CmdTerminate,
+	wpaclient.CmdInterfaceAdd,
+	wpaclient.CmdInterfaceRemove,
+	wpaclient.CmdInterfaceList,
+	wpaclient.CmdInterfaces,
+	wpaclient.CmdApScan,
+	wpaclient.CmdScanInterval,
+	wpaclient.CmdBssExpireAge,
+	wpaclient.CmdBssExpireCount,
+	wpaclient.CmdBssFlush,
+	wpaclient.CmdStkstart,
+	wpaclient.CmdFtDs,
+	wpaclient.CmdWpsPbc,
+	wpaclient.CmdWpsPin,
+	wpaclient.CmdWpsCheckPin,
+	wpaclient.CmdWpsReg,
+	wpaclient.CmdWpsApPin,
+	wpaclient.CmdWpsCancel,
+	wpaclient.CmdWpsErStart,
+	wpaclient.CmdWpsErStop,
+	wpaclient.CmdWpsErPin,
+	wpaclient.CmdWpsErPbc,
+	wpaclient.CmdWpsErLearn,
+	wpaclient.CmdWpsErSetConfig,
+	wpaclient.CmdWpsErConfig,
+	wpaclient.CmdIbssRsn,
+	wpaclient.CmdSta,
+	wpaclient.CmdAllSta,
+	wpaclient.CmdStaFirst,
+	wpaclient.CmdStaNext,
+	wpaclient.CmdDeauthenticate,
+	wpaclient.CmdDisassociate,
+	wpaclient.CmdChanSwitch,
+	wpaclient.CmdSuspend,
+	wpaclient.CmdResume,
+	wpaclient.CmdRoam,
+	wpaclient.CmdP2pFind,
+	wpaclient.CmdP2pStopFind,
+	wpaclient.CmdP2pAspProvision,
+	wpaclient.CmdP2pAspProvisionResp,
+	wpaclient.CmdP2pConnect,
+	wpaclient.CmdP2pListen,
+	wpaclient.CmdP2pGroupRemove,
+	wpaclient.CmdP2pGroupAdd,
+	wpaclient.CmdP2pGroupMember,
+	wpaclient.CmdP2pProvDisc,
+	wpaclient.CmdP2pGetPassphrase,
+	wpaclient.CmdP2pServDiscReq,
+	wpaclient.CmdP2pServDiscCancelReq,
+	wpaclient.CmdP2pServDiscResp,
+	wpaclient.CmdP2pServiceUpdate,
+	wpaclient.CmdP2pServDiscExternal,
+	wpaclient.CmdP2pServiceFlush,
+	wpaclient.CmdP2pServiceAdd,
+	wpaclient.CmdP2pServiceRep,
+	wpaclient.CmdP2pServiceDel,
+	wpaclient.CmdP2pReject,
+	wpaclient.CmdP2pInvite,
+	wpaclient.CmdP2pPeers,
+	wpaclient.CmdP2pPeer,
+	wpaclient.CmdP2pSet,
+	wpaclient.CmdP2pFlush,
+	wpaclient.CmdP2pCancel,
+	wpaclient.CmdP2pUnauthorize,
+	wpaclient.CmdP2pPresenceReq,
+	wpaclient.CmdP2pExtListen,
+	wpaclient.CmdP2pRemoveClient,
+	wpaclient.CmdVendorElemAdd,
+	wpaclient.CmdVendorElemGet,
+	wpaclient.CmdVendorElemRemove,
+	wpaclient.CmdStaAutoconnect,
+	wpaclient.CmdTdlsDiscover,
+	wpaclient.CmdTdlsSetup,
+	wpaclient.CmdTdlsTeardown,
+	wpaclient.CmdTdlsLinkStatus,
+	wpaclient.CmdWmmAcAddts,
+	wpaclient.CmdWmmAcDelts,
+	wpaclient.CmdWmmAcStatus,
+	wpaclient.CmdTdlsChanSwitch,
+	wpaclient.CmdTdlsCancelChanSwitch,
+	wpaclient.CmdSignalPoll,
+	wpaclient.CmdSignalMonitor,
+	wpaclient.CmdPktcntPoll,
+	wpaclient.CmdReauthenticate,
+	wpaclient.CmdRaw,
+	wpaclient.CmdFlush,
+	wpaclient.CmdRadioWork,
+	wpaclient.CmdVendor,
+	wpaclient.CmdNeighborRepRequest,
+	wpaclient.CmdErpFlush,
+	wpaclient.CmdMacRandScan,
+	wpaclient.CmdGetPrefFreqList,
+	wpaclient.CmdP2pLoStart,
+	wpaclient.CmdP2pLoStop,
+	wpaclient.CmdDppQrCode,
+	wpaclient.CmdDppBootstrapGen,
+	wpaclient.CmdDppBootstrapRemove,
+	wpaclient.CmdDppBootstrapGetUri,
+	wpaclient.CmdDppBootstrapInfo,
+	wpaclient.CmdDppAuthInit,
+	wpaclient.CmdDppListen,
+	wpaclient.CmdDppStopListen,
+	wpaclient.CmdDppConfiguratorAdd,
+	wpaclient.CmdDppConfiguratorRemove,
+	wpaclient.CmdDppConfiguratorSign,
+	wpaclient.CmdDppConfiguratorGetKey,
+	wpaclient.CmdDppPkexAdd,
+	wpaclient.CmdDppPkexRemove,
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// Key codes handled by editor
+const (
+	keyCtrlA     = 1
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlE     = 5
+	keyBackspace = 8
+	keyTab       = 9
+	keyLF        = 10
+	keyCR        = 13
+	keyCtrlU     = 21
+	keyEsc       = 27
+	keyDelete    = 127
+)
+
+// editor reads lines from a terminal in raw mode,
+// with history and tab completion
+type editor struct {
+	in     *bufio.Reader
+	out    io.Writer
+	prompt string
+
+	hist []string
+	// complete returns candidates for the last word of line
+	complete func(line string) []string
+
+	// protects line state, events are printed while editing
+	mut  sync.Mutex
+	line []rune
+	pos  int
+}
+
+func newEditor(in io.Reader, out io.Writer, prompt string, complete func(string) []string) *editor {
+	return &editor{in: bufio.NewReader(in), out: out, prompt: prompt, complete: complete}
+}
+
+// readLine reads a line, returns io.EOF on Ctrl-D or Ctrl-C on an empty line
+func (e *editor) readLine() (string, error) {
+	e.mut.Lock()
+	e.line, e.pos = nil, 0
+	e.refresh()
+	e.mut.Unlock()
+
+	// index of history entry shown, len(hist) is the edited line
+	hi := len(e.hist)
+	edited := ""
+
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return "", err
+		}
+
+		e.mut.Lock()
+		switch r {
+		case keyCR, keyLF:
+			l := string(e.line)
+			fmt.Fprint(e.out, "\r\n")
+			e.mut.Unlock()
+
+			if strings.TrimSpace(l) != "" && (len(e.hist) == 0 || e.hist[len(e.hist)-1] != l) {
+				e.hist = append(e.hist, l)
+			}
+			return l, nil
+		case keyCtrlC, keyCtrlD:
+			empty := len(e.line) == 0
+			fmt.Fprint(e.out, "\r\n")
+			e.line, e.pos = nil, 0
+
+			if empty {
+				e.mut.Unlock()
+				return "", io.EOF
+			}
+			e.refresh()
+		case keyCtrlA:
+			e.pos = 0
+		case keyCtrlE:
+			e.pos = len(e.line)
+		case keyCtrlU:
+			e.line, e.pos = e.line[e.pos:], 0
+		case keyBackspace, keyDelete:
+			if e.pos > 0 {
+				e.line = append(e.line[:e.pos-1], e.line[e.pos:]...)
+				e.pos--
+			}
+		case keyTab:
+			e.tab()
+		case keyEsc:
+			hi = e.escape(hi, &edited)
+		default:
+			if r >= ' ' {
+				e.line = append(e.line[:e.pos], append([]rune{r}, e.line[e.pos:]...)...)
+				e.pos++
+			}
+		}
+
+		e.refresh()
+		e.mut.Unlock()
+	}
+}
+
+// escape handles arrow and delete escape sequences, returns the history index
+func (e *editor) escape(hi int, edited *string) int {
+	b, _ := e.in.ReadByte()
+	if b != '[' {
+		return hi
+	}
+
+	b, _ = e.in.ReadByte()
+	switch b {
+	case 'A', 'B':
+		if hi == len(e.hist) {
+			*edited = string(e.line)
+		}
+
+		if b == 'A' && hi > 0 {
+			hi--
+		} else if b == 'B' && hi < len(e.hist) {
+			hi++
+		}
+
+		l := *edited
+		if hi < len(e.hist) {
+			l = e.hist[hi]
+		}
+		e.line, e.pos = []rune(l), len([]rune(l))
+	case 'C':
+		if e.pos < len(e.line) {
+			e.pos++
+		}
+	case 'D':
+		if e.pos > 0 {
+			e.pos--
+		}
+	case '3':
+		e.in.ReadByte() // '~'
+		if e.pos < len(e.line) {
+			e.line = append(e.line[:e.pos], e.line[e.pos+1:]...)
+		}
+	}
+
+	return hi
+}
+
+// tab completes the word before cursor, candidates are listed if ambiguous
+func (e *editor) tab() {
+	if e.complete == nil {
+		return
+	}
+
+	before := string(e.line[:e.pos])
+	cs := e.complete(before)
+	if len(cs) == 0 {
+		return
+	}
+
+	word := before[strings.LastIndexAny(before, " ")+1:]
+	ins := commonPrefix(cs)[len(word):]
+	if len(cs) == 1 {
+		ins += " "
+	}
+
+	if ins == "" {
+		fmt.Fprintf(e.out, "\r\n%s\r\n", strings.Join(cs, "  "))
+		return
+	}
+
+	r := []rune(ins)
+	e.line = append(e.line[:e.pos], append(r, e.line[e.pos:]...)...)
+	e.pos += len(r)
+}
+
+// print prints s above the line being edited
+func (e *editor) print(s string) {
+	e.mut.Lock()
+	defer e.mut.Unlock()
+
+	fmt.Fprintf(e.out, "\r\x1b[K%s\r\n", strings.Replace(s, "\n", "\r\n", -1))
+	e.refresh()
+}
+
+// refresh redraws prompt and line, placing the cursor
+func (e *editor) refresh() {
+	fmt.Fprintf(e.out, "\r\x1b[K%s%s", e.prompt, string(e.line))
+	if n := len(e.line) - e.pos; n > 0 {
+		fmt.Fprintf(e.out, "\x1b[%dD", n)
+	}
+}
+
+func commonPrefix(ss []string) string {
+	p := ss[0]
+	for _, s := range ss[1:] {
+		for !strings.HasPrefix(s, p) {
+			p = p[:len(p)-1]
+		}
+	}
+
+	return p
+}
@@ -0,0 +1,58 @@
+//go:build ignore
+// +build ignore
+
+// gen_commands generates commands_gen.go from the command constants of cmd.go
+package main
+
+import (
+	"bytes"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"log"
+	"strings"
+)
+
+// skip are constants of cmd.go which are not commands
+var skip = map[string]bool{"CmdIfname": true}
+
+func main() {
+	f, err := parser.ParseFile(token.NewFileSet(), "../../cmd.go", nil, 0)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	b := &bytes.Buffer{}
+	b.WriteString("// Code generated by gen_commands.go from cmd.go; DO NOT EDIT.\n\n")
+	b.WriteString("package main\n\nimport \"github.com/brlbil/wpaclient\"\n\n")
+	b.WriteString("// commands are the wpaclient command constants completed in the first\n")
+	b.WriteString("// word of a command line\n")
+	b.WriteString("var commands = []string{\n")
+
+	for _, d := range f.Decls {
+		g, ok := d.(*ast.GenDecl)
+		if !ok || g.Tok != token.CONST {
+			continue
+		}
+
+		for _, s := range g.Specs {
+			for _, n := range s.(*ast.ValueSpec).Names {
+				if strings.HasPrefix(n.Name, "Cmd") && !skip[n.Name] {
+					b.WriteString("\twpaclient." + n.Name + ",\n")
+				}
+			}
+		}
+	}
+	b.WriteString("}\n")
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile("commands_gen.go", src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
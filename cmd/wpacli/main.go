@@ -0,0 +1,90 @@
+// Command wpacli is a wpa_cli replacement built on wpaclient.
+//
+//	wpacli [-p ctrl_path] [-i ifname] [-g global_ctrl] [-a action_script] [--json] [command [args...]]
+//
+// Given a command, it is executed once and its response printed. Commands with
+// typed parsers, like status, signal_poll or list_networks, are printed as JSON
+// with --json. Without a command an interactive prompt with history and
+// tab completion is started, events are printed as they arrive.
+// With -a, action_script is run on CONNECTED and DISCONNECTED events like wpa_cli does.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/brlbil/wpaclient"
+)
+
+func main() {
+	ctrlPath := flag.String("p", "/var/run/wpa_supplicant", "path to control sockets")
+	ifname := flag.String("i", "", "interface name, first one in ctrl path if empty")
+	global := flag.String("g", "", "global control interface socket")
+	action := flag.String("a", "", "action script run on CONNECTED and DISCONNECTED events")
+	jsonOut := flag.Bool("json", false, "print responses of commands with typed parsers as JSON")
+	flag.Parse()
+
+	cl, err := connect(*ctrlPath, *ifname, *global)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	cl.json = *jsonOut
+	cl.out = os.Stdout
+
+	switch {
+	case *action != "":
+		err = cl.runAction(*action, *ctrlPath)
+	case flag.NArg() > 0:
+		err = cl.run(flag.Args())
+	default:
+		err = cl.repl()
+	}
+
+	cl.close()
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// connect returns a cli for ifname in ctrlPath, or through global
+// control interface if global is not empty
+func connect(ctrlPath, ifname, global string) (*cli, error) {
+	if global != "" {
+		g, err := wpaclient.NewGlobal(global)
+		if err != nil {
+			return nil, fmt.Errorf("could not connect to global control interface: %w", err)
+		}
+
+		cl := &cli{c: g.Client, g: g, ifname: ifname}
+		if ifname != "" {
+			cl.c = g.Interface(ifname)
+		}
+
+		return cl, nil
+	}
+
+	if ifname == "" {
+		for _, i := range wpaclient.Discover(ctrlPath) {
+			if !i.Global && !i.P2PDevice && filepath.Dir(i.Path) == filepath.Clean(ctrlPath) {
+				ifname = i.Name
+				break
+			}
+		}
+
+		if ifname == "" {
+			return nil, fmt.Errorf("no interface found in %s", ctrlPath)
+		}
+	}
+
+	c, err := wpaclient.New(filepath.Join(ctrlPath, ifname))
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to %s: %w", ifname, err)
+	}
+
+	return &cli{c: c, ifname: ifname}, nil
+}
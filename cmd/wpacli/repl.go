@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/brlbil/wpaclient"
+)
+
+// historySize is the number of prompt history lines kept between sessions
+const historySize = 100
+
+// repl runs the interactive prompt until quit or EOF
+func (cl *cli) repl() error {
+	restore, err := makeRaw(int(os.Stdin.Fd()))
+	if err != nil {
+		// not a terminal, read commands line by line
+		return cl.lines(os.Stdin)
+	}
+	defer restore()
+
+	out := cl.out
+	cl.out = crlfWriter{out}
+
+	ed := newEditor(os.Stdin, out, "> ", cl.completions)
+	ed.hist = loadHistory()
+	defer func() { saveHistory(ed.hist) }()
+
+	if cl.ifname != "" {
+		ed.print(fmt.Sprintf("Selected interface '%s'", cl.ifname))
+	}
+	ed.print("Interactive mode")
+
+	ch, err := cl.c.Notify()
+	if err != nil {
+		ed.print(fmt.Sprintf("Could not attach to events: %v", err))
+	} else {
+		go func() {
+			for ev := range ch {
+				ed.print(formatEvent(ev))
+			}
+		}()
+		defer cl.c.Stop(ch)
+	}
+
+	for {
+		l, err := ed.readLine()
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if !cl.line(l, ed.print) {
+			return nil
+		}
+	}
+}
+
+// lines runs commands read from r
+func (cl *cli) lines(r io.Reader) error {
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		if !cl.line(sc.Text(), func(s string) { fmt.Fprintln(cl.out, s) }) {
+			return nil
+		}
+	}
+
+	return sc.Err()
+}
+
+// line runs command line l, returns false on quit
+func (cl *cli) line(l string, print func(string)) bool {
+	args := strings.Fields(l)
+	if len(args) == 0 {
+		return true
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "quit", "exit":
+		return false
+	}
+
+	if err := cl.run(args); err != nil {
+		print(errString(err))
+	}
+
+	return true
+}
+
+// errString returns err the way wpa_cli prints it
+func errString(err error) string {
+	switch err {
+	case wpaclient.ErrCmdFailed:
+		return "FAIL"
+	case wpaclient.ErrUnknownCmd:
+		return "UNKNOWN COMMAND"
+	}
+
+	return err.Error()
+}
+
+func formatEvent(ev wpaclient.Event) string {
+	if ev.Err != nil {
+		return ev.Err.Error()
+	}
+
+	msg := ev.Message
+	if ev.AuthReq != nil {
+		msg = fmt.Sprintf("%s%s-%d:%s", msg, ev.AuthReq.Type, ev.AuthReq.ID, ev.AuthReq.Text)
+	}
+
+	if ev.Ifname != "" {
+		return fmt.Sprintf("%s=%s <%d>%s", wpaclient.CmdIfname, ev.Ifname, ev.Sev, msg)
+	}
+
+	return fmt.Sprintf("<%d>%s", ev.Sev, msg)
+}
+
+//go:generate go run gen_commands.go
+
+// completions returns the candidates for the last word of line, commands
+// for the first word, network ids for network commands and BSSIDs otherwise
+func (cl *cli) completions(line string) []string {
+	fs := strings.Fields(line)
+	word := ""
+	if len(fs) > 0 && !strings.HasSuffix(line, " ") {
+		word, fs = fs[len(fs)-1], fs[:len(fs)-1]
+	}
+
+	var cands []string
+	switch {
+	case len(fs) == 0:
+		cands = append(cands, "quit")
+		for _, c := range commands {
+			cands = append(cands, strings.ToLower(c))
+		}
+	case strings.Contains(strings.ToUpper(fs[0]), "NETWORK"):
+		ns, _ := cl.c.ListNetworks()
+		for _, n := range ns {
+			cands = append(cands, strconv.Itoa(n.ID))
+		}
+	default:
+		res, _ := cl.c.Execute(wpaclient.CmdScanResults)
+		for i, l := range strings.Split(string(res), "\n") {
+			if f := strings.Fields(l); i > 0 && len(f) > 0 {
+				cands = append(cands, f[0])
+			}
+		}
+	}
+
+	return filterPrefix(cands, word)
+}
+
+// filterPrefix returns sorted unique candidates starting with p
+func filterPrefix(cands []string, p string) []string {
+	seen := map[string]struct{}{}
+	res := []string{}
+	for _, c := range cands {
+		if _, ok := seen[c]; ok || !strings.HasPrefix(c, strings.ToLower(p)) && !strings.HasPrefix(c, p) {
+			continue
+		}
+		seen[c] = struct{}{}
+		res = append(res, c)
+	}
+
+	sort.Strings(res)
+	return res
+}
+
+func historyFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(home, ".wpacli_history")
+}
+
+func loadHistory() []string {
+	b, err := ioutil.ReadFile(historyFile())
+	if err != nil || len(bytes.TrimSpace(b)) == 0 {
+		return nil
+	}
+
+	return strings.Split(string(bytes.TrimSpace(b)), "\n")
+}
+
+func saveHistory(h []string) {
+	if len(h) > historySize {
+		h = h[len(h)-historySize:]
+	}
+
+	if f := historyFile(); f != "" {
+		ioutil.WriteFile(f, []byte(strings.Join(h, "\n")+"\n"), 0600)
+	}
+}
+
+// crlfWriter writes "\r\n" line endings, needed in raw terminal mode
+type crlfWriter struct {
+	w io.Writer
+}
+
+func (c crlfWriter) Write(b []byte) (int, error) {
+	if _, err := c.w.Write([]byte(strings.Replace(string(b), "\n", "\r\n", -1))); err != nil {
+		return 0, err
+	}
+
+	return len(b), nil
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// makeRaw puts terminal fd into raw mode and returns a function restoring it
+func makeRaw(fd int) (func(), error) {
+	var old syscall.Termios
+	if err := ioctl(fd, syscall.TCGETS, &old); err != nil {
+		return nil, err
+	}
+
+	t := old
+	t.Iflag &^= syscall.IGNBRK | syscall.BRKINT | syscall.PARMRK | syscall.ISTRIP |
+		syscall.INLCR | syscall.IGNCR | syscall.ICRNL | syscall.IXON
+	t.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	t.Cflag &^= syscall.CSIZE | syscall.PARENB
+	t.Cflag |= syscall.CS8
+	t.Cc[syscall.VMIN] = 1
+	t.Cc[syscall.VTIME] = 0
+
+	if err := ioctl(fd, syscall.TCSETS, &t); err != nil {
+		return nil, err
+	}
+
+	return func() { ioctl(fd, syscall.TCSETS, &old) }, nil
+}
+
+func ioctl(fd int, req uintptr, t *syscall.Termios) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), req, uintptr(unsafe.Pointer(t)))
+	if errno != 0 {
+		return errno
+	}
+
+	return nil
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+// +build !linux
+
+package main
+
+import "errors"
+
+// makeRaw is not supported, prompt falls back to line reading without editing
+func makeRaw(fd int) (func(), error) {
+	return nil, errors.New("raw terminal mode not supported")
+}
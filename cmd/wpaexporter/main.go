@@ -0,0 +1,70 @@
+// Command wpaexporter serves wpa_supplicant metrics for Prometheus.
+//
+//	wpaexporter -i wlan0 -listen :9847
+//	wpaexporter -g /var/run/wpa_supplicant-global
+//
+// Through the global control interface every interface is exported.
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/brlbil/wpaclient"
+	"github.com/brlbil/wpaclient/wpaexporter"
+)
+
+func main() {
+	iface := flag.String("i", "", "interface name or control socket path, wlan0 if empty;\n"+
+		"comma separated interface names with -g, all of them if empty")
+	global := flag.String("g", "", "global control interface")
+	listen := flag.String("listen", ":9847", "HTTP listen address")
+	interval := flag.Duration("interval", wpaexporter.DefaultInterval, "collection interval")
+	flag.Parse()
+
+	e := wpaexporter.New(*interval)
+	defer e.Close()
+
+	if *global != "" {
+		g, err := wpaclient.NewGlobal(*global)
+		if err != nil {
+			log.Fatalf("connect to %s failed: %v", *global, err)
+		}
+		defer g.Close()
+
+		ifs := []string{}
+		if *iface != "" {
+			ifs = strings.Split(*iface, ",")
+		} else if ifs, err = g.Interfaces(); err != nil {
+			log.Fatalf("interfaces failed: %v", err)
+		}
+
+		for _, i := range ifs {
+			if err := e.Add(i, g.Interface(i)); err != nil {
+				log.Fatalf("export %s failed: %v", i, err)
+			}
+		}
+	} else {
+		if *iface == "" {
+			*iface = "wlan0"
+		}
+
+		c, err := wpaclient.New(*iface)
+		if err != nil {
+			log.Fatalf("connect to %s failed: %v", *iface, err)
+		}
+		defer c.Close()
+
+		if err := e.Add(*iface, c); err != nil {
+			log.Fatalf("export %s failed: %v", *iface, err)
+		}
+	}
+
+	http.Handle("/metrics", e)
+	log.Printf("serving metrics on %s/metrics", *listen)
+	if err := http.ListenAndServe(*listen, nil); err != nil {
+		log.Fatal(err)
+	}
+}
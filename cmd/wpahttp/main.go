@@ -0,0 +1,36 @@
+// Command wpahttp serves the wpahttp JSON API for a wpa_supplicant interface.
+//
+//	wpahttp -i wlan0 -listen :8080
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/brlbil/wpaclient"
+	"github.com/brlbil/wpaclient/wpahttp"
+)
+
+func main() {
+	iface := flag.String("i", "wlan0", "interface name, control socket path or udp: address")
+	listen := flag.String("listen", "127.0.0.1:8080", "HTTP listen address")
+	flag.Parse()
+
+	if err := run(*iface, *listen); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// run serves iface on listen until serving fails, the client is closed on return
+func run(iface, listen string) error {
+	c, err := wpaclient.New(iface)
+	if err != nil {
+		return fmt.Errorf("connect to %s failed: %w", iface, err)
+	}
+	defer c.Close()
+
+	log.Printf("serving %s on %s", iface, listen)
+	return http.ListenAndServe(listen, wpahttp.NewServer(c))
+}
@@ -140,6 +140,8 @@ const (
 	DppEventConfSent = "DPP-CONF-SENT "
 	// DppEventConfFailed as defined in wpactrl/wpa_ctrl.h:161
 	DppEventConfFailed = "DPP-CONF-FAILED "
+	// DppEventConfobjAkm as defined in wpa_ctrl.h of releases newer than this file
+	DppEventConfobjAkm = "DPP-CONFOBJ-AKM "
 	// DppEventConfobjSsid as defined in wpactrl/wpa_ctrl.h:162
 	DppEventConfobjSsid = "DPP-CONFOBJ-SSID "
 	// DppEventConfobjPass as defined in wpactrl/wpa_ctrl.h:163
@@ -0,0 +1,72 @@
+package wpaclient
+
+import (
+	"errors"
+	"strconv"
+)
+
+// Credential types requested with "CTRL-REQ-" events
+const (
+	AuthReqIdentity    = "IDENTITY"
+	AuthReqPassword    = "PASSWORD"
+	AuthReqNewPassword = "NEW_PASSWORD"
+	AuthReqPin         = "PIN"
+	AuthReqOtp         = "OTP"
+	AuthReqPassphrase  = "PASSPHRASE"
+	AuthReqSim         = "SIM"
+)
+
+// promptTypes are the request types CredentialPrompter is asked for
+var promptTypes = map[string]bool{
+	AuthReqIdentity: true, AuthReqPassword: true, AuthReqNewPassword: true, AuthReqPin: true,
+	AuthReqOtp: true, AuthReqPassphrase: true, AuthReqSim: true,
+}
+
+// CredentialPrompter is asked for credentials requested by wpa_supplicant,
+// for example a user interface prompting for EAP identity and password
+type CredentialPrompter interface {
+	// Prompt returns the value for req of interface ifname, ifname is set
+	// for requests received through global control interface.
+	// The request is left unanswered if an error is returned.
+	Prompt(ifname string, req *AuthReq) (Secret, error)
+}
+
+// WithCredentialPrompter answers IDENTITY, PASSWORD, NEW_PASSWORD, PIN, OTP,
+// PASSPHRASE and SIM requests with values returned by p. Client starts
+// monitoring events when it is created, interface clients of Global and
+// P2P group clients inherit p.
+func WithCredentialPrompter(p CredentialPrompter) Option {
+	return func(c *Client) {
+		c.prompter = p
+	}
+}
+
+// Respond answers a "CTRL-REQ-" request, sends "CTRL-RSP-<TYPE>-<id>:<value>"
+func (c *Client) Respond(req *AuthReq, value Secret) error {
+	if req == nil {
+		return errors.New("no request")
+	}
+
+	_, err := c.Execute(WpaCtrlRsp + req.Type + "-" + strconv.Itoa(req.ID) + ":" + value.Reveal())
+	return err
+}
+
+// prompt asks prompter for ev and responds with the value,
+// requests received through global control interface are routed to their interface
+func (c *Client) prompt(ev Event) {
+	if c.prompter == nil || ev.AuthReq == nil || !promptTypes[ev.AuthReq.Type] {
+		return
+	}
+
+	v, err := c.prompter.Prompt(ev.Ifname, ev.AuthReq)
+	if err != nil {
+		return
+	}
+
+	rc := c
+	if ev.Ifname != "" && c.ifname == "" {
+		rc = (&Global{Client: c}).Interface(ev.Ifname)
+	}
+
+	rc.Respond(ev.AuthReq, v)
+}
@@ -0,0 +1,147 @@
+package wpaclient
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type testPrompter struct {
+	reqs chan AuthReq
+}
+
+func (p *testPrompter) Prompt(ifname string, req *AuthReq) (Secret, error) {
+	p.reqs <- *req
+	if req.Type == AuthReqOtp {
+		return "", errors.New("canceled")
+	}
+
+	return "s3cret", nil
+}
+
+func TestCredentialPrompter(t *testing.T) {
+	ts, cleanup := newTestServer(t)
+	defer cleanup()
+
+	rsp := WpaCtrlRsp + "PASSWORD-1:s3cret"
+	ts.setCmd(rsp, "OK")
+
+	p := &testPrompter{reqs: make(chan AuthReq, 5)}
+	c, err := New(ts.addr(), WithCredentialPrompter(p))
+	if err != nil {
+		t.Fatalf("New Client failed, %v", err)
+	}
+	defer c.Close()
+
+	ts.sendMsg(3,
+		WpaCtrlReq+"EXT_CERT_CHECK-1:Certificate check needed",
+		WpaCtrlReq+"OTP-1:Challenge 1235663 needed for SSID foobar",
+		WpaCtrlReq+"PASSWORD-1:Password needed for SSID foobar",
+	)
+
+	// requests are prompted concurrently
+	prompted := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		select {
+		case req := <-p.reqs:
+			if req.ID != 1 {
+				t.Errorf("expected request of network 1, got %+v", req)
+			}
+			prompted[req.Type] = true
+		case <-time.After(time.Second):
+			t.Fatalf("requests not prompted, got %v", prompted)
+		}
+	}
+
+	if !prompted[AuthReqOtp] || !prompted[AuthReqPassword] {
+		t.Errorf("expected OTP and PASSWORD requests to be prompted, got %v", prompted)
+	}
+
+	for i := 0; i < 100 && ts.lastCmd() != rsp; i++ {
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	if ts.lastCmd() != rsp {
+		t.Errorf("expected %s to be sent, got %s", rsp, ts.lastCmd())
+	}
+}
+
+func TestRespond(t *testing.T) {
+	ts, cleanup := newTestServer(t)
+	defer cleanup()
+
+	c, err := New(ts.addr())
+	if err != nil {
+		t.Fatalf("New Client failed, %v", err)
+	}
+	defer c.Close()
+
+	if err := c.Respond(nil, "x"); err == nil {
+		t.Error("Respond expect an error for nil request")
+	}
+
+	err = c.Respond(&AuthReq{ID: 2, Type: AuthReqPin}, "1234")
+	if err != ErrUnknownCmd {
+		t.Errorf("Respond expect error %v, got %v", ErrUnknownCmd, err)
+	}
+
+	if exp := WpaCtrlRsp + "PIN-2:1234"; ts.lastCmd() != exp {
+		t.Errorf("expected %s to be sent, got %s", exp, ts.lastCmd())
+	}
+}
+
+func TestCredentialPrompterTransport(t *testing.T) {
+	cmd, cmdSrv := Pipe()
+	ev, evSrv := Pipe()
+
+	rsp := WpaCtrlRsp + "PASSWORD-1:s3cret"
+	go pipeServer(cmdSrv, map[string]string{rsp: "OK"})
+	go pipeServer(evSrv, map[string]string{cmdAttach: "OK", cmdDetach: "OK"})
+
+	p := &testPrompter{reqs: make(chan AuthReq, 5)}
+	c, err := NewWithTransport(cmd, ev, WithCredentialPrompter(p))
+	if err != nil {
+		t.Fatalf("NewWithTransport not expect an error, got %v", err)
+	}
+	defer c.Close()
+
+	evSrv.Send([]byte("<3>" + WpaCtrlReq + "PASSWORD-1:Password needed for SSID foobar"))
+
+	select {
+	case req := <-p.reqs:
+		if req.Type != AuthReqPassword {
+			t.Errorf("expected %s request, got %+v", AuthReqPassword, req)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("request not prompted")
+	}
+
+	cmd, _ = Pipe()
+	if _, err := NewWithTransport(cmd, nil, WithCredentialPrompter(p)); err == nil {
+		t.Error("NewWithTransport expect an error without event transport")
+	}
+}
+
+func TestCredentialPrompterInherited(t *testing.T) {
+	ts, cleanup := newTestServer(t)
+	defer cleanup()
+
+	p := &testPrompter{reqs: make(chan AuthReq, 5)}
+	g, err := NewGlobal(ts.addr(), WithCredentialPrompter(p))
+	if err != nil {
+		t.Fatalf("NewGlobal failed, %v", err)
+	}
+	defer g.Close()
+
+	if c := g.Interface("wlan0"); c.prompter != p {
+		t.Error("expected interface client to inherit the prompter")
+	}
+
+	gc, err := NewP2P(g.Interface("p2p-dev-wlan0")).GroupClient(&Group{Ifname: "p2p-wlan0-0"})
+	if err != nil {
+		t.Fatalf("GroupClient not expect an error, got %v", err)
+	}
+	if gc.prompter != p {
+		t.Error("expected group client to inherit the prompter")
+	}
+}
@@ -0,0 +1,193 @@
+package wpaclient
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CtrlDirs are the control interface directories searched by Discover
+var CtrlDirs = []string{
+	"/var/run/wpa_supplicant",
+	"/var/wpa_supplicant",
+	"/run/wpa_supplicant",
+	"/var/run/hostapd",
+	"/run/hostapd",
+}
+
+// DiscoverTimeout is the time a control socket has to answer PING
+var DiscoverTimeout = time.Second
+
+// Iface describes a control socket found by Discover
+type Iface struct {
+	Name      string
+	Path      string
+	P2PDevice bool
+	Global    bool
+	Hostapd   bool
+}
+
+// Discover returns every responding control socket in CtrlDirs, dirs,
+// and the default global control interface
+func Discover(dirs ...string) []Iface {
+	ifs := []Iface{}
+	for _, p := range candidates(dirs) {
+		if i, ok := probe(p); ok {
+			ifs = append(ifs, i)
+		}
+	}
+
+	return ifs
+}
+
+// candidates returns paths of sockets in directories and global control interface
+func candidates(dirs []string) []string {
+	ps := []string{}
+	seen := map[string]struct{}{}
+
+	add := func(p string) {
+		fi, err := os.Stat(p)
+		if err != nil || fi.Mode()&os.ModeSocket == 0 {
+			return
+		}
+
+		// same directory can be reached through /var/run symlink
+		if r, err := filepath.EvalSymlinks(p); err == nil {
+			if _, ok := seen[r]; ok {
+				return
+			}
+			seen[r] = struct{}{}
+		}
+		ps = append(ps, p)
+	}
+
+	for _, d := range append(append([]string{}, CtrlDirs...), dirs...) {
+		fis, err := ioutil.ReadDir(d)
+		if err != nil {
+			continue
+		}
+
+		for _, fi := range fis {
+			add(filepath.Join(d, fi.Name()))
+		}
+	}
+	add(globalAddr)
+
+	sort.Strings(ps)
+	return ps
+}
+
+// probe pings the control socket at p and identifies it
+func probe(p string) (Iface, bool) {
+	s, err := dial(p)
+	if err != nil {
+		return Iface{}, false
+	}
+	defer s.Close()
+
+	exec := func(cmd string) string {
+		s.SetDeadline(time.Now().Add(DiscoverTimeout))
+		b, err := execute(s, []byte(cmd))
+		if err != nil {
+			return ""
+		}
+		return string(b)
+	}
+
+	if exec(CmdPing) != "PONG\n" {
+		return Iface{}, false
+	}
+
+	name := filepath.Base(p)
+	i := Iface{
+		Name:      name,
+		Path:      p,
+		P2PDevice: strings.HasPrefix(name, "p2p-dev-"),
+		Hostapd:   strings.Contains(p, "hostapd"),
+	}
+
+	// INTERFACE_LIST is answered by interface sockets as well,
+	// INTERFACES is only known by global control interface
+	if p == globalAddr || validate(CmdInterfaces, []byte(exec(CmdInterfaces))) == nil {
+		i.Global = true
+		return i, true
+	}
+
+	// hostapd reports "state", wpa_supplicant reports "wpa_state"
+	if st := exec(CmdStatus); strings.HasPrefix(st, "state=") || strings.Contains(st, "\nstate=") {
+		i.Hostapd = true
+	}
+
+	return i, true
+}
+
+// Discovery event types
+const (
+	IfaceAdded   = "added"
+	IfaceRemoved = "removed"
+)
+
+// DiscoveryEvent is sent by Watch when a control socket appears or disappears
+type DiscoveryEvent struct {
+	Type  string
+	Iface Iface
+}
+
+// Watch sends a DiscoveryEvent on returned channel whenever a control socket in
+// CtrlDirs or dirs appears or disappears, until ctx is done.
+// Sockets present when Watch is called are reported as added first.
+func Watch(ctx context.Context, dirs ...string) (<-chan DiscoveryEvent, error) {
+	changed, err := watchDirs(ctx, append(append([]string{}, CtrlDirs...), dirs...))
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan DiscoveryEvent, 10)
+	go func() {
+		defer close(ch)
+
+		known := map[string]Iface{}
+		for {
+			ifs := Discover(dirs...)
+
+			cur := map[string]Iface{}
+			evs := []DiscoveryEvent{}
+			for _, i := range ifs {
+				cur[i.Path] = i
+				if _, ok := known[i.Path]; !ok {
+					evs = append(evs, DiscoveryEvent{Type: IfaceAdded, Iface: i})
+				}
+			}
+
+			for p, i := range known {
+				if _, ok := cur[p]; !ok {
+					evs = append(evs, DiscoveryEvent{Type: IfaceRemoved, Iface: i})
+				}
+			}
+			known = cur
+
+			for _, ev := range evs {
+				select {
+				case ch <- ev:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			select {
+			case _, ok := <-changed:
+				if !ok {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return ch, nil
+}
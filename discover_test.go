@@ -0,0 +1,172 @@
+package wpaclient
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// fakeCtrl answers PING and responds to other commands from res
+func fakeCtrl(t *testing.T, p string, res map[string]string) func() {
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: p, Net: "unixgram"})
+	if err != nil {
+		t.Fatalf("listen failed, %v", err)
+	}
+
+	go func() {
+		b := make([]byte, 4095)
+		for {
+			n, addr, err := conn.ReadFrom(b)
+			if err != nil {
+				return
+			}
+
+			r, ok := res[string(b[:n])]
+			switch {
+			case string(b[:n]) == CmdPing:
+				r = "PONG"
+			case !ok:
+				r = "UNKNOWN COMMAND"
+			}
+			conn.WriteTo([]byte(r+"\n"), addr)
+		}
+	}()
+
+	return func() {
+		conn.Close()
+		os.Remove(p)
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wpa_discover")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	hdir := filepath.Join(dir, "hostapd")
+	os.Mkdir(hdir, 0755)
+
+	defer fakeCtrl(t, filepath.Join(dir, "wlan0"), map[string]string{CmdStatus: "wpa_state=COMPLETED",
+		CmdInterfaceList: "nl80211\twlan0\tphy0"})()
+	defer fakeCtrl(t, filepath.Join(dir, "p2p-dev-wlan0"), map[string]string{CmdStatus: "wpa_state=DISCONNECTED"})()
+	defer fakeCtrl(t, filepath.Join(dir, "global"), map[string]string{CmdInterfaces: "wlan0\np2p-dev-wlan0"})()
+	defer fakeCtrl(t, filepath.Join(hdir, "wlan1"), map[string]string{CmdStatus: "state=ENABLED\nphy=phy1"})()
+
+	// stale socket file, nobody listening
+	stale := filepath.Join(dir, "wlan2")
+	conn, _ := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: stale, Net: "unixgram"})
+	conn.Close()
+
+	// not a socket
+	ioutil.WriteFile(filepath.Join(dir, "file"), nil, 0644)
+
+	ifs := Discover(dir, hdir)
+
+	exp := []Iface{
+		{Name: "global", Path: filepath.Join(dir, "global"), Global: true},
+		{Name: "wlan1", Path: filepath.Join(hdir, "wlan1"), Hostapd: true},
+		{Name: "p2p-dev-wlan0", Path: filepath.Join(dir, "p2p-dev-wlan0"), P2PDevice: true},
+		{Name: "wlan0", Path: filepath.Join(dir, "wlan0")},
+	}
+
+	if !reflect.DeepEqual(ifs, exp) {
+		t.Errorf("Discover expected %#v\ngot %#v", exp, ifs)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wpa_watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	defer fakeCtrl(t, filepath.Join(dir, "wlan0"), nil)()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch, err := Watch(ctx, dir)
+	if err != nil {
+		t.Fatalf("Watch not expect an error, got %v", err)
+	}
+
+	next := func() DiscoveryEvent {
+		select {
+		case ev := <-ch:
+			return ev
+		case <-time.After(time.Second * 3):
+			t.Fatal("discovery event not received")
+		}
+		return DiscoveryEvent{}
+	}
+
+	if ev := next(); ev.Type != IfaceAdded || ev.Iface.Name != "wlan0" {
+		t.Errorf("expected wlan0 to be added, got %#v", ev)
+	}
+
+	stop := fakeCtrl(t, filepath.Join(dir, "wlan1"), nil)
+	if ev := next(); ev.Type != IfaceAdded || ev.Iface.Name != "wlan1" {
+		t.Errorf("expected wlan1 to be added, got %#v", ev)
+	}
+
+	stop()
+	if ev := next(); ev.Type != IfaceRemoved || ev.Iface.Name != "wlan1" {
+		t.Errorf("expected wlan1 to be removed, got %#v", ev)
+	}
+
+	cancel()
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Error("expected channel to be closed")
+		}
+	case <-time.After(time.Second):
+		t.Error("channel not closed")
+	}
+}
+
+func TestWatchMissingDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wpa_watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ctrl := filepath.Join(dir, "ctrl")
+	ch, err := Watch(ctx, ctrl)
+	if err != nil {
+		t.Fatalf("Watch not expect an error, got %v", err)
+	}
+
+	if err := os.Mkdir(ctrl, 0700); err != nil {
+		t.Fatal(err)
+	}
+	// let the created dir be watched before the socket is created
+	time.Sleep(time.Millisecond * 50)
+	defer fakeCtrl(t, filepath.Join(ctrl, "wlan0"), nil)()
+
+	select {
+	case ev := <-ch:
+		if ev.Type != IfaceAdded || ev.Iface.Name != "wlan0" {
+			t.Errorf("expected wlan0 to be added, got %#v", ev)
+		}
+	case <-time.After(time.Second * 3):
+		t.Fatal("discovery event not received")
+	}
+
+	if _, err := Watch(ctx, filepath.Join(dir, "a", "b")); err == nil && runtime.GOOS == "linux" {
+		t.Error("Watch expect an error when parent of a dir is missing")
+	}
+}
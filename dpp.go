@@ -0,0 +1,462 @@
+package wpaclient
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrDPPConfFailed returned when "DPP-CONF-FAILED" received
+var ErrDPPConfFailed = constError("dpp configuration failed")
+
+// DPPError represents "DPP-FAIL" and "DPP-NOT-COMPATIBLE" events
+type DPPError struct {
+	Text string
+}
+
+func (e *DPPError) Error() string {
+	return "dpp failed: " + e.Text
+}
+
+// DPP roles
+const (
+	DPPRoleConfigurator = "configurator"
+	DPPRoleEnrollee     = "enrollee"
+	DPPRoleEither       = "either"
+)
+
+// dppSettle is how long to wait for more configuration object events,
+// they are reported after "DPP-CONF-RECEIVED" without an end event
+var dppSettle = 200 * time.Millisecond
+
+// DPP runs Device Provisioning Protocol (Wi-Fi Easy Connect) commands
+type DPP struct {
+	c *Client
+}
+
+// NewDPP returns DPP using c
+func NewDPP(c *Client) *DPP {
+	return &DPP{c: c}
+}
+
+// DPPURI is bootstrapping information of a "DPP:" URI, as encoded in a QR code
+type DPPURI struct {
+	// Channels are global operating class/channel pairs, like "81/1"
+	Channels []string
+	MAC      net.HardwareAddr
+	Info     string
+	Version  int
+	Host     string
+	// Key is the DER encoded public key
+	Key []byte
+	URI string
+}
+
+// PublicKey parses Key
+func (u *DPPURI) PublicKey() (crypto.PublicKey, error) {
+	return x509.ParsePKIXPublicKey(u.Key)
+}
+
+// ParseDPPURI parses "DPP:[C:<channels>;][M:<mac>;][I:<info>;][V:<version>;]K:<key>;;"
+func ParseDPPURI(s string) (*DPPURI, error) {
+	if !strings.HasPrefix(s, "DPP:") || !strings.HasSuffix(s, ";;") {
+		return nil, fmt.Errorf("invalid dpp uri %q", s)
+	}
+
+	u := &DPPURI{URI: s}
+	for _, f := range strings.Split(strings.TrimSuffix(strings.TrimPrefix(s, "DPP:"), ";;"), ";") {
+		if len(f) < 2 || f[1] != ':' {
+			return nil, fmt.Errorf("invalid dpp uri field %q", f)
+		}
+
+		v := f[2:]
+		switch f[0] {
+		case 'C':
+			u.Channels = strings.Split(v, ",")
+		case 'M':
+			mac, err := hex.DecodeString(v)
+			if err != nil || len(mac) != 6 {
+				return nil, fmt.Errorf("parse mac: %s", v)
+			}
+			u.MAC = net.HardwareAddr(mac)
+		case 'I':
+			u.Info = v
+		case 'V':
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, fmt.Errorf("parse version: %w", err)
+			}
+			u.Version = n
+		case 'H':
+			u.Host = v
+		case 'K':
+			key, err := base64.StdEncoding.DecodeString(v)
+			if err != nil {
+				return nil, fmt.Errorf("parse key: %w", err)
+			}
+			u.Key = key
+		}
+	}
+
+	if len(u.Key) == 0 {
+		return nil, errors.New("dpp uri has no key")
+	}
+
+	return u, nil
+}
+
+// BootstrapOptions are the options of "DPP_BOOTSTRAP_GEN" command
+type BootstrapOptions struct {
+	// Channels to listen on, like "81/1"
+	Channels []string
+	MAC      string
+	Info     string
+	// Curve is like "prime256v1", default of wpa_supplicant is used if empty
+	Curve string
+	// Key is a DER encoded private key in hex, generated if empty
+	Key Secret
+}
+
+// BootstrapGen executes "DPP_BOOTSTRAP_GEN type=qrcode" command and returns
+// the id of generated bootstrapping information, see BootstrapURI
+func (d *DPP) BootstrapGen(opts BootstrapOptions) (int, error) {
+	args := []string{"type=qrcode"}
+	if len(opts.Channels) > 0 {
+		args = append(args, "chan="+strings.Join(opts.Channels, ","))
+	}
+	if opts.MAC != "" {
+		args = append(args, "mac="+strings.Replace(opts.MAC, ":", "", -1))
+	}
+	if opts.Info != "" {
+		args = append(args, "info="+opts.Info)
+	}
+	if opts.Curve != "" {
+		args = append(args, "curve="+opts.Curve)
+	}
+	if opts.Key != "" {
+		args = append(args, "key="+opts.Key.Reveal())
+	}
+
+	return d.id(CmdDppBootstrapGen, args...)
+}
+
+// BootstrapURI executes "DPP_BOOTSTRAP_GET_URI" command and returns the
+// "DPP:" URI of bootstrapping information id, to be shown as a QR code
+func (d *DPP) BootstrapURI(id int) (string, error) {
+	res, err := d.c.Execute(CmdDppBootstrapGetUri, strconv.Itoa(id))
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(res)), nil
+}
+
+// BootstrapRemove executes "DPP_BOOTSTRAP_REMOVE" command
+func (d *DPP) BootstrapRemove(id int) error {
+	_, err := d.c.Execute(CmdDppBootstrapRemove, strconv.Itoa(id))
+	return err
+}
+
+// QRCode executes "DPP_QR_CODE" command with a scanned peer uri and returns
+// the id of its bootstrapping information, uri is validated first
+func (d *DPP) QRCode(uri string) (int, error) {
+	if _, err := ParseDPPURI(uri); err != nil {
+		return 0, err
+	}
+
+	return d.id(CmdDppQrCode, uri)
+}
+
+// ConfiguratorAdd executes "DPP_CONFIGURATOR_ADD" command and returns the
+// configurator id. A new signing key is generated on curve if key is empty.
+func (d *DPP) ConfiguratorAdd(curve string, key Secret) (int, error) {
+	args := []string{}
+	if curve != "" {
+		args = append(args, "curve="+curve)
+	}
+	if key != "" {
+		args = append(args, "key="+key.Reveal())
+	}
+
+	return d.id(CmdDppConfiguratorAdd, args...)
+}
+
+// ConfiguratorRemove executes "DPP_CONFIGURATOR_REMOVE" command
+func (d *DPP) ConfiguratorRemove(id int) error {
+	_, err := d.c.Execute(CmdDppConfiguratorRemove, strconv.Itoa(id))
+	return err
+}
+
+// ConfiguratorKey executes "DPP_CONFIGURATOR_GET_KEY" command and returns the
+// signing key of configurator id in hex, to restore it with ConfiguratorAdd
+func (d *DPP) ConfiguratorKey(id int) (Secret, error) {
+	res, err := d.c.Execute(CmdDppConfiguratorGetKey, strconv.Itoa(id))
+	if err != nil {
+		return "", err
+	}
+
+	return Secret(strings.TrimSpace(string(res))), nil
+}
+
+// ConfiguratorSign executes "DPP_CONFIGURATOR_SIGN" command, configurator id
+// signs a configuration for this device, like a connector with conf "sta-dpp"
+// or "ap-dpp", and returns it
+func (d *DPP) ConfiguratorSign(ctx context.Context, id int, conf, ssid string) (*DPPConfig, error) {
+	return d.exchange(ctx, CmdDppConfiguratorSign, "conf="+conf, "ssid="+hex.EncodeToString([]byte(ssid)),
+		"configurator="+strconv.Itoa(id))
+}
+
+// PKEXAdd executes "DPP_PKEX_ADD" command, bootstrapping information own is
+// exchanged with the peer knowing identifier and code, init starts the exchange
+func (d *DPP) PKEXAdd(own int, identifier string, code Secret, init bool) (int, error) {
+	args := []string{"own=" + strconv.Itoa(own), "identifier=" + identifier}
+	if init {
+		args = append(args, "init=1")
+	}
+
+	// code is the last argument, it can hold spaces
+	return d.id(CmdDppPkexAdd, append(args, "code="+code.Reveal())...)
+}
+
+// PKEXRemove executes "DPP_PKEX_REMOVE" command
+func (d *DPP) PKEXRemove(id int) error {
+	_, err := d.c.Execute(CmdDppPkexRemove, strconv.Itoa(id))
+	return err
+}
+
+// id executes cmd and returns the id it responds with
+func (d *DPP) id(cmd string, args ...string) (int, error) {
+	res, err := d.c.Execute(cmd, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := strconv.Atoi(strings.TrimSpace(string(res)))
+	if err != nil {
+		return 0, fmt.Errorf("parse id: %w", err)
+	}
+
+	return id, nil
+}
+
+// DPPAuthOptions are the options of "DPP_AUTH_INIT" command
+type DPPAuthOptions struct {
+	// Peer is the bootstrapping id of the peer, see QRCode
+	Peer int
+	// Own is the bootstrapping id of this device for mutual authentication, not used if zero
+	Own int
+	// Role is DPPRoleConfigurator, DPPRoleEnrollee or DPPRoleEither
+	Role string
+	// Configurator, Conf, SSID, Pass and PSK configure the peer as configurator,
+	// Conf is like "sta-dpp", "sta-psk", "sta-sae" or "ap-dpp"
+	Configurator int
+	Conf         string
+	SSID         string
+	Pass         Secret
+	// PSK is a 256 bit key in hex
+	PSK Secret
+}
+
+func (o DPPAuthOptions) args() []string {
+	args := []string{"peer=" + strconv.Itoa(o.Peer)}
+	if o.Own > 0 {
+		args = append(args, "own="+strconv.Itoa(o.Own))
+	}
+	if o.Role != "" {
+		args = append(args, "role="+o.Role)
+	}
+	if o.Configurator > 0 {
+		args = append(args, "configurator="+strconv.Itoa(o.Configurator))
+	}
+	if o.Conf != "" {
+		args = append(args, "conf="+o.Conf)
+	}
+	if o.SSID != "" {
+		args = append(args, "ssid="+hex.EncodeToString([]byte(o.SSID)))
+	}
+	if o.Pass != "" {
+		args = append(args, "pass="+hex.EncodeToString([]byte(o.Pass.Reveal())))
+	}
+	if o.PSK != "" {
+		args = append(args, "psk="+o.PSK.Reveal())
+	}
+
+	return args
+}
+
+// AuthInit executes "DPP_AUTH_INIT" command, authenticates with the peer and
+// waits for the configuration exchange. The received configuration is returned
+// as enrollee, nil is returned when the configuration is sent as configurator.
+// Exchange is stopped with "DPP_STOP_LISTEN" when ctx is done.
+func (d *DPP) AuthInit(ctx context.Context, opts DPPAuthOptions) (*DPPConfig, error) {
+	return d.exchange(ctx, CmdDppAuthInit, opts.args()...)
+}
+
+// Listen executes "DPP_LISTEN" command, listens on freq for an authentication
+// initiated by the peer in role, and waits for the configuration exchange, see AuthInit
+func (d *DPP) Listen(ctx context.Context, freq int, role string) (*DPPConfig, error) {
+	args := []string{strconv.Itoa(freq)}
+	if role != "" {
+		args = append(args, "role="+role)
+	}
+
+	return d.exchange(ctx, CmdDppListen, args...)
+}
+
+// StopListen executes "DPP_STOP_LISTEN" command
+func (d *DPP) StopListen() error {
+	_, err := d.c.Execute(CmdDppStopListen)
+	return err
+}
+
+// exchange executes cmd and waits for the configuration to be received or sent
+func (d *DPP) exchange(ctx context.Context, cmd string, args ...string) (*DPPConfig, error) {
+	ch, err := d.c.Notify(strings.TrimSpace(DppEventConfReceived), strings.TrimSpace(DppEventConfSent),
+		strings.TrimSpace(DppEventConfFailed), DppEventFail, DppEventNotCompatible, "DPP-CONFOBJ-",
+		DppEventConnector, DppEventCSignKey, DppEventNetAccessKey, DppEventNetworkID)
+	if err != nil {
+		return nil, err
+	}
+	defer d.c.Stop(ch)
+
+	if _, err := d.c.Execute(cmd, args...); err != nil {
+		return nil, err
+	}
+
+	cfg := &DPPConfig{NetworkID: -1}
+	var settle <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			d.StopListen()
+			return nil, ctx.Err()
+		case <-settle:
+			return cfg, nil
+		case ev, ok := <-ch:
+			if !ok {
+				return nil, errors.New("event channel closed")
+			}
+
+			switch {
+			case strings.HasPrefix(ev.Message, strings.TrimSpace(DppEventConfSent)):
+				return nil, nil
+			case strings.HasPrefix(ev.Message, strings.TrimSpace(DppEventConfFailed)):
+				return nil, ErrDPPConfFailed
+			case strings.HasPrefix(ev.Message, DppEventFail):
+				return nil, &DPPError{Text: strings.TrimPrefix(ev.Message, DppEventFail)}
+			case strings.HasPrefix(ev.Message, DppEventNotCompatible):
+				return nil, &DPPError{Text: "not compatible " + strings.TrimPrefix(ev.Message, DppEventNotCompatible)}
+			}
+
+			if err := cfg.Add(ev); err != nil {
+				return nil, err
+			}
+			if cfg.NetworkID >= 0 {
+				return cfg, nil
+			}
+			if cfg.received {
+				settle = time.After(dppSettle)
+			}
+		}
+	}
+}
+
+// DPPConfig is a configuration object received with "DPP-CONF-RECEIVED"
+// and the events following it
+type DPPConfig struct {
+	// AKM is like "psk", "sae", "psk+sae" or "dpp"
+	AKM          string
+	SSID         string
+	Pass         Secret
+	PSK          Secret
+	Connector    string
+	CSignKey     string
+	NetAccessKey Secret
+	// NetworkID is the id of the network added if dpp_config_processing is enabled, -1 otherwise
+	NetworkID int
+
+	received bool
+}
+
+// Add decodes configuration object event ev into cfg, other events are ignored
+func (cfg *DPPConfig) Add(ev Event) error {
+	msg := ev.Message
+	value := func(name string) string { return strings.TrimSpace(strings.TrimPrefix(msg, name)) }
+	unhex := func(name string) (Secret, error) {
+		b, err := hex.DecodeString(value(name))
+		if err != nil {
+			return "", fmt.Errorf("parse %s: %w", strings.TrimSpace(name), err)
+		}
+		return Secret(b), nil
+	}
+
+	var err error
+	switch {
+	case strings.HasPrefix(msg, strings.TrimSpace(DppEventConfReceived)):
+		cfg.received = true
+	case strings.HasPrefix(msg, DppEventConfobjAkm):
+		cfg.AKM = value(DppEventConfobjAkm)
+	case strings.HasPrefix(msg, DppEventConfobjSsid):
+		cfg.SSID = strings.TrimPrefix(msg, DppEventConfobjSsid)
+	case strings.HasPrefix(msg, DppEventConfobjPass):
+		cfg.Pass, err = unhex(DppEventConfobjPass)
+	case strings.HasPrefix(msg, DppEventConfobjPsk):
+		cfg.PSK = Secret(value(DppEventConfobjPsk))
+	case strings.HasPrefix(msg, DppEventConnector):
+		cfg.Connector = value(DppEventConnector)
+	case strings.HasPrefix(msg, DppEventCSignKey):
+		cfg.CSignKey = value(DppEventCSignKey)
+	case strings.HasPrefix(msg, DppEventNetAccessKey):
+		// <key> [<expiry>]
+		cfg.NetAccessKey = Secret(strings.Fields(value(DppEventNetAccessKey) + " ")[0])
+	case strings.HasPrefix(msg, DppEventNetworkID):
+		if cfg.NetworkID, err = strconv.Atoi(value(DppEventNetworkID)); err != nil {
+			err = fmt.Errorf("parse network id: %w", err)
+		}
+	}
+
+	return err
+}
+
+// dppKeyMgmt maps AKMs to key_mgmt values
+var dppKeyMgmt = map[string]string{"dpp": "DPP", "psk": "WPA-PSK", "sae": "SAE"}
+
+// NetworkConfig returns the network cfg configures, see Client.Connect
+func (cfg *DPPConfig) NetworkConfig() NetworkConfig {
+	nc := NetworkConfig{SSID: cfg.SSID, Params: map[string]string{}}
+
+	kms := []string{}
+	for _, a := range strings.Split(cfg.AKM, "+") {
+		if km, ok := dppKeyMgmt[a]; ok {
+			kms = append(kms, km)
+		}
+	}
+	nc.KeyMgmt = strings.Join(kms, " ")
+
+	switch {
+	case cfg.Pass != "" && cfg.AKM == "sae":
+		nc.SAEPassword = cfg.Pass
+	case cfg.Pass != "":
+		nc.PSK = cfg.Pass
+	case cfg.PSK != "":
+		nc.PSK = cfg.PSK
+	}
+
+	if cfg.Connector != "" {
+		nc.Params["dpp_connector"] = Quote(cfg.Connector)
+		nc.Params["dpp_csign"] = cfg.CSignKey
+		nc.Params["dpp_netaccesskey"] = cfg.NetAccessKey.Reveal()
+		// DPP requires protected management frames
+		nc.Params["ieee80211w"] = "2"
+	}
+
+	return nc
+}
@@ -0,0 +1,279 @@
+package wpaclient
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testDPPURI(t *testing.T) string {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey failed, %v", err)
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("MarshalPKIXPublicKey failed, %v", err)
+	}
+
+	return "DPP:C:81/1,115/36;M:021f1f3742d9;I:SN=4774LH2b4044;V:2;K:" + base64.StdEncoding.EncodeToString(der) + ";;"
+}
+
+func TestParseDPPURI(t *testing.T) {
+	uri := testDPPURI(t)
+	u, err := ParseDPPURI(uri)
+	if err != nil {
+		t.Fatalf("ParseDPPURI not expect an error, got %v", err)
+	}
+
+	if !reflect.DeepEqual(u.Channels, []string{"81/1", "115/36"}) || u.MAC.String() != "02:1f:1f:37:42:d9" ||
+		u.Info != "SN=4774LH2b4044" || u.Version != 2 || u.URI != uri {
+		t.Errorf("unexpected uri %+v", u)
+	}
+
+	if _, err := u.PublicKey(); err != nil {
+		t.Errorf("PublicKey not expect an error, got %v", err)
+	}
+
+	for _, s := range []string{"", "DPP:K:;;", "DPP:M:0211;K:AAAA;;", "DPP:I:info;;", "DPP:K:AAAA;"} {
+		if _, err := ParseDPPURI(s); err == nil {
+			t.Errorf("ParseDPPURI expect an error for %q", s)
+		}
+	}
+}
+
+func TestDPPCommands(t *testing.T) {
+	ts, cleanup := newTestServer(t)
+	defer cleanup()
+
+	for _, cmd := range []string{CmdDppBootstrapGen, CmdDppQrCode, CmdDppConfiguratorAdd, CmdDppPkexAdd} {
+		ts.setCmd(cmd, "1")
+	}
+	for _, cmd := range []string{CmdDppBootstrapRemove, CmdDppConfiguratorRemove, CmdDppPkexRemove} {
+		ts.setCmd(cmd, "OK")
+	}
+	uri := testDPPURI(t)
+	ts.setCmd(CmdDppBootstrapGetUri, uri)
+	ts.setCmd(CmdDppConfiguratorGetKey, "3077020101")
+
+	c, err := New(ts.addr())
+	if err != nil {
+		t.Fatalf("New Client failed, %v", err)
+	}
+	defer c.Close()
+
+	d := NewDPP(c)
+	tests := []struct {
+		name string
+		fn   func() error
+		last string
+	}{
+		{name: "bootstrap gen", fn: func() error {
+			_, err := d.BootstrapGen(BootstrapOptions{Channels: []string{"81/1"}, MAC: "02:1f:1f:37:42:d9", Info: "SN=1"})
+			return err
+		}, last: CmdDppBootstrapGen + " type=qrcode chan=81/1 mac=021f1f3742d9 info=SN=1"},
+		{name: "bootstrap uri", fn: func() error {
+			s, err := d.BootstrapURI(1)
+			if err == nil && s != uri {
+				t.Errorf("expected %s, got %s", uri, s)
+			}
+			return err
+		}, last: CmdDppBootstrapGetUri + " 1"},
+		{name: "bootstrap remove", fn: func() error { return d.BootstrapRemove(1) }, last: CmdDppBootstrapRemove + " 1"},
+		{name: "qr code", fn: func() error {
+			_, err := d.QRCode(uri)
+			return err
+		}, last: CmdDppQrCode + " " + uri},
+		{name: "configurator add", fn: func() error {
+			_, err := d.ConfiguratorAdd("prime256v1", "")
+			return err
+		}, last: CmdDppConfiguratorAdd + " curve=prime256v1"},
+		{name: "configurator key", fn: func() error {
+			k, err := d.ConfiguratorKey(1)
+			if err == nil && k != "3077020101" {
+				t.Errorf("unexpected key %s", k.Reveal())
+			}
+			return err
+		}, last: CmdDppConfiguratorGetKey + " 1"},
+		{name: "configurator remove", fn: func() error { return d.ConfiguratorRemove(1) },
+			last: CmdDppConfiguratorRemove + " 1"},
+		{name: "pkex add", fn: func() error {
+			_, err := d.PKEXAdd(1, "printer", "secret code", true)
+			return err
+		}, last: CmdDppPkexAdd + " own=1 identifier=printer init=1 code=secret code"},
+		{name: "pkex remove", fn: func() error { return d.PKEXRemove(1) }, last: CmdDppPkexRemove + " 1"},
+	}
+
+	for _, tt := range tests {
+		if err := tt.fn(); err != nil {
+			t.Errorf("%s not expect an error, got %v", tt.name, err)
+		}
+		if ts.lastCmd() != tt.last {
+			t.Errorf("%s expected %s to be sent, got %s", tt.name, tt.last, ts.lastCmd())
+		}
+	}
+
+	if _, err := d.QRCode("not a uri"); err == nil {
+		t.Error("QRCode expect an error")
+	}
+}
+
+func TestDPPExchange(t *testing.T) {
+	ts, cleanup := newTestServer(t)
+	defer cleanup()
+
+	for _, cmd := range []string{CmdDppAuthInit, CmdDppListen, CmdDppStopListen, CmdDppConfiguratorSign} {
+		ts.setCmd(cmd, "OK")
+	}
+
+	c, err := New(ts.addr())
+	if err != nil {
+		t.Fatalf("New Client failed, %v", err)
+	}
+	defer c.Close()
+
+	d := NewDPP(c)
+	home := hex.EncodeToString([]byte("home"))
+	pass := hex.EncodeToString([]byte("p4ssw0rd"))
+
+	tests := []struct {
+		name    string
+		fn      func(context.Context) (*DPPConfig, error)
+		started string
+		events  []string
+		cfg     *DPPConfig
+		err     error
+		last    string
+	}{
+		{name: "enrollee psk", fn: func(ctx context.Context) (*DPPConfig, error) {
+			return d.AuthInit(ctx, DPPAuthOptions{Peer: 2, Own: 1, Role: DPPRoleEnrollee})
+		}, started: CmdDppAuthInit + " peer=2 own=1 role=enrollee",
+			events: []string{DppEventAuthSuccess + "init=1", "DPP-CONF-RECEIVED", DppEventConfobjAkm + "psk",
+				DppEventConfobjSsid + "home", DppEventConfobjPass + pass},
+			cfg: &DPPConfig{AKM: "psk", SSID: "home", Pass: "p4ssw0rd", NetworkID: -1, received: true}},
+		{name: "listen with network", fn: func(ctx context.Context) (*DPPConfig, error) {
+			return d.Listen(ctx, 2437, DPPRoleEnrollee)
+		}, started: CmdDppListen + " 2437 role=enrollee",
+			events: []string{"DPP-CONF-RECEIVED", DppEventConnector + "eyJ0eXAi.eyJncm91cHMi.sig",
+				DppEventCSignKey + "3039", DppEventNetAccessKey + "3077 1735689600", DppEventNetworkID + "4"},
+			cfg: &DPPConfig{Connector: "eyJ0eXAi.eyJncm91cHMi.sig", CSignKey: "3039", NetAccessKey: "3077",
+				NetworkID: 4, received: true}},
+		{name: "configurator", fn: func(ctx context.Context) (*DPPConfig, error) {
+			return d.AuthInit(ctx, DPPAuthOptions{Peer: 2, Configurator: 1, Conf: "sta-psk", SSID: "home", Pass: "p4ssw0rd"})
+		}, started: CmdDppAuthInit + " peer=2 configurator=1 conf=sta-psk ssid=" + home + " pass=" + pass,
+			events: []string{DppEventAuthSuccess + "init=1", "DPP-CONF-SENT"}},
+		{name: "sign", fn: func(ctx context.Context) (*DPPConfig, error) {
+			return d.ConfiguratorSign(ctx, 1, "ap-dpp", "home")
+		}, started: CmdDppConfiguratorSign + " conf=ap-dpp ssid=" + home + " configurator=1",
+			events: []string{"DPP-CONF-RECEIVED", DppEventConfobjAkm + "dpp", DppEventConnector + "a.b.c"},
+			cfg:    &DPPConfig{AKM: "dpp", Connector: "a.b.c", NetworkID: -1, received: true}},
+		{name: "failed", fn: func(ctx context.Context) (*DPPConfig, error) {
+			return d.AuthInit(ctx, DPPAuthOptions{Peer: 2})
+		}, started: CmdDppAuthInit + " peer=2", events: []string{"DPP-CONF-FAILED"}, err: ErrDPPConfFailed},
+		{name: "fail", fn: func(ctx context.Context) (*DPPConfig, error) {
+			return d.AuthInit(ctx, DPPAuthOptions{Peer: 2})
+		}, started: CmdDppAuthInit + " peer=2", events: []string{DppEventFail + "Configurator rejected configuration"},
+			err: &DPPError{Text: "Configurator rejected configuration"}},
+		{name: "canceled", fn: func(ctx context.Context) (*DPPConfig, error) {
+			return d.Listen(ctx, 2437, "")
+		}, started: CmdDppListen + " 2437", err: context.Canceled, last: CmdDppStopListen},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts.mut.Lock()
+			ts.last = ""
+			ts.mut.Unlock()
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			type result struct {
+				cfg *DPPConfig
+				err error
+			}
+			res := make(chan result, 1)
+			go func() {
+				cfg, err := tt.fn(ctx)
+				res <- result{cfg, err}
+			}()
+
+			for i := 0; i < 100 && ts.lastCmd() != tt.started; i++ {
+				time.Sleep(time.Millisecond * 10)
+			}
+			if ts.lastCmd() != tt.started {
+				t.Fatalf("expected %s to be sent, got %s", tt.started, ts.lastCmd())
+			}
+
+			if len(tt.events) == 0 {
+				cancel()
+			}
+			ts.sendMsg(3, tt.events...)
+
+			var r result
+			select {
+			case r = <-res:
+			case <-time.After(time.Second):
+				t.Fatal("exchange not finished")
+			}
+
+			if !reflect.DeepEqual(r.cfg, tt.cfg) || !reflect.DeepEqual(r.err, tt.err) {
+				t.Errorf("expected %+v, %v, got %+v, %v", tt.cfg, tt.err, r.cfg, r.err)
+			}
+
+			if tt.last != "" && ts.lastCmd() != tt.last {
+				t.Errorf("expected %s to be sent last, got %s", tt.last, ts.lastCmd())
+			}
+		})
+	}
+}
+
+func TestDPPNetworkConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  DPPConfig
+		exp  NetworkConfig
+	}{
+		{name: "psk", cfg: DPPConfig{AKM: "psk", SSID: "home", Pass: "p4ssw0rd"},
+			exp: NetworkConfig{SSID: "home", KeyMgmt: "WPA-PSK", PSK: "p4ssw0rd", Params: map[string]string{}}},
+		{name: "sae", cfg: DPPConfig{AKM: "sae", SSID: "home", Pass: "p4ssw0rd"},
+			exp: NetworkConfig{SSID: "home", KeyMgmt: "SAE", SAEPassword: "p4ssw0rd", Params: map[string]string{}}},
+		{name: "dpp", cfg: DPPConfig{AKM: "dpp", SSID: "home", Connector: "a.b.c", CSignKey: "3039", NetAccessKey: "3077"},
+			exp: NetworkConfig{SSID: "home", KeyMgmt: "DPP", Params: map[string]string{"dpp_connector": `"a.b.c"`,
+				"dpp_csign": "3039", "dpp_netaccesskey": "3077", "ieee80211w": "2"}}},
+	}
+
+	for _, tt := range tests {
+		if nc := tt.cfg.NetworkConfig(); !reflect.DeepEqual(nc, tt.exp) {
+			t.Errorf("%s expected %+v, got %+v", tt.name, tt.exp, nc)
+		}
+	}
+}
+
+func TestRedactDPP(t *testing.T) {
+	if s, _ := redactCommand(CmdDppAuthInit + " peer=2 conf=sta-psk ssid=686f6d65 pass=7034"); s !=
+		CmdDppAuthInit+" peer=2 conf=sta-psk ssid=686f6d65 pass="+Redacted {
+		t.Errorf("unexpected redacted command %s", s)
+	}
+	for _, code := range []string{"secret", "secret code", "code=a b"} {
+		if s, _ := redactCommand(CmdDppPkexAdd + " own=1 identifier=printer init=1 code=" + code); s !=
+			CmdDppPkexAdd+" own=1 identifier=printer init=1 code="+Redacted {
+			t.Errorf("unexpected redacted command %s", s)
+		}
+	}
+	if cmd, args := redact(CmdDppPkexAdd, []string{"own=1", "code=secret code"}); cmd != CmdDppPkexAdd ||
+		strings.Join(args, " ") != "own=1 code="+Redacted {
+		t.Errorf("unexpected redacted args %v", args)
+	}
+	if s := redactEvent(DppEventConfobjPass + "7034"); s != DppEventConfobjPass+Redacted {
+		t.Errorf("unexpected redacted event %s", s)
+	}
+}
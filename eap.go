@@ -0,0 +1,210 @@
+package wpaclient
+
+import (
+	"errors"
+	"strings"
+)
+
+// BlobPrefix refers to a blob set with "SET blob" instead of a file,
+// it can be used as certificate or key path
+const BlobPrefix = "blob://"
+
+// EAPProfile is the 802.1X/EAP configuration of a NetworkConfig.
+// Empty identities and passwords are requested by wpa_supplicant
+// with "CTRL-REQ-" events when connecting, see CredentialPrompter.
+type EAPProfile interface {
+	// Params returns network parameters of the profile in the order they are set,
+	// values are quoted. An error is returned for invalid combinations.
+	Params() ([][2]string, error)
+	// Warnings returns insecure settings of the profile
+	Warnings() []string
+}
+
+// EAP profile warnings
+const (
+	WarnNoCACert      = "no CA certificate set, server is not authenticated"
+	WarnNoDomainMatch = "no domain suffix match set, any server certified by the CA is accepted"
+)
+
+// EAPPEAP is a PEAP profile, Phase2 is MSCHAPV2 if empty
+type EAPPEAP struct {
+	Identity          string
+	AnonymousIdentity string
+	Password          Secret
+	Phase2            string
+	CACert            string
+	DomainSuffixMatch string
+}
+
+// Params implements EAPProfile
+func (p EAPPEAP) Params() ([][2]string, error) {
+	ps := eapParams("PEAP", p.Identity, p.AnonymousIdentity, p.Password)
+	ps = append(ps, [2]string{"phase2", Quote("auth=" + orDefault(p.Phase2, "MSCHAPV2"))})
+	return append(ps, serverParams(p.CACert, p.DomainSuffixMatch)...), nil
+}
+
+// Warnings implements EAPProfile
+func (p EAPPEAP) Warnings() []string {
+	return serverWarnings(p.CACert, p.DomainSuffixMatch)
+}
+
+// EAPTTLS is a TTLS profile, Phase2 is MSCHAPV2 if empty.
+// Phase2 methods prefixed with "EAP-", like EAP-MSCHAPV2, are tunneled EAP methods.
+type EAPTTLS struct {
+	Identity          string
+	AnonymousIdentity string
+	Password          Secret
+	Phase2            string
+	CACert            string
+	DomainSuffixMatch string
+}
+
+// Params implements EAPProfile
+func (p EAPTTLS) Params() ([][2]string, error) {
+	phase2 := "auth=" + orDefault(p.Phase2, "MSCHAPV2")
+	if strings.HasPrefix(p.Phase2, "EAP-") {
+		phase2 = "autheap=" + strings.TrimPrefix(p.Phase2, "EAP-")
+	}
+
+	ps := eapParams("TTLS", p.Identity, p.AnonymousIdentity, p.Password)
+	ps = append(ps, [2]string{"phase2", Quote(phase2)})
+	return append(ps, serverParams(p.CACert, p.DomainSuffixMatch)...), nil
+}
+
+// Warnings implements EAPProfile
+func (p EAPTTLS) Warnings() []string {
+	return serverWarnings(p.CACert, p.DomainSuffixMatch)
+}
+
+// EAPTLS is a TLS profile authenticating with a client certificate,
+// certificates and key are file paths or BlobPrefix references
+type EAPTLS struct {
+	Identity           string
+	ClientCert         string
+	PrivateKey         string
+	PrivateKeyPassword Secret
+	CACert             string
+	DomainSuffixMatch  string
+}
+
+// Params implements EAPProfile
+func (p EAPTLS) Params() ([][2]string, error) {
+	if p.ClientCert == "" || p.PrivateKey == "" {
+		return nil, errors.New("TLS: client certificate and private key are required")
+	}
+
+	ps := eapParams("TLS", p.Identity, "", "")
+	ps = append(ps, [2]string{"client_cert", Quote(p.ClientCert)}, [2]string{"private_key", Quote(p.PrivateKey)})
+	if p.PrivateKeyPassword != "" {
+		ps = append(ps, [2]string{"private_key_passwd", Quote(p.PrivateKeyPassword.Reveal())})
+	}
+
+	return append(ps, serverParams(p.CACert, p.DomainSuffixMatch)...), nil
+}
+
+// Warnings implements EAPProfile
+func (p EAPTLS) Warnings() []string {
+	return serverWarnings(p.CACert, p.DomainSuffixMatch)
+}
+
+// EAPPWD is an EAP-pwd profile, the server is authenticated by the password
+type EAPPWD struct {
+	Identity string
+	Password Secret
+}
+
+// Params implements EAPProfile
+func (p EAPPWD) Params() ([][2]string, error) {
+	return eapParams("PWD", p.Identity, "", p.Password), nil
+}
+
+// Warnings implements EAPProfile
+func (p EAPPWD) Warnings() []string { return nil }
+
+// EAPSIM is an EAP-SIM profile. Identity is read from the SIM card if empty,
+// PIN unlocks the card accessed through PC/SC.
+type EAPSIM struct {
+	Identity string
+	PIN      Secret
+}
+
+// Params implements EAPProfile
+func (p EAPSIM) Params() ([][2]string, error) { return simParams("SIM", p), nil }
+
+// Warnings implements EAPProfile
+func (p EAPSIM) Warnings() []string { return nil }
+
+// EAPAKA is an EAP-AKA profile, see EAPSIM
+type EAPAKA EAPSIM
+
+// Params implements EAPProfile
+func (p EAPAKA) Params() ([][2]string, error) { return simParams("AKA", EAPSIM(p)), nil }
+
+// Warnings implements EAPProfile
+func (p EAPAKA) Warnings() []string { return nil }
+
+// EAPAKAPrime is an EAP-AKA' profile, see EAPSIM
+type EAPAKAPrime EAPSIM
+
+// Params implements EAPProfile
+func (p EAPAKAPrime) Params() ([][2]string, error) { return simParams("AKA'", EAPSIM(p)), nil }
+
+// Warnings implements EAPProfile
+func (p EAPAKAPrime) Warnings() []string { return nil }
+
+func simParams(method string, p EAPSIM) [][2]string {
+	ps := eapParams(method, p.Identity, "", "")
+	if p.PIN != "" {
+		ps = append(ps, [2]string{"pcsc", Quote("")}, [2]string{"pin", Quote(p.PIN.Reveal())})
+	}
+
+	return ps
+}
+
+// eapParams returns eap and identity parameters, empty values are not set
+func eapParams(method, identity, anonymous string, password Secret) [][2]string {
+	ps := [][2]string{{"eap", method}}
+	if identity != "" {
+		ps = append(ps, [2]string{"identity", Quote(identity)})
+	}
+	if anonymous != "" {
+		ps = append(ps, [2]string{"anonymous_identity", Quote(anonymous)})
+	}
+	if password != "" {
+		ps = append(ps, [2]string{"password", Quote(password.Reveal())})
+	}
+
+	return ps
+}
+
+// serverParams returns server authentication parameters
+func serverParams(caCert, domain string) [][2]string {
+	ps := [][2]string{}
+	if caCert != "" {
+		ps = append(ps, [2]string{"ca_cert", Quote(caCert)})
+	}
+	if domain != "" {
+		ps = append(ps, [2]string{"domain_suffix_match", Quote(domain)})
+	}
+
+	return ps
+}
+
+func serverWarnings(caCert, domain string) []string {
+	switch {
+	case caCert == "":
+		return []string{WarnNoCACert}
+	case domain == "":
+		return []string{WarnNoDomainMatch}
+	}
+
+	return nil
+}
+
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+
+	return s
+}
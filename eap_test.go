@@ -0,0 +1,124 @@
+package wpaclient
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEAPProfile(t *testing.T) {
+	tests := []struct {
+		name     string
+		profile  EAPProfile
+		exp      [][2]string
+		warnings []string
+		err      bool
+	}{
+		{
+			name:    "peap",
+			profile: EAPPEAP{Identity: "bob", AnonymousIdentity: "anon", Password: "secret", CACert: "/etc/ca.pem", DomainSuffixMatch: "example.com"},
+			exp: [][2]string{{"eap", "PEAP"}, {"identity", `"bob"`}, {"anonymous_identity", `"anon"`}, {"password", `"secret"`},
+				{"phase2", `"auth=MSCHAPV2"`}, {"ca_cert", `"/etc/ca.pem"`}, {"domain_suffix_match", `"example.com"`}},
+		},
+		{
+			name:     "peap no ca",
+			profile:  EAPPEAP{Identity: "bob"},
+			exp:      [][2]string{{"eap", "PEAP"}, {"identity", `"bob"`}, {"phase2", `"auth=MSCHAPV2"`}},
+			warnings: []string{WarnNoCACert},
+		},
+		{
+			name:     "ttls eap phase2",
+			profile:  EAPTTLS{Identity: "bob", Phase2: "EAP-MSCHAPV2", CACert: BlobPrefix + "ca"},
+			exp:      [][2]string{{"eap", "TTLS"}, {"identity", `"bob"`}, {"phase2", `"autheap=MSCHAPV2"`}, {"ca_cert", `"blob://ca"`}},
+			warnings: []string{WarnNoDomainMatch},
+		},
+		{
+			name:    "ttls pap",
+			profile: EAPTTLS{Identity: "bob", Password: "secret", Phase2: "PAP", CACert: "/etc/ca.pem", DomainSuffixMatch: "example.com"},
+			exp: [][2]string{{"eap", "TTLS"}, {"identity", `"bob"`}, {"password", `"secret"`}, {"phase2", `"auth=PAP"`},
+				{"ca_cert", `"/etc/ca.pem"`}, {"domain_suffix_match", `"example.com"`}},
+		},
+		{
+			name:    "tls",
+			profile: EAPTLS{Identity: "bob", ClientCert: "/etc/bob.pem", PrivateKey: "/etc/bob.key", PrivateKeyPassword: "secret", CACert: "/etc/ca.pem", DomainSuffixMatch: "example.com"},
+			exp: [][2]string{{"eap", "TLS"}, {"identity", `"bob"`}, {"client_cert", `"/etc/bob.pem"`}, {"private_key", `"/etc/bob.key"`},
+				{"private_key_passwd", `"secret"`}, {"ca_cert", `"/etc/ca.pem"`}, {"domain_suffix_match", `"example.com"`}},
+		},
+		{
+			name:     "tls no key",
+			profile:  EAPTLS{Identity: "bob", ClientCert: "/etc/bob.pem"},
+			warnings: []string{WarnNoCACert},
+			err:      true,
+		},
+		{
+			name:    "pwd",
+			profile: EAPPWD{Identity: "bob", Password: "secret"},
+			exp:     [][2]string{{"eap", "PWD"}, {"identity", `"bob"`}, {"password", `"secret"`}},
+		},
+		{
+			name:    "sim",
+			profile: EAPSIM{PIN: "1234"},
+			exp:     [][2]string{{"eap", "SIM"}, {"pcsc", `""`}, {"pin", `"1234"`}},
+		},
+		{
+			name:    "aka",
+			profile: EAPAKA{Identity: "0232010000000000@wlan.mnc232.mcc02.3gppnetwork.org"},
+			exp:     [][2]string{{"eap", "AKA"}, {"identity", `"0232010000000000@wlan.mnc232.mcc02.3gppnetwork.org"`}},
+		},
+		{
+			name:    "aka prime",
+			profile: EAPAKAPrime{},
+			exp:     [][2]string{{"eap", "AKA'"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ps, err := tt.profile.Params()
+			if (err != nil) != tt.err {
+				t.Fatalf("Params expected error %t, got %v", tt.err, err)
+			}
+
+			if !reflect.DeepEqual(ps, tt.exp) {
+				t.Errorf("expected params %v, got %v", tt.exp, ps)
+			}
+
+			if ws := tt.profile.Warnings(); !reflect.DeepEqual(ws, tt.warnings) {
+				t.Errorf("expected warnings %v, got %v", tt.warnings, ws)
+			}
+		})
+	}
+}
+
+func TestNetworkConfigEAP(t *testing.T) {
+	cfg := NetworkConfig{SSID: "corp", EAP: EAPPWD{Identity: "bob"}}
+	ps, err := cfg.params()
+	if err != nil {
+		t.Fatalf("params not expect an error, got %v", err)
+	}
+
+	exp := [][2]string{{"ssid", `"corp"`}, {"key_mgmt", "WPA-EAP"}, {"eap", "PWD"}, {"identity", `"bob"`}}
+	if !reflect.DeepEqual(ps, exp) {
+		t.Errorf("expected params %v, got %v", exp, ps)
+	}
+
+	ts, cleanup := newTestServer(t)
+	defer cleanup()
+
+	c, err := New(ts.addr())
+	if err != nil {
+		t.Fatalf("New Client failed, %v", err)
+	}
+	defer c.Close()
+
+	if _, err := c.Connect(NetworkConfig{SSID: "corp", EAP: EAPTLS{}}); err == nil {
+		t.Error("Connect expect an error for invalid profile")
+	}
+
+	if ts.lastCmd() != "" {
+		t.Errorf("expected nothing to be sent for invalid profile, got %s", ts.lastCmd())
+	}
+
+	if ws := (NetworkConfig{EAP: EAPPEAP{}}).Warnings(); len(ws) != 1 {
+		t.Errorf("expected a warning, got %v", ws)
+	}
+}
@@ -0,0 +1,277 @@
+package wpaclient
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// EAPPhase is the step of an EAP authentication an EAPEvent reports
+type EAPPhase string
+
+// EAP phases
+const (
+	EAPStarted        EAPPhase = "started"
+	EAPProposedMethod EAPPhase = "proposed method"
+	EAPMethodSelected EAPPhase = "method selected"
+	EAPStatus         EAPPhase = "status"
+	EAPNotification   EAPPhase = "notification"
+	EAPPeerCert       EAPPhase = "peer certificate"
+	EAPCertError      EAPPhase = "certificate error"
+	EAPRetransmit     EAPPhase = "retransmit"
+	EAPSuccess        EAPPhase = "success"
+	EAPFailure        EAPPhase = "failure"
+	EAPTimeout        EAPPhase = "timeout"
+)
+
+// eapPhases maps events to phases, second variants are reported by hostapd
+var eapPhases = []struct {
+	event string
+	phase EAPPhase
+}{
+	{WpaEventEapStarted, EAPStarted},
+	{WpaEventEapProposedMethod, EAPProposedMethod},
+	{WpaEventEapMethod, EAPMethodSelected},
+	{WpaEventEapStatus, EAPStatus},
+	{WpaEventEapNotification, EAPNotification},
+	{WpaEventEapPeerCert, EAPPeerCert},
+	{WpaEventEapTLSCertError, EAPCertError},
+	{WpaEventEapRetransmit, EAPRetransmit},
+	{WpaEventEapRetransmit2, EAPRetransmit},
+	{WpaEventEapSuccess, EAPSuccess},
+	{WpaEventEapSuccess2, EAPSuccess},
+	{WpaEventEapFailure, EAPFailure},
+	{WpaEventEapFailure2, EAPFailure},
+	{WpaEventEapTimeoutFailure, EAPTimeout},
+	{WpaEventEapTimeoutFailure2, EAPTimeout},
+}
+
+// eapMethods are the names of EAP method types
+var eapMethods = map[int]string{
+	1: "IDENTITY", 2: "NOTIFICATION", 3: "NAK", 4: "MD5", 5: "OTP", 6: "GTC", 13: "TLS",
+	17: "LEAP", 18: "SIM", 21: "TTLS", 23: "AKA", 25: "PEAP", 26: "MSCHAPV2",
+	43: "FAST", 47: "PSK", 48: "SAKE", 49: "IKEV2", 50: "AKA'", 51: "GPSK", 52: "PWD",
+	53: "EKE", 55: "TEAP", 254: "EXPANDED",
+}
+
+// EAPEvent represents a "CTRL-EVENT-EAP-" event. Method and Vendor are set
+// for proposed and selected methods, Status and Parameter for status events,
+// Parameter holds the text of other events.
+type EAPEvent struct {
+	Phase     EAPPhase
+	Method    string
+	Vendor    int
+	Status    string
+	Parameter string
+}
+
+// IsEAPEvent reports whether ev is an EAP event ParseEAPEvent can parse
+func IsEAPEvent(ev Event) bool {
+	for _, p := range eapPhases {
+		if strings.HasPrefix(ev.Message, p.event) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ParseEAPEvent parses a "CTRL-EVENT-EAP-" event
+func ParseEAPEvent(ev Event) (*EAPEvent, error) {
+	for _, p := range eapPhases {
+		if !strings.HasPrefix(ev.Message, p.event) {
+			continue
+		}
+
+		msg := strings.TrimPrefix(ev.Message, p.event)
+		ee := &EAPEvent{Phase: p.phase}
+
+		switch p.phase {
+		case EAPProposedMethod:
+			// vendor=0 method=25, " -> NAK" is appended if refused
+			ps := parseQuotedParams(msg)
+			if i := strings.Index(msg, "->"); i >= 0 {
+				ee.Parameter = strings.TrimSpace(msg[i+2:])
+			}
+			if err := ee.setMethod(ps["vendor"], ps["method"]); err != nil {
+				return nil, err
+			}
+		case EAPMethodSelected:
+			// EAP vendor 0 method 25 (PEAP) selected
+			fs := strings.Fields(msg)
+			if len(fs) < 5 || fs[1] != "vendor" || fs[3] != "method" {
+				return nil, fmt.Errorf("parse method: %s", msg)
+			}
+			if err := ee.setMethod(fs[2], fs[4]); err != nil {
+				return nil, err
+			}
+			if len(fs) > 5 && strings.HasPrefix(fs[5], "(") {
+				ee.Method = strings.Trim(fs[5], "()")
+			}
+		case EAPStatus:
+			// status='started' parameter=''
+			ps := parseQuotedParams(msg)
+			ee.Status, ee.Parameter = ps["status"], ps["parameter"]
+		default:
+			ee.Parameter = msg
+		}
+
+		return ee, nil
+	}
+
+	return nil, fmt.Errorf("not an EAP event: %s", ev.Message)
+}
+
+func (ee *EAPEvent) setMethod(vendor, method string) error {
+	v, err := strconv.Atoi(vendor)
+	if err != nil {
+		return fmt.Errorf("parse vendor: %w", err)
+	}
+
+	m, err := strconv.Atoi(method)
+	if err != nil {
+		return fmt.Errorf("parse method: %w", err)
+	}
+
+	ee.Vendor, ee.Method = v, strconv.Itoa(m)
+	if name, ok := eapMethods[m]; ok && v == 0 {
+		ee.Method = name
+	}
+
+	return nil
+}
+
+// EAPOutcome classifies the result of an authentication attempt
+type EAPOutcome int
+
+// EAP outcomes
+const (
+	EAPInProgress EAPOutcome = iota
+	EAPSucceeded
+	EAPFailed
+	EAPBadCredentials
+	EAPServerCertRejected
+	EAPMethodNotSupported
+	EAPTimedOut
+	EAPNoServerResponse
+)
+
+var eapOutcomes = []string{
+	"authentication in progress",
+	"authentication succeeded",
+	"authentication failed",
+	"credentials rejected by the authentication server",
+	"server certificate rejected",
+	"EAP method not supported by the authentication server",
+	"authentication timed out",
+	"no response from the authentication server",
+}
+
+func (o EAPOutcome) String() string {
+	if o < 0 || int(o) >= len(eapOutcomes) {
+		return "outcome " + strconv.Itoa(int(o))
+	}
+
+	return eapOutcomes[o]
+}
+
+// EAPSession collects the events of an authentication attempt,
+// "CTRL-EVENT-EAP-STARTED" starts a new attempt discarding previous events
+type EAPSession struct {
+	Events    []EAPEvent
+	Method    string
+	PeerCerts []*PeerCert
+	CertError *CertError
+
+	refused  bool
+	certFail bool
+	done     bool
+	outcome  EAPOutcome
+}
+
+// Add adds ev to the session, non EAP events are ignored.
+// It reports whether the attempt is finished.
+func (s *EAPSession) Add(ev Event) bool {
+	if strings.HasPrefix(ev.Message, WpaEventEapPeerAlt) {
+		for i := len(s.PeerCerts) - 1; i >= 0; i-- {
+			if s.PeerCerts[i].AddAlt(ev) == nil {
+				break
+			}
+		}
+		return s.done
+	}
+
+	ee, err := ParseEAPEvent(ev)
+	if err != nil {
+		return s.done
+	}
+
+	if ee.Phase == EAPStarted && len(s.Events) > 0 {
+		*s = EAPSession{}
+	}
+	s.Events = append(s.Events, *ee)
+
+	switch ee.Phase {
+	case EAPProposedMethod:
+		if ee.Parameter == "NAK" {
+			s.refused = true
+		}
+	case EAPMethodSelected:
+		s.Method = ee.Method
+	case EAPStatus:
+		switch {
+		case ee.Status == "refuse proposed method":
+			s.refused = true
+		case ee.Status == "remote certificate verification" && ee.Parameter != "success",
+			ee.Status == "local TLS alert":
+			s.certFail = true
+		}
+	case EAPPeerCert:
+		if pc, err := ParsePeerCert(ev); err == nil {
+			s.PeerCerts = append(s.PeerCerts, pc)
+		}
+	case EAPCertError:
+		if ce, err := ParseCertError(ev); err == nil {
+			s.CertError = ce
+		}
+		s.certFail = true
+	case EAPSuccess:
+		s.finish(EAPSucceeded)
+	case EAPFailure:
+		s.finish(s.failure())
+	case EAPTimeout:
+		if s.Method == "" {
+			s.finish(EAPNoServerResponse)
+		} else {
+			s.finish(EAPTimedOut)
+		}
+	}
+
+	return s.done
+}
+
+// failure classifies a failed attempt by the events preceding it
+func (s *EAPSession) failure() EAPOutcome {
+	switch {
+	case s.certFail:
+		return EAPServerCertRejected
+	case s.Method == "" && s.refused:
+		return EAPMethodNotSupported
+	case s.Method != "":
+		return EAPBadCredentials
+	}
+
+	return EAPFailed
+}
+
+func (s *EAPSession) finish(o EAPOutcome) {
+	if !s.done {
+		s.done, s.outcome = true, o
+	}
+}
+
+// Done reports whether the attempt is finished
+func (s *EAPSession) Done() bool { return s.done }
+
+// Outcome returns the classified result of the attempt
+func (s *EAPSession) Outcome() EAPOutcome { return s.outcome }
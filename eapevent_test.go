@@ -0,0 +1,150 @@
+package wpaclient
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseEAPEvent(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  string
+		exp  *EAPEvent
+		err  bool
+	}{
+		{name: "started", msg: WpaEventEapStarted + "EAP authentication started",
+			exp: &EAPEvent{Phase: EAPStarted, Parameter: "EAP authentication started"}},
+		{name: "proposed", msg: WpaEventEapProposedMethod + "vendor=0 method=25",
+			exp: &EAPEvent{Phase: EAPProposedMethod, Method: "PEAP"}},
+		{name: "proposed nak", msg: WpaEventEapProposedMethod + "vendor=0 method=4 -> NAK",
+			exp: &EAPEvent{Phase: EAPProposedMethod, Method: "MD5", Parameter: "NAK"}},
+		{name: "proposed vendor", msg: WpaEventEapProposedMethod + "vendor=40808 method=1",
+			exp: &EAPEvent{Phase: EAPProposedMethod, Method: "1", Vendor: 40808}},
+		{name: "method", msg: WpaEventEapMethod + "EAP vendor 0 method 21 (TTLS) selected",
+			exp: &EAPEvent{Phase: EAPMethodSelected, Method: "TTLS"}},
+		{name: "status", msg: WpaEventEapStatus + "status='remote certificate verification' parameter='success'",
+			exp: &EAPEvent{Phase: EAPStatus, Status: "remote certificate verification", Parameter: "success"}},
+		{name: "empty status parameter", msg: WpaEventEapStatus + "status='started' parameter=''",
+			exp: &EAPEvent{Phase: EAPStatus, Status: "started"}},
+		{name: "failure", msg: WpaEventEapFailure + "EAP authentication failed",
+			exp: &EAPEvent{Phase: EAPFailure, Parameter: "EAP authentication failed"}},
+		{name: "failure2", msg: WpaEventEapFailure2 + "EAP authentication failed",
+			exp: &EAPEvent{Phase: EAPFailure, Parameter: "EAP authentication failed"}},
+		{name: "bad method", msg: WpaEventEapMethod + "EAP method selected", err: true},
+		{name: "bad proposed", msg: WpaEventEapProposedMethod + "vendor=0", err: true},
+		{name: "not eap", msg: WpaEventConnected + "- Connection completed", err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ee, err := ParseEAPEvent(Event{Message: tt.msg})
+			if (err != nil) != tt.err {
+				t.Fatalf("ParseEAPEvent expected error %t, got %v", tt.err, err)
+			}
+
+			if !reflect.DeepEqual(ee, tt.exp) {
+				t.Errorf("expected %+v, got %+v", tt.exp, ee)
+			}
+
+			if IsEAPEvent(Event{Message: tt.msg}) != (tt.name != "not eap") {
+				t.Errorf("IsEAPEvent unexpected result for %s", tt.msg)
+			}
+		})
+	}
+}
+
+func TestEAPSession(t *testing.T) {
+	started := []string{
+		WpaEventEapStarted + "EAP authentication started",
+		WpaEventEapStatus + "status='started' parameter=''",
+	}
+	peap := append(started,
+		WpaEventEapProposedMethod+"vendor=0 method=25",
+		WpaEventEapStatus+"status='accept proposed method' parameter='PEAP'",
+		WpaEventEapMethod+"EAP vendor 0 method 25 (PEAP) selected",
+		WpaEventEapPeerCert+"depth=0 subject='/CN=radius.example.com' hash=abcd",
+		WpaEventEapPeerAlt+"depth=0 DNS:radius.example.com",
+	)
+
+	tests := []struct {
+		name string
+		msgs []string
+		exp  EAPOutcome
+	}{
+		{name: "in progress", msgs: started, exp: EAPInProgress},
+		{name: "success", msgs: append(peap[:len(peap):len(peap)],
+			WpaEventEapStatus+"status='remote certificate verification' parameter='success'",
+			WpaEventEapStatus+"status='completion' parameter='success'",
+			WpaEventEapSuccess+"EAP authentication completed successfully",
+		), exp: EAPSucceeded},
+		{name: "bad credentials", msgs: append(peap[:len(peap):len(peap)],
+			WpaEventEapStatus+"status='remote certificate verification' parameter='success'",
+			WpaEventEapStatus+"status='completion' parameter='failure'",
+			WpaEventEapFailure+"EAP authentication failed",
+		), exp: EAPBadCredentials},
+		{name: "cert rejected", msgs: append(peap[:len(peap):len(peap)],
+			WpaEventEapTLSCertError+"reason=1 depth=0 subject='/CN=radius.example.com' err='self signed certificate'",
+			WpaEventEapStatus+"status='local TLS alert' parameter='unknown CA'",
+			WpaEventEapFailure+"EAP authentication failed",
+		), exp: EAPServerCertRejected},
+		{name: "method not supported", msgs: append(started[:len(started):len(started)],
+			WpaEventEapProposedMethod+"vendor=0 method=4 -> NAK",
+			WpaEventEapStatus+"status='refuse proposed method' parameter='MD5'",
+			WpaEventEapFailure+"EAP authentication failed",
+		), exp: EAPMethodNotSupported},
+		{name: "no server response", msgs: append(started[:len(started):len(started)],
+			WpaEventEapRetransmit+"EAP retransmit",
+			WpaEventEapTimeoutFailure+"EAP authentication failed due to no response received",
+		), exp: EAPNoServerResponse},
+		{name: "timeout", msgs: append(peap[:len(peap):len(peap)],
+			WpaEventEapTimeoutFailure+"EAP authentication failed due to no response received",
+		), exp: EAPTimedOut},
+		{name: "failed", msgs: append(started[:len(started):len(started)],
+			WpaEventEapFailure+"EAP authentication failed",
+		), exp: EAPFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &EAPSession{}
+			s.Add(Event{Message: WpaEventEapStarted + "EAP authentication started"})
+			s.Add(Event{Message: WpaEventEapFailure + "EAP authentication failed"})
+
+			done := false
+			for _, m := range tt.msgs {
+				done = s.Add(Event{Message: m})
+				s.Add(Event{Message: WpaEventConnected + "- Connection completed"})
+			}
+
+			if done != (tt.exp != EAPInProgress) || s.Done() != done {
+				t.Errorf("expected done %t, got %t", tt.exp != EAPInProgress, done)
+			}
+
+			if s.Outcome() != tt.exp {
+				t.Errorf("expected outcome %q, got %q", tt.exp, s.Outcome())
+			}
+
+			// alternative subjects are added to peer certificates
+			n := 0
+			for _, m := range tt.msgs {
+				if !strings.HasPrefix(m, WpaEventEapPeerAlt) {
+					n++
+				}
+			}
+
+			if len(s.Events) != n {
+				t.Errorf("expected %d events of the last attempt, got %d", n, len(s.Events))
+			}
+		})
+	}
+
+	s := &EAPSession{}
+	for _, m := range peap {
+		s.Add(Event{Message: m})
+	}
+
+	if s.Method != "PEAP" || len(s.PeerCerts) != 1 || !reflect.DeepEqual(s.PeerCerts[0].AltSubject, []string{"DNS:radius.example.com"}) {
+		t.Errorf("unexpected session %+v", s)
+	}
+}
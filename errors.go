@@ -10,7 +10,9 @@ var ErrUnknownCmd = constError("unknown command")
 // ErrCmdFailed returned when a FAIL message received
 var ErrCmdFailed = constError("command failed")
 
-// InvalidCmdError returned when Invalid COMMAND message received
+// InvalidCmdError returned when Invalid COMMAND message received.
+// Err is the reason given by wpa_supplicant, command arguments are
+// never included so secrets do not end up in error messages.
 type InvalidCmdError struct {
 	Cmd string
 	Err string
@@ -1,6 +1,7 @@
 package wpaclient
 
 import (
+	"bytes"
 	"fmt"
 	"strconv"
 	"strings"
@@ -15,6 +16,8 @@ type AuthReq struct {
 
 // Event represends events received from wpa_supplicant
 type Event struct {
+	// Ifname is set for events received through global control interface
+	Ifname  string
 	Sev     int
 	Message string
 	AuthReq *AuthReq
@@ -22,6 +25,21 @@ type Event struct {
 }
 
 func parseEvent(b []byte) *Event {
+	ifname := ""
+	if bytes.HasPrefix(b, []byte(CmdIfname+"=")) {
+		if i := bytes.IndexByte(b, ' '); i > 0 {
+			ifname = string(b[len(CmdIfname)+1 : i])
+			b = b[i+1:]
+		}
+	}
+
+	ev := parse(b)
+	ev.Ifname = ifname
+
+	return ev
+}
+
+func parse(b []byte) *Event {
 	if len(b) < 5 {
 		msg := strings.TrimSuffix(string(b), "\n")
 		return &Event{Err: fmt.Errorf("message too short: %s", msg)}
@@ -28,6 +28,11 @@ func TestParseEvent(t *testing.T) {
 			buf:  []byte(fmt.Sprintf("<2>%s\n", WpaEventConnected)),
 			ev:   &Event{Sev: 2, Message: WpaEventConnected},
 		},
+		{
+			name: "event global interface",
+			buf:  []byte(fmt.Sprintf("IFNAME=wlan0 <2>%s\n", WpaEventConnected)),
+			ev:   &Event{Ifname: "wlan0", Sev: 2, Message: WpaEventConnected},
+		},
 		{
 			name: "event auth failed",
 			buf:  []byte(fmt.Sprintf("<3>%sOTP-T:Challenge 1235663 needed for SSID foobar\n", WpaCtrlReq)),
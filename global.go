@@ -0,0 +1,101 @@
+package wpaclient
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Global represents a client of wpa_supplicant global control interface,
+// started with "wpa_supplicant -g /var/run/wpa_supplicant-global"
+type Global struct {
+	*Client
+}
+
+// NewGlobal returns a new Global connected to addr,
+// default global control interface is used if addr is empty
+func NewGlobal(addr string, opts ...Option) (*Global, error) {
+	if addr == "" {
+		addr = globalAddr
+	}
+
+	c, err := New(addr, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Global{Client: c}, nil
+}
+
+// Interfaces executes "INTERFACES" command and returns the names of
+// the interfaces managed by wpa_supplicant
+func (g *Global) Interfaces() ([]string, error) {
+	res, err := g.Execute(CmdInterfaces)
+	if err != nil {
+		return nil, err
+	}
+
+	ifs := []string{}
+	for _, l := range strings.Split(string(bytes.TrimSpace(res)), "\n") {
+		if l = strings.TrimSpace(l); l != "" {
+			ifs = append(ifs, l)
+		}
+	}
+
+	return ifs, nil
+}
+
+// DriverInterface represents an interface a driver can offer,
+// returned from "INTERFACE_LIST" command
+type DriverInterface struct {
+	Driver string
+	Ifname string
+	Desc   string
+}
+
+// InterfaceList executes "INTERFACE_LIST" command and returns the interfaces
+// drivers can offer, use Interfaces for the ones managed by wpa_supplicant
+func (g *Global) InterfaceList() ([]DriverInterface, error) {
+	res, err := g.Execute(CmdInterfaceList)
+	if err != nil {
+		return nil, err
+	}
+
+	ifs := []DriverInterface{}
+	for _, l := range strings.Split(string(bytes.TrimSpace(res)), "\n") {
+		if strings.TrimSpace(l) == "" {
+			continue
+		}
+
+		f := strings.SplitN(l, "\t", 3)
+		if len(f) != 3 {
+			return nil, fmt.Errorf("invalid interface list line %q", l)
+		}
+		ifs = append(ifs, DriverInterface{Driver: f[0], Ifname: f[1], Desc: f[2]})
+	}
+
+	return ifs, nil
+}
+
+// InterfaceAdd executes "INTERFACE_ADD" command, adds an interface to wpa_supplicant.
+// Only ifname is required, empty arguments are left to wpa_supplicant defaults.
+func (g *Global) InterfaceAdd(ifname, confname, driver, ctrlInterface, driverParam, bridge string) error {
+	arg := strings.Join([]string{ifname, confname, driver, ctrlInterface, driverParam, bridge}, "\t")
+
+	_, err := g.Execute(CmdInterfaceAdd, strings.TrimRight(arg, "\t"))
+	return err
+}
+
+// InterfaceRemove executes "INTERFACE_REMOVE" command, removes an interface from wpa_supplicant
+func (g *Global) InterfaceRemove(ifname string) error {
+	_, err := g.Execute(CmdInterfaceRemove, ifname)
+	return err
+}
+
+// Interface returns a Client running commands on interface ifname through
+// the global control interface, using "IFNAME=<ifname> <cmd>" routing.
+// Returned Client shares the sockets of Global, closing Global closes it.
+func (g *Global) Interface(ifname string) *Client {
+	return &Client{addr: g.addr, cmdsock: g.cmdsock, cmdmut: g.cmdmut, rec: g.rec, hooks: g.hooks,
+		prompter: g.prompter, hand: &handlers{}, amut: g.amut, ifname: ifname, global: g.Client}
+}
@@ -0,0 +1,97 @@
+package wpaclient
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGlobal(t *testing.T) {
+	ts, close := newTestServer(t)
+	defer close()
+
+	g, err := NewGlobal(ts.addr())
+	if err != nil {
+		t.Fatalf("NewGlobal failed, %v", err)
+	}
+	defer g.Close()
+
+	ts.setCmd(CmdInterfaces, "wlan0\np2p-dev-wlan0\n")
+	ifs, err := g.Interfaces()
+	if err != nil {
+		t.Errorf("Interfaces not expect an error, got %v", err)
+	}
+	if !reflect.DeepEqual(ifs, []string{"wlan0", "p2p-dev-wlan0"}) {
+		t.Errorf("Interfaces expected [wlan0 p2p-dev-wlan0], got %v", ifs)
+	}
+
+	ts.setCmd(CmdInterfaceList, "ndis\t\\Device\\NPF_{2D6F6E63-9A2B-4F3C-A3F1-4D8B2B7C1E11}\tIntel(R) Wi-Fi 6 AX201 160MHz\n")
+	dis, err := g.InterfaceList()
+	if err != nil {
+		t.Errorf("InterfaceList not expect an error, got %v", err)
+	}
+	exp := []DriverInterface{{Driver: "ndis", Ifname: `\Device\NPF_{2D6F6E63-9A2B-4F3C-A3F1-4D8B2B7C1E11}`,
+		Desc: "Intel(R) Wi-Fi 6 AX201 160MHz"}}
+	if !reflect.DeepEqual(dis, exp) {
+		t.Errorf("InterfaceList expected %v, got %v", exp, dis)
+	}
+
+	// no driver offers interfaces on Linux
+	ts.setCmd(CmdInterfaceList, "")
+	if dis, err := g.InterfaceList(); err != nil || len(dis) != 0 {
+		t.Errorf("InterfaceList expected no interfaces, got %v, %v", dis, err)
+	}
+
+	ts.setCmd(CmdInterfaceAdd, "OK")
+	if err := g.InterfaceAdd("wlan2", "/etc/wpa.conf", "nl80211", "", "", ""); err != nil {
+		t.Errorf("InterfaceAdd not expect an error, got %v", err)
+	}
+	if exp := "INTERFACE_ADD wlan2\t/etc/wpa.conf\tnl80211"; ts.lastCmd() != exp {
+		t.Errorf("InterfaceAdd expected to send %q, got %q", exp, ts.lastCmd())
+	}
+
+	ts.setCmd(CmdInterfaceRemove, "FAIL")
+	if err := g.InterfaceRemove("wlan2"); err != ErrCmdFailed {
+		t.Errorf("InterfaceRemove expect error %v, got %v", ErrCmdFailed, err)
+	}
+
+	wlan0 := g.Interface("wlan0")
+	if _, err := wlan0.Execute(CmdPing); err != nil {
+		t.Errorf("Execute not expect an error, got %v", err)
+	}
+	if exp := "IFNAME=wlan0 PING"; ts.lastCmd() != exp {
+		t.Errorf("Execute expected to send %q, got %q", exp, ts.lastCmd())
+	}
+
+	ch, err := wlan0.Notify()
+	if err != nil {
+		t.Fatalf("Notify not expect an error, got %v", err)
+	}
+	defer wlan0.Stop(ch)
+
+	if _, err := g.Execute("IFEVENTS"); err != nil {
+		t.Errorf("Execute not expect an error, got %v", err)
+	}
+
+	select {
+	case ev := <-ch:
+		exp := Event{Ifname: "wlan0", Sev: 3, Message: WpaEventConnected}
+		if !reflect.DeepEqual(ev, exp) {
+			t.Errorf("Event expected %#v, got %#v", exp, ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("event not received")
+	}
+
+	time.Sleep(time.Millisecond * 10)
+	if len(ch) != 0 {
+		t.Errorf("expect events of other interfaces to be filtered, got %d", len(ch))
+	}
+
+	if err := wlan0.Close(); err != nil {
+		t.Errorf("Close not expect an error, got %v", err)
+	}
+	if _, err := g.Execute(CmdPing); err != nil {
+		t.Errorf("Global expected to be usable after interface Close, got %v", err)
+	}
+}
@@ -0,0 +1,261 @@
+package wpaclient
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// Redacted replaces secret values passed to hooks
+const Redacted = "[REDACTED]"
+
+// Hook is called for every command exchange and event of a Client,
+// it can be used for logging, metrics or tracing like a span per Execute.
+// Secret arguments are redacted before hooks are called.
+type Hook interface {
+	// OnCommand is called after cmd is executed, resp is the raw response
+	OnCommand(cmd string, args []string, dur time.Duration, resp []byte, err error)
+	// OnEvent is called for every received event
+	OnEvent(ev Event)
+}
+
+// WithHook calls h for every command and event, it can be given more than once
+func WithHook(h Hook) Option {
+	return func(c *Client) {
+		c.hooks = append(c.hooks, h)
+	}
+}
+
+// WithLogger logs every command at debug level, failed ones at warn level,
+// and every event at info level to l
+func WithLogger(l *slog.Logger) Option {
+	return WithHook(&logHook{l: l})
+}
+
+type logHook struct {
+	l *slog.Logger
+}
+
+func (h *logHook) OnCommand(cmd string, args []string, dur time.Duration, resp []byte, err error) {
+	lvl := slog.LevelDebug
+	attrs := []slog.Attr{
+		slog.String("cmd", cmd),
+		slog.Any("args", args),
+		slog.Duration("duration", dur),
+		slog.String("response", strings.TrimSuffix(string(resp), "\n")),
+	}
+
+	if err != nil {
+		lvl = slog.LevelWarn
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+
+	h.l.LogAttrs(context.Background(), lvl, "wpa_supplicant command", attrs...)
+}
+
+func (h *logHook) OnEvent(ev Event) {
+	attrs := []slog.Attr{slog.Int("level", ev.Sev), slog.String("message", ev.Message)}
+	if ev.Ifname != "" {
+		attrs = append(attrs, slog.String("ifname", ev.Ifname))
+	}
+
+	if ev.Err != nil {
+		h.l.LogAttrs(context.Background(), slog.LevelWarn, "wpa_supplicant event",
+			slog.String("error", ev.Err.Error()))
+		return
+	}
+
+	h.l.LogAttrs(context.Background(), slog.LevelInfo, "wpa_supplicant event", attrs...)
+}
+
+// secretNetworkVars are the network and credential variables holding secrets
+var secretNetworkVars = map[string]bool{
+	"psk": true, "password": true, "sae_password": true, "private_key_passwd": true,
+	"private_key2_passwd": true, "wep_key0": true, "wep_key1": true, "wep_key2": true,
+	"wep_key3": true, "pin": true, "machine_password": true, "dpp_netaccesskey": true,
+}
+
+// secretArgs maps commands to the index their secret arguments start at
+var secretArgs = map[string]int{
+	CmdPassword:    1,
+	CmdNewPassword: 1,
+	CmdPin:         1,
+	CmdOtp:         1,
+	CmdPassphrase:  1,
+	CmdSim:         1,
+	CmdWpsPin:      1,
+	CmdWpsReg:      1,
+	CmdWpsErPin:    1,
+	CmdWpsErLearn:  1,
+	CmdWpsErConfig: 1,
+	CmdWpsCheckPin: 0,
+	CmdWpsApPin:    1,
+}
+
+// secretResponses are the commands responding with a WPS PIN or a passphrase
+var secretResponses = map[string]bool{CmdWpsPin: true, CmdWpsApPin: true, CmdWpsCheckPin: true,
+	CmdP2pConnect: true, CmdP2pGetPassphrase: true, CmdDppConfiguratorGetKey: true}
+
+// secretParams maps commands to their key=value arguments holding secrets
+var secretParams = map[string][]string{
+	CmdDppAuthInit:         {"pass", "psk"},
+	CmdDppConfiguratorSign: {"pass", "psk"},
+	CmdDppConfiguratorAdd:  {"key"},
+	CmdDppBootstrapGen:     {"key"},
+}
+
+// secretEvents are the events carrying credentials after their name
+var secretEvents = []string{WpsEventCredReceived, WpsEventErApSettings, DppEventConfobjPass,
+	DppEventConfobjPsk, DppEventNetAccessKey}
+
+// secretEventParams are the events carrying credentials in their parameters
+var secretEventParams = map[string][]string{P2pEventGroupStarted: {"passphrase", "psk"}}
+
+// redactEvent returns a raw event or an event message with credentials redacted
+func redactEvent(s string) string {
+	for _, e := range secretEvents {
+		if i := strings.Index(s, e); i >= 0 {
+			return s[:i+len(e)] + Redacted
+		}
+	}
+
+	for e, ps := range secretEventParams {
+		if !strings.Contains(s, e) {
+			continue
+		}
+
+		for _, p := range ps {
+			s = redactParam(s, p)
+		}
+	}
+
+	return s
+}
+
+// redactParam replaces the value of " name=value" or " name=\"value\"" in s
+func redactParam(s, name string) string {
+	i := strings.Index(s, " "+name+"=")
+	if i < 0 {
+		return s
+	}
+	i += len(name) + 2
+
+	j := strings.IndexByte(s[i:]+" ", ' ')
+	if strings.HasPrefix(s[i:], `"`) {
+		// quoted values can hold spaces and quotes, it is safer to redact
+		// up to the last quote followed by a space
+		i++
+		if j = strings.LastIndex(s[i:]+" ", `" `); j < 0 {
+			j = len(s) - i
+		}
+	}
+
+	return s[:i] + Redacted + s[i+j:]
+}
+
+// redact returns cmd and args with secret values replaced by Redacted,
+// args are split on spaces, so arguments given as a single string are handled
+func redact(cmd string, args []string) (string, []string) {
+	if strings.HasPrefix(cmd, WpaCtrlRsp) {
+		if i := strings.IndexByte(cmd, ':'); i > 0 {
+			return cmd[:i+1] + Redacted, nil
+		}
+	}
+
+	fs := strings.Fields(strings.Join(args, " "))
+
+	i, ok := secretArgs[cmd]
+	switch cmd {
+	case CmdSetNetwork, CmdSetCred:
+		// <id> <name> <value>
+		if len(fs) > 2 && secretNetworkVars[fs[1]] {
+			i, ok = 2, true
+		}
+	case CmdWpsApPin:
+		// "WPS_AP_PIN set <pin>", "random" and "get" return the pin
+		ok = len(fs) > 1 && fs[0] == "set"
+	case CmdP2pConnect:
+		// "P2P_CONNECT <addr> <pin> ...", "pbc" and "pin" have no secret
+		i, ok = 1, len(fs) > 1 && fs[1] != "pbc" && fs[1] != "pin"
+	case CmdSet:
+		// "SET blob <name> <hex>", blobs can hold private keys
+		if len(fs) > 2 && fs[0] == "blob" {
+			i, ok = 2, true
+		}
+	case CmdDppPkexAdd:
+		// "code=<code>" is the last argument, the code can hold spaces
+		for k, f := range fs {
+			if strings.HasPrefix(f, "code=") {
+				fs = append(fs[:k], "code="+Redacted)
+				break
+			}
+		}
+	}
+
+	for k, f := range fs {
+		for _, p := range secretParams[cmd] {
+			if strings.HasPrefix(f, p+"=") {
+				fs[k] = p + "=" + Redacted
+			}
+		}
+	}
+
+	if !ok || i >= len(fs) {
+		return cmd, fs
+	}
+
+	return cmd, append(fs[:i], Redacted)
+}
+
+// redactCommand redacts a raw command datagram, see redact. It is returned
+// unchanged if nothing is redacted. secretRes reports whether its response
+// holds a secret.
+func redactCommand(b string) (s string, secretRes bool) {
+	prefix := ""
+	if strings.HasPrefix(b, CmdIfname+"=") {
+		if i := strings.IndexByte(b, ' '); i > 0 {
+			prefix, b = b[:i+1], b[i+1:]
+		}
+	}
+
+	fs := strings.SplitN(b, " ", 2)
+	cmd, args := redact(fs[0], fs[1:])
+	secretRes = secretResponses[fs[0]]
+	if cmd == fs[0] && !strings.Contains(strings.Join(args, " "), Redacted) {
+		return prefix + b, secretRes
+	}
+
+	if len(args) == 0 {
+		return prefix + cmd, secretRes
+	}
+
+	return prefix + cmd + " " + strings.Join(args, " "), secretRes
+}
+
+// onCommand calls hooks with secrets redacted
+func (c *Client) onCommand(cmd string, args []string, dur time.Duration, resp []byte, err error) {
+	if len(c.hooks) == 0 {
+		return
+	}
+
+	if secretResponses[cmd] && resp != nil {
+		resp = []byte(Redacted)
+	}
+
+	cmd, args = redact(cmd, args)
+	for _, h := range c.hooks {
+		h.OnCommand(cmd, args, dur, resp, err)
+	}
+}
+
+func (c *Client) onEvent(ev Event) {
+	if len(c.hooks) == 0 {
+		return
+	}
+
+	ev.Message = redactEvent(ev.Message)
+	for _, h := range c.hooks {
+		h.OnEvent(ev)
+	}
+}
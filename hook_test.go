@@ -0,0 +1,157 @@
+package wpaclient
+
+import (
+	"bytes"
+	"log/slog"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		name    string
+		cmd     string
+		args    []string
+		expCmd  string
+		expArgs []string
+	}{
+		{name: "no secret", cmd: CmdSetNetwork, args: []string{"0", "ssid", `"home"`},
+			expCmd: CmdSetNetwork, expArgs: []string{"0", "ssid", `"home"`}},
+		{name: "psk", cmd: CmdSetNetwork, args: []string{"0", "psk", `"secret"`},
+			expCmd: CmdSetNetwork, expArgs: []string{"0", "psk", Redacted}},
+		{name: "single arg", cmd: CmdSetNetwork, args: []string{`0 sae_password "a b c"`},
+			expCmd: CmdSetNetwork, expArgs: []string{"0", "sae_password", Redacted}},
+		{name: "cred", cmd: CmdSetCred, args: []string{"0", "password", `"secret"`},
+			expCmd: CmdSetCred, expArgs: []string{"0", "password", Redacted}},
+		{name: "password", cmd: CmdPassword, args: []string{"1", "secret"},
+			expCmd: CmdPassword, expArgs: []string{"1", Redacted}},
+		{name: "wps pin", cmd: CmdWpsPin, args: []string{"any", "12345670"},
+			expCmd: CmdWpsPin, expArgs: []string{"any", Redacted}},
+		{name: "wps pin generated", cmd: CmdWpsPin, args: []string{"any"},
+			expCmd: CmdWpsPin, expArgs: []string{"any"}},
+		{name: "wps check pin", cmd: CmdWpsCheckPin, args: []string{"12345670"},
+			expCmd: CmdWpsCheckPin, expArgs: []string{Redacted}},
+		{name: "wps ap pin get", cmd: CmdWpsApPin, args: []string{"get"},
+			expCmd: CmdWpsApPin, expArgs: []string{"get"}},
+		{name: "wps ap pin set", cmd: CmdWpsApPin, args: []string{"set", "12345670", "300"},
+			expCmd: CmdWpsApPin, expArgs: []string{"set", Redacted}},
+		{name: "blob", cmd: CmdSet, args: []string{"blob", "key", "deadbeef"},
+			expCmd: CmdSet, expArgs: []string{"blob", "key", Redacted}},
+		{name: "set", cmd: CmdSet, args: []string{"update_config", "1"},
+			expCmd: CmdSet, expArgs: []string{"update_config", "1"}},
+		{name: "ctrl rsp", cmd: WpaCtrlRsp + "PASSWORD-1:secret",
+			expCmd: WpaCtrlRsp + "PASSWORD-1:" + Redacted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd, args := redact(tt.cmd, tt.args)
+			if cmd != tt.expCmd {
+				t.Errorf("expected cmd %s, got %s", tt.expCmd, cmd)
+			}
+
+			if len(args) != 0 || len(tt.expArgs) != 0 {
+				if !reflect.DeepEqual(args, tt.expArgs) {
+					t.Errorf("expected args %q, got %q", tt.expArgs, args)
+				}
+			}
+		})
+	}
+}
+
+type testHook struct {
+	cmds []string
+	resp []string
+	errs []error
+	evs  chan Event
+}
+
+func (h *testHook) OnCommand(cmd string, args []string, dur time.Duration, resp []byte, err error) {
+	h.cmds = append(h.cmds, strings.Join(append([]string{cmd}, args...), " "))
+	h.resp = append(h.resp, string(resp))
+	h.errs = append(h.errs, err)
+}
+
+func (h *testHook) OnEvent(ev Event) {
+	h.evs <- ev
+}
+
+// syncBuffer is written by the event goroutine of Client
+type syncBuffer struct {
+	mut sync.Mutex
+	b   bytes.Buffer
+}
+
+func (s *syncBuffer) Write(p []byte) (int, error) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return s.b.Write(p)
+}
+
+func (s *syncBuffer) String() string {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return s.b.String()
+}
+
+func TestHook(t *testing.T) {
+	ts, cleanup := newTestServer(t)
+	defer cleanup()
+
+	ts.setCmd(CmdWpsPin, "12345670")
+
+	h := &testHook{evs: make(chan Event, 10)}
+	logs := &syncBuffer{}
+	l := slog.New(slog.NewTextHandler(logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	c, err := New(ts.addr(), WithHook(h), WithLogger(l))
+	if err != nil {
+		t.Fatalf("New Client failed, %v", err)
+	}
+	defer c.Close()
+
+	c.Execute(CmdAddNetwork)
+	c.Execute(CmdSetNetwork, "0", "psk", `"secret"`)
+	c.Execute(CmdWpsPin, "any")
+	c.Execute("NOT_A_COMMAND")
+
+	exp := []string{CmdAddNetwork, CmdSetNetwork + " 0 psk " + Redacted, CmdWpsPin + " any", "NOT_A_COMMAND"}
+	if !reflect.DeepEqual(h.cmds, exp) {
+		t.Errorf("expected commands %q, got %q", exp, h.cmds)
+	}
+
+	if h.resp[2] != Redacted {
+		t.Errorf("expected WPS_PIN response to be redacted, got %q", h.resp[2])
+	}
+
+	if h.errs[3] != ErrUnknownCmd {
+		t.Errorf("expected error %v, got %v", ErrUnknownCmd, h.errs[3])
+	}
+
+	if _, err := c.Notify(); err != nil {
+		t.Fatalf("Notify not expect an error, got %v", err)
+	}
+	c.Execute("EVENTS")
+
+	select {
+	case ev := <-h.evs:
+		if ev.Message != WpaEventAvoidFreq {
+			t.Errorf("expected %s event, got %#v", WpaEventAvoidFreq, ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("event not received by hook")
+	}
+
+	for _, s := range []string{"cmd=SET_NETWORK", "level=WARN", "msg=\"wpa_supplicant command\""} {
+		if !strings.Contains(logs.String(), s) {
+			t.Errorf("expected logs to contain %s, got %s", s, logs)
+		}
+	}
+
+	if strings.Contains(logs.String(), "secret") || strings.Contains(logs.String(), "12345670") {
+		t.Errorf("expected secrets to be redacted in logs, got %s", logs)
+	}
+}
@@ -0,0 +1,102 @@
+package hostapd
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/brlbil/wpaclient"
+)
+
+// StaEvents are the station events parsed by ParseStaEvent
+var StaEvents = []string{
+	wpaclient.ApStaConnected,
+	wpaclient.ApStaDisconnected,
+	wpaclient.ApStaPossiblePskMismatch,
+	wpaclient.ApStaPollOk,
+	wpaclient.ApRejectedMaxSta,
+	wpaclient.ApRejectedBlockedSta,
+}
+
+// StaEvent represents an "AP-STA-*" or "AP-REJECTED-*" event
+type StaEvent struct {
+	// Type is the event name, one of StaEvents
+	Type string
+	Addr net.HardwareAddr
+	// Params holds key=value parameters, like p2p_dev_addr or keyid
+	Params map[string]string
+}
+
+// ParseStaEvent parses a station event, returns nil if ev is not one of StaEvents
+func ParseStaEvent(ev wpaclient.Event) (*StaEvent, error) {
+	for _, t := range StaEvents {
+		if !strings.HasPrefix(ev.Message, t) {
+			continue
+		}
+
+		f := strings.Fields(strings.TrimPrefix(ev.Message, t))
+		if len(f) == 0 {
+			return nil, fmt.Errorf("%s: missing station address", strings.TrimSpace(t))
+		}
+
+		addr, err := net.ParseMAC(f[0])
+		if err != nil {
+			return nil, fmt.Errorf("parse mac: %w", err)
+		}
+
+		se := &StaEvent{Type: t, Addr: addr, Params: map[string]string{}}
+		for _, p := range f[1:] {
+			if i := strings.Index(p, "="); i > 0 {
+				se.Params[p[:i]] = p[i+1:]
+			}
+		}
+
+		return se, nil
+	}
+
+	return nil, nil
+}
+
+// NotifySta returns a channel receiving parsed station events until it is
+// passed to StopSta, events failed to parse are dropped
+func (c *Client) NotifySta() (<-chan StaEvent, error) {
+	ch, err := c.Notify(StaEvents...)
+	if err != nil {
+		return nil, err
+	}
+
+	sch := make(chan StaEvent, 5)
+	c.mut.Lock()
+	c.sta[sch] = ch
+	c.mut.Unlock()
+
+	go func() {
+		defer close(sch)
+
+		for ev := range ch {
+			se, err := ParseStaEvent(ev)
+			if err != nil || se == nil {
+				continue
+			}
+
+			select {
+			case sch <- *se:
+			default:
+			}
+		}
+	}()
+
+	return sch, nil
+}
+
+// StopSta stops relaying station events to ch and closes it
+func (c *Client) StopSta(ch <-chan StaEvent) {
+	c.mut.Lock()
+	evs, ok := c.sta[ch]
+	delete(c.sta, ch)
+	c.mut.Unlock()
+
+	if ok {
+		c.Stop(evs)
+	}
+}
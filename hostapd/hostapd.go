@@ -0,0 +1,231 @@
+// Package hostapd provides a hostapd control interface client.
+// hostapd speaks the same protocol as wpa_supplicant, so Client is built on wpaclient.
+package hostapd
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/brlbil/wpaclient"
+)
+
+// hostapd specific command constants
+const (
+	CmdEnable    = "ENABLE"
+	CmdDisable   = "DISABLE"
+	CmdReload    = "RELOAD"
+	CmdGetConfig = "GET_CONFIG"
+)
+
+// CtrlDirs are the directories searched for hostapd control sockets
+var CtrlDirs = []string{"/var/run/hostapd", "/run/hostapd"}
+
+// Client represents hostapd client
+type Client struct {
+	*wpaclient.Client
+
+	mut sync.Mutex
+	// event channels of NotifySta channels
+	sta map[<-chan StaEvent]<-chan wpaclient.Event
+}
+
+// New returns a new Client, addr is either a socket path or
+// an interface name searched in CtrlDirs
+func New(addr string, opts ...wpaclient.Option) (*Client, error) {
+	p := addr
+	if !fileExists(addr) {
+		for _, d := range CtrlDirs {
+			if a := path.Join(d, addr); fileExists(a) {
+				p = a
+				break
+			}
+		}
+	}
+
+	c, err := wpaclient.New(p, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{Client: c, sta: map[<-chan StaEvent]<-chan wpaclient.Event{}}, nil
+}
+
+func fileExists(p string) bool {
+	_, err := os.Stat(p)
+	return err == nil
+}
+
+// BSS represents a BSS entry of hostapd status
+type BSS struct {
+	Ifname string
+	BSSID  net.HardwareAddr
+	SSID   string
+	NumSta int
+}
+
+// Status represents data returned from "STATUS" command
+type Status struct {
+	State      string
+	Phy        string
+	Freq       int
+	Channel    int
+	HT         bool
+	VHT        bool
+	HE         bool
+	BeaconInt  int
+	DTIMPeriod int
+	BSS        []BSS
+	// Params holds every returned field
+	Params map[string]string
+}
+
+// Status executes "STATUS" command and returns parsed status
+func (c *Client) Status() (*Status, error) {
+	res, err := c.Execute(wpaclient.CmdStatus)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseStatus(res)
+}
+
+func parseStatus(b []byte) (*Status, error) {
+	kv := wpaclient.ParseKV(b)
+
+	s := &Status{
+		State:  kv["state"],
+		Phy:    kv["phy"],
+		HT:     kv["ieee80211n"] == "1",
+		VHT:    kv["ieee80211ac"] == "1",
+		HE:     kv["ieee80211ax"] == "1",
+		Params: kv,
+	}
+
+	for k, p := range map[string]*int{"freq": &s.Freq, "channel": &s.Channel,
+		"beacon_int": &s.BeaconInt, "dtim_period": &s.DTIMPeriod} {
+		if err := wpaclient.KVInt(kv, k, p); err != nil {
+			return nil, err
+		}
+	}
+
+	for i := 0; ; i++ {
+		ifn, ok := kv[fmt.Sprintf("bss[%d]", i)]
+		if !ok {
+			break
+		}
+
+		b := BSS{Ifname: ifn, SSID: kv[fmt.Sprintf("ssid[%d]", i)]}
+		if v := kv[fmt.Sprintf("bssid[%d]", i)]; v != "" {
+			mac, err := net.ParseMAC(v)
+			if err != nil {
+				return nil, fmt.Errorf("parse bssid: %w", err)
+			}
+			b.BSSID = mac
+		}
+
+		if err := wpaclient.KVInt(kv, fmt.Sprintf("num_sta[%d]", i), &b.NumSta); err != nil {
+			return nil, err
+		}
+		s.BSS = append(s.BSS, b)
+	}
+
+	return s, nil
+}
+
+// Config represents data returned from "GET_CONFIG" command
+type Config struct {
+	BSSID    net.HardwareAddr
+	SSID     string
+	WPSState string
+	WPA      int
+	KeyMgmt  []string
+	// Params holds every returned field
+	Params map[string]string
+}
+
+// GetConfig executes "GET_CONFIG" command and returns current BSS configuration
+func (c *Client) GetConfig() (*Config, error) {
+	res, err := c.Execute(CmdGetConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	kv := wpaclient.ParseKV(res)
+	cf := &Config{SSID: kv["ssid"], WPSState: kv["wps_state"], Params: kv}
+
+	if v := kv["bssid"]; v != "" {
+		if cf.BSSID, err = net.ParseMAC(v); err != nil {
+			return nil, fmt.Errorf("parse bssid: %w", err)
+		}
+	}
+
+	if err := wpaclient.KVInt(kv, "wpa", &cf.WPA); err != nil {
+		return nil, err
+	}
+
+	if v := kv["key_mgmt"]; v != "" {
+		cf.KeyMgmt = strings.Fields(v)
+	}
+
+	return cf, nil
+}
+
+// Set executes "SET" command, changes a configuration parameter at runtime
+func (c *Client) Set(name, value string) error {
+	_, err := c.Execute(wpaclient.CmdSet, name, value)
+	return err
+}
+
+// Enable executes "ENABLE" command, enables the interface
+func (c *Client) Enable() error {
+	_, err := c.Execute(CmdEnable)
+	return err
+}
+
+// Disable executes "DISABLE" command, disables the interface
+func (c *Client) Disable() error {
+	_, err := c.Execute(CmdDisable)
+	return err
+}
+
+// Reload executes "RELOAD" command, reloads configuration file
+func (c *Client) Reload() error {
+	_, err := c.Execute(CmdReload)
+	return err
+}
+
+// Deauthenticate executes "DEAUTHENTICATE" command for station addr,
+// reason is not sent if zero
+func (c *Client) Deauthenticate(addr string, reason int) error {
+	_, err := c.Execute(wpaclient.CmdDeauthenticate, reasonArgs(addr, reason)...)
+	return err
+}
+
+// Disassociate executes "DISASSOCIATE" command for station addr,
+// reason is not sent if zero
+func (c *Client) Disassociate(addr string, reason int) error {
+	_, err := c.Execute(wpaclient.CmdDisassociate, reasonArgs(addr, reason)...)
+	return err
+}
+
+func reasonArgs(addr string, reason int) []string {
+	if reason == 0 {
+		return []string{addr}
+	}
+
+	return []string{addr, "reason=" + strconv.Itoa(reason)}
+}
+
+// ChanSwitch executes "CHAN_SWITCH" command, switches to freq after csCount beacons.
+// params are optional settings like "bandwidth=80", "center_freq1=5210", "vht" or "blocktx".
+func (c *Client) ChanSwitch(csCount, freq int, params ...string) error {
+	args := append([]string{strconv.Itoa(csCount), strconv.Itoa(freq)}, params...)
+
+	_, err := c.Execute(wpaclient.CmdChanSwitch, args...)
+	return err
+}
@@ -0,0 +1,265 @@
+package hostapd
+
+import (
+	"net"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/brlbil/wpaclient"
+	"github.com/brlbil/wpaclient/wpatest"
+)
+
+var staRes = `02:00:00:00:00:01
+flags=[AUTH][ASSOC][AUTHORIZED][WMM][HT]
+aid=1
+rx_packets=12
+tx_packets=7
+rx_bytes=1650
+tx_bytes=980
+inactive_msec=340
+signal=-42
+connected_time=35
+`
+
+var staRes2 = `02:00:00:00:00:02
+flags=[AUTH][ASSOC]
+aid=2
+rx_bytes=0
+tx_bytes=0
+`
+
+func newClient(t *testing.T) (*wpatest.Server, *Client, func()) {
+	s, err := wpatest.NewServer()
+	if err != nil {
+		t.Fatalf("NewServer failed, %v", err)
+	}
+
+	c, err := New(s.Addr())
+	if err != nil {
+		s.Close()
+		t.Fatalf("New failed, %v", err)
+	}
+
+	return s, c, func() {
+		c.Close()
+		s.Close()
+	}
+}
+
+func TestStatus(t *testing.T) {
+	s, c, close := newClient(t)
+	defer close()
+
+	s.SetResponse(wpaclient.CmdStatus, `state=ENABLED
+phy=phy0
+freq=2412
+channel=1
+ieee80211n=1
+ieee80211ac=0
+beacon_int=100
+dtim_period=2
+bss[0]=wlan0
+bssid[0]=02:00:00:00:03:00
+ssid[0]=test
+num_sta[0]=2`)
+
+	st, err := c.Status()
+	if err != nil {
+		t.Fatalf("Status not expect an error, got %v", err)
+	}
+
+	exp := []BSS{{Ifname: "wlan0", BSSID: net.HardwareAddr{2, 0, 0, 0, 3, 0}, SSID: "test", NumSta: 2}}
+	if st.State != "ENABLED" || st.Freq != 2412 || st.Channel != 1 || !st.HT || st.VHT ||
+		st.BeaconInt != 100 || st.DTIMPeriod != 2 || !reflect.DeepEqual(st.BSS, exp) {
+		t.Errorf("unexpected status %#v", st)
+	}
+
+	s.SetResponse(wpaclient.CmdStatus, "state=ENABLED\nfreq=abc")
+	if _, err := c.Status(); err == nil {
+		t.Error("Status expect an error, got <nil>")
+	}
+}
+
+func TestGetConfig(t *testing.T) {
+	s, c, close := newClient(t)
+	defer close()
+
+	s.SetResponse(CmdGetConfig, `bssid=02:00:00:00:03:00
+ssid=test
+wps_state=disabled
+wpa=2
+key_mgmt=WPA-PSK SAE
+group_cipher=CCMP
+rsn_pairwise_cipher=CCMP`)
+
+	cf, err := c.GetConfig()
+	if err != nil {
+		t.Fatalf("GetConfig not expect an error, got %v", err)
+	}
+
+	if cf.SSID != "test" || cf.WPA != 2 || !reflect.DeepEqual(cf.KeyMgmt, []string{"WPA-PSK", "SAE"}) ||
+		cf.BSSID.String() != "02:00:00:00:03:00" || cf.Params["group_cipher"] != "CCMP" {
+		t.Errorf("unexpected config %#v", cf)
+	}
+}
+
+func TestStations(t *testing.T) {
+	s, c, close := newClient(t)
+	defer close()
+
+	s.SetResponse(wpaclient.CmdStaFirst, staRes)
+	s.Handle(wpaclient.CmdStaNext, func(args []string) string {
+		if len(args) == 1 && args[0] == "02:00:00:00:00:01" {
+			return staRes2
+		}
+		return ""
+	})
+	s.Handle(wpaclient.CmdSta, func(args []string) string {
+		if len(args) == 1 && args[0] == "02:00:00:00:00:01" {
+			return staRes
+		}
+		return "FAIL"
+	})
+
+	exp := Station{
+		Addr:          net.HardwareAddr{2, 0, 0, 0, 0, 1},
+		Flags:         []string{"AUTH", "ASSOC", "AUTHORIZED", "WMM", "HT"},
+		AID:           1,
+		RxBytes:       1650,
+		TxBytes:       980,
+		RxPackets:     12,
+		TxPackets:     7,
+		InactiveMs:    340,
+		Signal:        -42,
+		ConnectedTime: 35,
+	}
+
+	stas, err := c.AllSta()
+	if err != nil {
+		t.Fatalf("AllSta not expect an error, got %v", err)
+	}
+	if len(stas) != 2 || stas[1].AID != 2 {
+		t.Fatalf("AllSta expected 2 stations, got %#v", stas)
+	}
+	stas[0].Params = nil
+	if !reflect.DeepEqual(stas[0], exp) {
+		t.Errorf("Station expected %#v\ngot %#v", exp, stas[0])
+	}
+
+	sta, err := c.StaFirst()
+	if err != nil || sta == nil || sta.AID != 1 {
+		t.Errorf("StaFirst returned %#v, %v", sta, err)
+	}
+
+	sta, err = c.StaNext(sta.Addr.String())
+	if err != nil || sta == nil || sta.AID != 2 {
+		t.Errorf("StaNext returned %#v, %v", sta, err)
+	}
+
+	sta, err = c.StaNext(sta.Addr.String())
+	if err != nil || sta != nil {
+		t.Errorf("StaNext expected end of list, got %#v, %v", sta, err)
+	}
+
+	if sta, err = c.Sta("02:00:00:00:00:01"); err != nil || sta.Signal != -42 {
+		t.Errorf("Sta returned %#v, %v", sta, err)
+	}
+
+	if _, err = c.Sta("02:00:00:00:00:09"); err != wpaclient.ErrCmdFailed {
+		t.Errorf("Sta expect error %v, got %v", wpaclient.ErrCmdFailed, err)
+	}
+}
+
+func TestCommands(t *testing.T) {
+	s, c, close := newClient(t)
+	defer close()
+
+	var mut sync.Mutex
+	got := []string{}
+	for _, cmd := range []string{CmdEnable, CmdDisable, CmdReload, wpaclient.CmdSet,
+		wpaclient.CmdDeauthenticate, wpaclient.CmdDisassociate, wpaclient.CmdChanSwitch} {
+		cmd := cmd
+		s.Handle(cmd, func(args []string) string {
+			mut.Lock()
+			got = append(got, cmd+" "+strings.Join(args, " "))
+			mut.Unlock()
+			return "OK"
+		})
+	}
+
+	for _, fn := range []func() error{
+		c.Enable,
+		c.Disable,
+		c.Reload,
+		func() error { return c.Set("wps_state", "2") },
+		func() error { return c.Deauthenticate("02:00:00:00:00:01", 3) },
+		func() error { return c.Disassociate("02:00:00:00:00:01", 0) },
+		func() error { return c.ChanSwitch(5, 5180, "bandwidth=80", "vht") },
+	} {
+		if err := fn(); err != nil {
+			t.Errorf("command not expect an error, got %v", err)
+		}
+	}
+
+	exp := []string{
+		"ENABLE ",
+		"DISABLE ",
+		"RELOAD ",
+		"SET wps_state 2",
+		"DEAUTHENTICATE 02:00:00:00:00:01 reason=3",
+		"DISASSOCIATE 02:00:00:00:00:01",
+		"CHAN_SWITCH 5 5180 bandwidth=80 vht",
+	}
+	mut.Lock()
+	defer mut.Unlock()
+	if !reflect.DeepEqual(got, exp) {
+		t.Errorf("expected commands %q, got %q", exp, got)
+	}
+}
+
+func TestStaEvents(t *testing.T) {
+	s, c, close := newClient(t)
+	defer close()
+
+	ch, err := c.NotifySta()
+	if err != nil {
+		t.Fatalf("NotifySta not expect an error, got %v", err)
+	}
+
+	s.SendEvent(wpatest.LevelInfo, wpaclient.ApEventEnabled)
+	s.SendEvent(wpatest.LevelInfo, wpaclient.ApStaConnected+"02:00:00:00:00:01 keyid=home")
+	s.SendEvent(wpatest.LevelInfo, wpaclient.ApStaDisconnected+"02:00:00:00:00:01")
+
+	exp := []StaEvent{
+		{Type: wpaclient.ApStaConnected, Addr: net.HardwareAddr{2, 0, 0, 0, 0, 1}, Params: map[string]string{"keyid": "home"}},
+		{Type: wpaclient.ApStaDisconnected, Addr: net.HardwareAddr{2, 0, 0, 0, 0, 1}, Params: map[string]string{}},
+	}
+
+	for _, e := range exp {
+		select {
+		case ev := <-ch:
+			if !reflect.DeepEqual(ev, e) {
+				t.Errorf("expected event %#v, got %#v", e, ev)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("event not received")
+		}
+	}
+
+	c.StopSta(ch)
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Error("expected channel to be closed by StopSta")
+		}
+	case <-time.After(time.Second):
+		t.Error("channel not closed by StopSta")
+	}
+
+	if _, err := ParseStaEvent(wpaclient.Event{Message: wpaclient.ApStaConnected + "zz"}); err == nil {
+		t.Error("ParseStaEvent expect an error, got <nil>")
+	}
+}
@@ -0,0 +1,28 @@
+package hostapd
+
+import "github.com/brlbil/wpaclient"
+
+// Station represents a station associated with hostapd
+type Station = wpaclient.Station
+
+// Sta executes "STA" command and returns station addr
+func (c *Client) Sta(addr string) (*Station, error) {
+	return c.Station(addr)
+}
+
+// StaFirst executes "STA-FIRST" command, returns nil if there are no stations
+func (c *Client) StaFirst() (*Station, error) {
+	return c.StationFirst()
+}
+
+// StaNext executes "STA-NEXT" command, returns the station after addr
+// or nil if addr is the last one
+func (c *Client) StaNext(addr string) (*Station, error) {
+	return c.StationNext(addr)
+}
+
+// AllSta returns every associated station, like hostapd_cli "all_sta"
+// iterating with "STA-FIRST" and "STA-NEXT"
+func (c *Client) AllSta() ([]Station, error) {
+	return c.Stations()
+}
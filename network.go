@@ -3,8 +3,10 @@ package wpaclient
 import (
 	"bytes"
 	"encoding/csv"
+	"encoding/hex"
 	"fmt"
 	"net"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -50,6 +52,310 @@ func parseNetwork(b []byte) ([]Network, error) {
 	return nts, nil
 }
 
+// Quote returns s in double quotes, the form of string
+// network parameters like ssid or identity. If s holds quotes, backslashes
+// or control characters it is returned in the escaped P"..." form.
+func Quote(s string) string {
+	if strings.IndexFunc(s, needsEscape) < 0 {
+		return `"` + s + `"`
+	}
+
+	b := &strings.Builder{}
+	b.WriteString(`P"`)
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '"', '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if c < 0x20 || c == 0x7f {
+				fmt.Fprintf(b, `\x%02x`, c)
+				continue
+			}
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+
+	return b.String()
+}
+
+func needsEscape(r rune) bool {
+	return r == '"' || r == '\\' || r < 0x20 || r == 0x7f
+}
+
+// quotePassphrase returns psk passphrase p in double quotes, the P"..." form
+// is not accepted for psk, wpa_supplicant reads up to the last quote instead
+func quotePassphrase(p string) string {
+	return `"` + p + `"`
+}
+
+// AddNetwork executes "ADD_NETWORK" command and returns the id of the new network,
+// it is disabled until configured and enabled
+func (c *Client) AddNetwork() (int, error) {
+	res, err := c.Execute(CmdAddNetwork)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := strconv.Atoi(string(bytes.TrimSpace(res)))
+	if err != nil {
+		return 0, fmt.Errorf("parse id: %w", err)
+	}
+
+	return id, nil
+}
+
+// SetNetwork executes "SET_NETWORK" command, sets network parameter name to value.
+// String values have to be quoted, see Quote.
+func (c *Client) SetNetwork(id int, name, value string) error {
+	_, err := c.Execute(CmdSetNetwork, strconv.Itoa(id), name, value)
+	return err
+}
+
+// GetNetwork executes "GET_NETWORK" command and returns network parameter name,
+// passwords are not returned by wpa_supplicant
+func (c *Client) GetNetwork(id int, name string) (string, error) {
+	res, err := c.Execute(CmdGetNetwork, strconv.Itoa(id), name)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSuffix(string(res), "\n"), nil
+}
+
+// RemoveNetwork executes "REMOVE_NETWORK" command
+func (c *Client) RemoveNetwork(id int) error {
+	_, err := c.Execute(CmdRemoveNetwork, strconv.Itoa(id))
+	return err
+}
+
+// SelectNetwork executes "SELECT_NETWORK" command, connects to network id
+// and disables the others
+func (c *Client) SelectNetwork(id int) error {
+	_, err := c.Execute(CmdSelectNetwork, strconv.Itoa(id))
+	return err
+}
+
+// EnableNetwork executes "ENABLE_NETWORK" command
+func (c *Client) EnableNetwork(id int) error {
+	_, err := c.Execute(CmdEnableNetwork, strconv.Itoa(id))
+	return err
+}
+
+// DisableNetwork executes "DISABLE_NETWORK" command
+func (c *Client) DisableNetwork(id int) error {
+	_, err := c.Execute(CmdDisableNetwork, strconv.Itoa(id))
+	return err
+}
+
+// Disconnect executes "DISCONNECT" command, disconnects and
+// stays disconnected until Reconnect
+func (c *Client) Disconnect() error {
+	_, err := c.Execute(CmdDisconnect)
+	return err
+}
+
+// Reconnect executes "RECONNECT" command
+func (c *Client) Reconnect() error {
+	_, err := c.Execute(CmdReconnect)
+	return err
+}
+
+// NetworkConfig is the configuration of a network added by AddNetworkConfig or Connect.
+// String values are quoted, Params are set as given after the other fields.
+// KeyMgmt is WPA-EAP if EAP is set, NONE if no secret is set and SAE if only
+// SAEPassword is set, otherwise wpa_supplicant default is used.
+type NetworkConfig struct {
+	SSID  string
+	BSSID string
+	// PSK is a passphrase or a 64 character hex key
+	PSK         Secret
+	SAEPassword Secret
+	KeyMgmt     string
+	Identity    string
+	Password    Secret
+	Hidden      bool
+	Priority    int
+	// EAP parameters are set after Identity and Password, overriding them
+	EAP    EAPProfile
+	Params map[string]string
+}
+
+// Warnings returns insecure settings of cfg, like an EAP profile
+// not authenticating the server
+func (cfg NetworkConfig) Warnings() []string {
+	if cfg.EAP == nil {
+		return nil
+	}
+
+	return cfg.EAP.Warnings()
+}
+
+// params returns network parameters of cfg in the order they are set
+func (cfg NetworkConfig) params() ([][2]string, error) {
+	ps := [][2]string{}
+	add := func(name, value string) {
+		ps = append(ps, [2]string{name, value})
+	}
+
+	if cfg.SSID != "" {
+		add("ssid", Quote(cfg.SSID))
+	}
+	if cfg.BSSID != "" {
+		add("bssid", cfg.BSSID)
+	}
+
+	km := cfg.KeyMgmt
+	if km == "" && cfg.EAP != nil {
+		km = "WPA-EAP"
+	}
+	if km == "" && cfg.PSK == "" && cfg.Password == "" && cfg.Identity == "" {
+		if cfg.SAEPassword == "" {
+			km = "NONE"
+		} else {
+			km = "SAE"
+		}
+	}
+	if km != "" {
+		add("key_mgmt", km)
+	}
+
+	if cfg.PSK != "" {
+		psk := cfg.PSK.Reveal()
+		if !isHexKey(psk) {
+			psk = quotePassphrase(psk)
+		}
+		add("psk", psk)
+	}
+	if cfg.SAEPassword != "" {
+		add("sae_password", Quote(cfg.SAEPassword.Reveal()))
+	}
+	if cfg.Identity != "" {
+		add("identity", Quote(cfg.Identity))
+	}
+	if cfg.Password != "" {
+		add("password", Quote(cfg.Password.Reveal()))
+	}
+	if cfg.Hidden {
+		add("scan_ssid", "1")
+	}
+	if cfg.Priority != 0 {
+		add("priority", strconv.Itoa(cfg.Priority))
+	}
+
+	if cfg.EAP != nil {
+		eps, err := cfg.EAP.Params()
+		if err != nil {
+			return nil, err
+		}
+		ps = append(ps, eps...)
+	}
+
+	names := make([]string, 0, len(cfg.Params))
+	for n := range cfg.Params {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		add(n, cfg.Params[n])
+	}
+
+	return ps, nil
+}
+
+// isHexKey reports whether psk is a raw 256 bit key instead of a passphrase
+func isHexKey(psk string) bool {
+	if len(psk) != 64 {
+		return false
+	}
+
+	_, err := hex.DecodeString(psk)
+	return err == nil
+}
+
+// ConfigureNetwork sets network parameters of network id from cfg,
+// returned errors name the failed parameter but never hold its value.
+// Nothing is set if cfg is invalid, Warnings are not checked.
+func (c *Client) ConfigureNetwork(id int, cfg NetworkConfig) error {
+	ps, err := cfg.params()
+	if err != nil {
+		return err
+	}
+
+	return c.setNetworkParams(id, ps)
+}
+
+func (c *Client) setNetworkParams(id int, ps [][2]string) error {
+	for _, p := range ps {
+		if err := c.SetNetwork(id, p[0], p[1]); err != nil {
+			return fmt.Errorf("set %s: %w", p[0], err)
+		}
+	}
+
+	return nil
+}
+
+// AddNetworkConfig adds a network configured with cfg and returns its id,
+// the network is removed if configuring fails
+func (c *Client) AddNetworkConfig(cfg NetworkConfig) (int, error) {
+	ps, err := cfg.params()
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := c.AddNetwork()
+	if err != nil {
+		return 0, err
+	}
+
+	if err := c.setNetworkParams(id, ps); err != nil {
+		c.RemoveNetwork(id)
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// Connect adds a network configured with cfg, selects it and returns its id,
+// the network is removed if selecting fails
+func (c *Client) Connect(cfg NetworkConfig) (int, error) {
+	id, err := c.AddNetworkConfig(cfg)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := c.SelectNetwork(id); err != nil {
+		c.RemoveNetwork(id)
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// SetNetworkSecret executes "SET_NETWORK" command with a secret value,
+// value is quoted
+func (c *Client) SetNetworkSecret(id int, name string, value Secret) error {
+	if name == "psk" {
+		return c.SetNetwork(id, name, quotePassphrase(value.Reveal()))
+	}
+
+	return c.SetNetwork(id, name, Quote(value.Reveal()))
+}
+
+// SetCredSecret executes "SET_CRED" command with a secret value
+// like password, value is quoted
+func (c *Client) SetCredSecret(id int, name string, value Secret) error {
+	_, err := c.Execute(CmdSetCred, strconv.Itoa(id), name, Quote(value.Reveal()))
+	return err
+}
+
 // AP represents Access Point data returned from "SCAN_RESULTS" commad
 type AP struct {
 	BSSID          net.HardwareAddr
@@ -1,8 +1,11 @@
 package wpaclient
 
 import (
+	"errors"
 	"net"
 	"reflect"
+	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -168,3 +171,128 @@ d0:7a:b5:31:23:a0	2472	-30	[WPA2-PSK-CCMP][WPS][ESS]	AP0
 	}
 
 }
+
+func TestNetworkMethods(t *testing.T) {
+	ts, close := newTestServer(t)
+	defer close()
+
+	c, err := New(ts.addr())
+	if err != nil {
+		t.Fatalf("New Client failed, %v", err)
+	}
+	defer c.Close()
+
+	id, err := c.AddNetwork()
+	if err != nil || id != 0 {
+		t.Fatalf("AddNetwork expected 0, got %d, %v", id, err)
+	}
+
+	if err := c.SetNetwork(id, "ssid", Quote("test")); err != nil {
+		t.Errorf("SetNetwork not expect an error, got %v", err)
+	}
+
+	if ts.lastCmd() != CmdSetNetwork+` 0 ssid "test"` {
+		t.Errorf("SetNetwork expected to send quoted ssid, got %s", ts.lastCmd())
+	}
+
+	ns, err := c.ListNetworks()
+	if err != nil || len(ns) != 1 || ns[0].SSID != "test" {
+		t.Errorf("ListNetworks expected network test, got %v, %v", ns, err)
+	}
+
+	if err := c.SetNetwork(3, "ssid", Quote("test")); err != ErrCmdFailed {
+		t.Errorf("SetNetwork expect error %v, got %v", ErrCmdFailed, err)
+	}
+
+	if err := c.RemoveNetwork(id); err != nil {
+		t.Errorf("RemoveNetwork not expect an error, got %v", err)
+	}
+
+	if err := c.RemoveNetwork(id); err != ErrCmdFailed {
+		t.Errorf("RemoveNetwork expect error %v, got %v", ErrCmdFailed, err)
+	}
+}
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		in  string
+		exp string
+	}{
+		{in: "", exp: `""`},
+		{in: "home wifi", exp: `"home wifi"`},
+		{in: "çay", exp: `"çay"`},
+		{in: `say "hi"`, exp: `P"say \"hi\""`},
+		{in: `a\b`, exp: `P"a\\b"`},
+		{in: "a\nb\tc\x1b", exp: `P"a\nb\tc\x1b"`},
+	}
+
+	for _, tt := range tests {
+		if q := Quote(tt.in); q != tt.exp {
+			t.Errorf("Quote(%q) expected %s, got %s", tt.in, tt.exp, q)
+		}
+	}
+}
+
+func TestNetworkConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  NetworkConfig
+		exp  [][2]string
+	}{
+		{name: "open", cfg: NetworkConfig{SSID: "cafe"},
+			exp: [][2]string{{"ssid", `"cafe"`}, {"key_mgmt", "NONE"}}},
+		{name: "psk", cfg: NetworkConfig{SSID: "home", PSK: "secret", Hidden: true, Priority: 2},
+			exp: [][2]string{{"ssid", `"home"`}, {"psk", `"secret"`}, {"scan_ssid", "1"}, {"priority", "2"}}},
+		{name: "quoted psk", cfg: NetworkConfig{SSID: `a "b"`, PSK: `se"cr\et`},
+			exp: [][2]string{{"ssid", `P"a \"b\""`}, {"psk", `"se"cr\et"`}}},
+		{name: "hex psk", cfg: NetworkConfig{SSID: "home", PSK: Secret(strings.Repeat("ab", 32))},
+			exp: [][2]string{{"ssid", `"home"`}, {"psk", strings.Repeat("ab", 32)}}},
+		{name: "sae", cfg: NetworkConfig{SSID: "home", SAEPassword: "secret", Params: map[string]string{"ieee80211w": "2"}},
+			exp: [][2]string{{"ssid", `"home"`}, {"key_mgmt", "SAE"}, {"sae_password", `"secret"`}, {"ieee80211w", "2"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if ps, _ := tt.cfg.params(); !reflect.DeepEqual(ps, tt.exp) {
+				t.Errorf("expected params %v, got %v", tt.exp, ps)
+			}
+		})
+	}
+
+	ts, cleanup := newTestServer(t)
+	defer cleanup()
+
+	c, err := New(ts.addr())
+	if err != nil {
+		t.Fatalf("New Client failed, %v", err)
+	}
+	defer c.Close()
+
+	id, err := c.Connect(NetworkConfig{SSID: "test", PSK: "secret"})
+	if err != nil {
+		t.Fatalf("Connect not expect an error, got %v", err)
+	}
+
+	if ts.lastCmd() != CmdSelectNetwork+" "+strconv.Itoa(id) {
+		t.Errorf("Connect expected to select network %d, got %s", id, ts.lastCmd())
+	}
+
+	ts.setCmd(CmdSelectNetwork, "FAIL")
+	if _, err := c.Connect(NetworkConfig{SSID: "test", PSK: "secret"}); err != ErrCmdFailed {
+		t.Errorf("Connect expect error %v, got %v", ErrCmdFailed, err)
+	}
+	ts.delCmd(CmdSelectNetwork)
+
+	if ts.lastCmd() != CmdRemoveNetwork+" "+strconv.Itoa(id+1) {
+		t.Errorf("Connect expected to remove network %d, got %s", id+1, ts.lastCmd())
+	}
+
+	err = c.ConfigureNetwork(3, NetworkConfig{PSK: "secret"})
+	if !errors.Is(err, ErrCmdFailed) {
+		t.Fatalf("ConfigureNetwork expect error %v, got %v", ErrCmdFailed, err)
+	}
+
+	if strings.Contains(err.Error(), "secret") {
+		t.Errorf("expected error not to contain the secret, got %v", err)
+	}
+}
@@ -0,0 +1,295 @@
+package wpaclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// P2P runs Wi-Fi Direct commands on a P2P device interface, like "p2p-dev-wlan0"
+type P2P struct {
+	c *Client
+}
+
+// NewP2P returns P2P using c
+func NewP2P(c *Client) *P2P {
+	return &P2P{c: c}
+}
+
+// P2PPeer is a Wi-Fi Direct device returned from "P2P_PEER" command
+// or reported with "P2P-DEVICE-FOUND" event
+type P2PPeer struct {
+	Addr          net.HardwareAddr
+	DeviceName    string
+	PriDevType    string
+	ConfigMethods uint16
+	DevCapab      uint8
+	GroupCapab    uint8
+	Manufacturer  string
+	ModelName     string
+	ModelNumber   string
+	SerialNumber  string
+	Level         int
+	// WFDSubelems are the Wi-Fi Display subelements, events report just the device info subelement
+	WFDSubelems []byte
+	// Params holds every returned field
+	Params map[string]string
+}
+
+// FindOptions are the options of "P2P_FIND" command
+type FindOptions struct {
+	// Timeout stops the search, searched until stopped if zero
+	Timeout time.Duration
+	// Type is "social" to search social channels only, or "progressive"
+	Type string
+	// DevType is the requested primary device type, like "7-0050F204-1"
+	DevType string
+	// Delay is the delay between search iterations
+	Delay time.Duration
+}
+
+func (o FindOptions) args() []string {
+	args := []string{}
+	if o.Timeout > 0 {
+		args = append(args, strconv.Itoa(int((o.Timeout+time.Second-1)/time.Second)))
+	}
+	if o.Type != "" {
+		args = append(args, "type="+o.Type)
+	}
+	if o.DevType != "" {
+		args = append(args, "dev_type="+o.DevType)
+	}
+	if o.Delay > 0 {
+		args = append(args, "delay="+strconv.Itoa(int(o.Delay/time.Millisecond)))
+	}
+
+	return args
+}
+
+// PeerTable holds the peers found by a search, updated from events until the search stops
+type PeerTable struct {
+	mut   sync.Mutex
+	peers map[string]P2PPeer
+	done  chan struct{}
+}
+
+// Peers returns found peers sorted by address
+func (t *PeerTable) Peers() []P2PPeer {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	peers := []P2PPeer{}
+	for _, p := range t.peers {
+		peers = append(peers, p)
+	}
+	sort.Slice(peers, func(i, j int) bool { return peers[i].Addr.String() < peers[j].Addr.String() })
+
+	return peers
+}
+
+// Peer returns found peer with addr
+func (t *PeerTable) Peer(addr string) (P2PPeer, bool) {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	for _, p := range t.peers {
+		if strings.EqualFold(p.Addr.String(), addr) {
+			return p, true
+		}
+	}
+
+	return P2PPeer{}, false
+}
+
+// Done is closed when the search stops
+func (t *PeerTable) Done() <-chan struct{} {
+	return t.done
+}
+
+func (t *PeerTable) event(ev Event) {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	switch {
+	case strings.HasPrefix(ev.Message, P2pEventDeviceFound):
+		if p, err := parseDeviceFound(strings.TrimPrefix(ev.Message, P2pEventDeviceFound)); err == nil {
+			t.peers[p.Addr.String()] = *p
+		}
+	case strings.HasPrefix(ev.Message, P2pEventDeviceLost):
+		ps := parseQuotedParams(strings.TrimPrefix(ev.Message, P2pEventDeviceLost))
+		if addr, err := net.ParseMAC(ps["p2p_dev_addr"]); err == nil {
+			delete(t.peers, addr.String())
+		}
+	}
+}
+
+// Find executes "P2P_FIND" command and returns a PeerTable tracking found peers.
+// The search is stopped with "P2P_STOP_FIND" when ctx is done.
+func (p *P2P) Find(ctx context.Context, opts FindOptions) (*PeerTable, error) {
+	// "P2P-FIND-STOPPED" has no parameters, it is not followed by a space
+	stopped := strings.TrimSpace(P2pEventFindStopped)
+	ch, err := p.c.Notify(P2pEventDeviceFound, P2pEventDeviceLost, stopped)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.c.Execute(CmdP2pFind, opts.args()...); err != nil {
+		p.c.Stop(ch)
+		return nil, err
+	}
+
+	t := &PeerTable{peers: map[string]P2PPeer{}, done: make(chan struct{})}
+	go func() {
+		defer close(t.done)
+		defer p.c.Stop(ch)
+
+		for {
+			select {
+			case <-ctx.Done():
+				p.StopFind()
+				return
+			case ev, ok := <-ch:
+				if !ok || strings.HasPrefix(ev.Message, stopped) {
+					return
+				}
+				t.event(ev)
+			}
+		}
+	}()
+
+	return t, nil
+}
+
+// StopFind executes "P2P_STOP_FIND" command
+func (p *P2P) StopFind() error {
+	_, err := p.c.Execute(CmdP2pStopFind)
+	return err
+}
+
+// Peers returns every known peer, iterating with "P2P_PEER FIRST" and
+// "P2P_PEER NEXT-<addr>" commands until there are no more peers
+func (p *P2P) Peers() ([]P2PPeer, error) {
+	peers := []P2PPeer{}
+	for arg := "FIRST"; ; {
+		res, err := p.c.Execute(CmdP2pPeer, arg)
+		if err == ErrCmdFailed || (err == nil && len(bytes.TrimSpace(res)) == 0) {
+			return peers, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		peer, err := parsePeer(res)
+		if err != nil {
+			return nil, err
+		}
+		peers = append(peers, *peer)
+		arg = "NEXT-" + peer.Addr.String()
+	}
+}
+
+// Peer executes "P2P_PEER" command and returns peer with addr
+func (p *P2P) Peer(addr string) (*P2PPeer, error) {
+	res, err := p.c.Execute(CmdP2pPeer, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return parsePeer(res)
+}
+
+// parsePeer parses the address line and key=value lines of "P2P_PEER" output
+func parsePeer(b []byte) (*P2PPeer, error) {
+	b = bytes.TrimSpace(b)
+	i := bytes.IndexByte(b, '\n')
+	if i < 0 {
+		i = len(b)
+	}
+
+	addr, err := net.ParseMAC(string(b[:i]))
+	if err != nil {
+		return nil, fmt.Errorf("parse mac: %w", err)
+	}
+
+	kv := ParseKV(b[i:])
+	p := &P2PPeer{Addr: addr, DeviceName: kv["device_name"], Params: kv}
+	if err := p.set(kv); err != nil {
+		return nil, err
+	}
+
+	if s := kv["wfd_subelems"]; s != "" {
+		if p.WFDSubelems, err = hex.DecodeString(s); err != nil {
+			return nil, fmt.Errorf("parse wfd_subelems: %w", err)
+		}
+	}
+
+	return p, nil
+}
+
+// parseDeviceFound parses "<addr> p2p_dev_addr=<addr> pri_dev_type=<type> name='<name>'
+// config_methods=0x<n> dev_capab=0x<n> group_capab=0x<n> [wfd_dev_info=0x<hex>] [new=1]"
+func parseDeviceFound(msg string) (*P2PPeer, error) {
+	kv := parseQuotedParams(msg[strings.IndexByte(msg, ' ')+1:])
+
+	addr, err := net.ParseMAC(kv["p2p_dev_addr"])
+	if err != nil {
+		return nil, fmt.Errorf("parse mac: %w", err)
+	}
+
+	p := &P2PPeer{Addr: addr, DeviceName: kv["name"], Params: kv}
+	if err := p.set(kv); err != nil {
+		return nil, err
+	}
+
+	if s := kv["wfd_dev_info"]; s != "" {
+		info, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+		if err != nil {
+			return nil, fmt.Errorf("parse wfd_dev_info: %w", err)
+		}
+		// device info subelement, id 0 and two octet length
+		p.WFDSubelems = append([]byte{0, byte(len(info) >> 8), byte(len(info))}, info...)
+	}
+
+	return p, nil
+}
+
+// set sets fields common to "P2P_PEER" output and "P2P-DEVICE-FOUND" event
+func (p *P2PPeer) set(kv map[string]string) error {
+	p.PriDevType = kv["pri_dev_type"]
+	p.Manufacturer, p.ModelName = kv["manufacturer"], kv["model_name"]
+	p.ModelNumber, p.SerialNumber = kv["model_number"], kv["serial_number"]
+
+	if err := KVInt(kv, "level", &p.Level); err != nil {
+		return err
+	}
+
+	for k, bits := range map[string]int{"config_methods": 16, "dev_capab": 8, "group_capab": 8} {
+		s, ok := kv[k]
+		if !ok {
+			continue
+		}
+
+		n, err := strconv.ParseUint(s, 0, bits)
+		if err != nil {
+			return fmt.Errorf("parse %s: %w", k, err)
+		}
+
+		switch k {
+		case "config_methods":
+			p.ConfigMethods = uint16(n)
+		case "dev_capab":
+			p.DevCapab = uint8(n)
+		case "group_capab":
+			p.GroupCapab = uint8(n)
+		}
+	}
+
+	return nil
+}
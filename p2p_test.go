@@ -0,0 +1,158 @@
+package wpaclient
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var p2pPeer = `02:00:00:00:00:01
+pri_dev_type=7-0050F204-1
+device_name=Living Room TV
+manufacturer=ACME
+model_name=TV1
+model_number=1.0
+serial_number=1234
+config_methods=0x188
+dev_capab=0x25
+group_capab=0x0
+level=-45
+wfd_subelems=000006011c440032`
+
+func TestP2PFind(t *testing.T) {
+	ts, cleanup := newTestServer(t)
+	defer cleanup()
+
+	ts.setCmd(CmdP2pFind, "OK")
+	ts.setCmd(CmdP2pStopFind, "OK")
+
+	c, err := New(ts.addr())
+	if err != nil {
+		t.Fatalf("New Client failed, %v", err)
+	}
+	defer c.Close()
+
+	p := NewP2P(c)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pt, err := p.Find(ctx, FindOptions{Timeout: 30 * time.Second, Type: "social", DevType: "7-0050F204-1",
+		Delay: 500 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Find not expect an error, got %v", err)
+	}
+
+	if exp := CmdP2pFind + " 30 type=social dev_type=7-0050F204-1 delay=500"; ts.lastCmd() != exp {
+		t.Errorf("expected %s to be sent, got %s", exp, ts.lastCmd())
+	}
+
+	ts.sendMsg(3,
+		P2pEventDeviceFound+"02:00:00:00:00:01 p2p_dev_addr=02:00:00:00:00:01 pri_dev_type=7-0050F204-1 "+
+			"name='Living Room TV' config_methods=0x188 dev_capab=0x25 group_capab=0x0 wfd_dev_info=0x00011c440032 new=1",
+		P2pEventDeviceFound+"02:00:00:00:00:02 p2p_dev_addr=02:00:00:00:00:02 pri_dev_type=10-0050F204-5 "+
+			"name='Phone' config_methods=0x3148 dev_capab=0x25 group_capab=0x0 new=1",
+		P2pEventDeviceLost+"p2p_dev_addr=02:00:00:00:00:02",
+	)
+
+	for i := 0; i < 100 && len(pt.Peers()) != 1; i++ {
+		time.Sleep(time.Millisecond * 10)
+	}
+	peers := pt.Peers()
+	if len(peers) != 1 {
+		t.Fatalf("expected 1 peer, got %d", len(peers))
+	}
+
+	peer, ok := pt.Peer("02:00:00:00:00:01")
+	if !ok {
+		t.Fatal("Peer expected to find 02:00:00:00:00:01")
+	}
+	if peer.DeviceName != "Living Room TV" || peer.ConfigMethods != 0x188 || peer.DevCapab != 0x25 ||
+		!reflect.DeepEqual(peer.WFDSubelems, []byte{0, 0, 6, 0, 1, 0x1c, 0x44, 0, 0x32}) {
+		t.Errorf("unexpected peer %+v", peer)
+	}
+
+	ts.sendMsg(3, "P2P-FIND-STOPPED")
+	select {
+	case <-pt.Done():
+	case <-time.After(time.Second):
+		t.Fatal("find not stopped")
+	}
+
+	pt, err = p.Find(ctx, FindOptions{})
+	if err != nil {
+		t.Fatalf("Find not expect an error, got %v", err)
+	}
+	if ts.lastCmd() != CmdP2pFind {
+		t.Errorf("expected %s to be sent, got %s", CmdP2pFind, ts.lastCmd())
+	}
+
+	cancel()
+	select {
+	case <-pt.Done():
+	case <-time.After(time.Second):
+		t.Fatal("find not stopped")
+	}
+	if ts.lastCmd() != CmdP2pStopFind {
+		t.Errorf("expected %s to be sent, got %s", CmdP2pStopFind, ts.lastCmd())
+	}
+}
+
+func TestP2PPeers(t *testing.T) {
+	ts, cleanup := newTestServer(t)
+	defer cleanup()
+
+	c, err := New(ts.addr())
+	if err != nil {
+		t.Fatalf("New Client failed, %v", err)
+	}
+	defer c.Close()
+
+	peers, err := NewP2P(c).Peers()
+	if err != nil {
+		t.Fatalf("Peers not expect an error, got %v", err)
+	}
+	if len(peers) != 0 {
+		t.Errorf("expected no peers, got %d", len(peers))
+	}
+
+	ts.mut.Lock()
+	ts.p2pPeers = []string{p2pPeer, "02:00:00:00:00:02\ndevice_name=Printer\nlevel=-60"}
+	ts.mut.Unlock()
+
+	peers, err = NewP2P(c).Peers()
+	if err != nil {
+		t.Fatalf("Peers not expect an error, got %v", err)
+	}
+	if ts.lastCmd() != CmdP2pPeer+" NEXT-02:00:00:00:00:02" {
+		t.Errorf("expected %s to be sent, got %s", CmdP2pPeer+" NEXT-02:00:00:00:00:02", ts.lastCmd())
+	}
+
+	addr, _ := net.ParseMAC("02:00:00:00:00:01")
+	exp := P2PPeer{Addr: addr, DeviceName: "Living Room TV", PriDevType: "7-0050F204-1", ConfigMethods: 0x188,
+		DevCapab: 0x25, Manufacturer: "ACME", ModelName: "TV1", ModelNumber: "1.0", SerialNumber: "1234",
+		Level: -45, WFDSubelems: []byte{0, 0, 6, 1, 0x1c, 0x44, 0, 0x32}}
+	if len(peers) != 2 {
+		t.Fatalf("expected 2 peers, got %d", len(peers))
+	}
+	peers[0].Params = nil
+	if !reflect.DeepEqual(peers[0], exp) {
+		t.Errorf("expected %+v, got %+v", exp, peers[0])
+	}
+	if peers[1].DeviceName != "Printer" || peers[1].Level != -60 {
+		t.Errorf("expected Printer at -60, got %+v", peers[1])
+	}
+
+	peer, err := NewP2P(c).Peer("02:00:00:00:00:02")
+	if err != nil {
+		t.Fatalf("Peer not expect an error, got %v", err)
+	}
+	if peer.DeviceName != "Printer" {
+		t.Errorf("expected Printer, got %s", peer.DeviceName)
+	}
+
+	if _, err := NewP2P(c).Peer("02:00:00:00:00:09"); err != ErrCmdFailed {
+		t.Errorf("Peer expect error %v, got %v", ErrCmdFailed, err)
+	}
+}
@@ -0,0 +1,337 @@
+package wpaclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// ErrP2PGroupFormation returned when "P2P-GROUP-FORMATION-FAILURE" received
+var ErrP2PGroupFormation = constError("p2p group formation failed")
+
+// p2pStatuses are the names of P2P status codes
+var p2pStatuses = []string{"success", "information currently unavailable", "incompatible parameters",
+	"limit reached", "invalid parameters", "unable to accommodate request", "previous protocol error",
+	"no common channels", "unknown p2p group", "both GO intent 15", "incompatible provisioning method",
+	"rejected by user"}
+
+// GoNegError represents "P2P-GO-NEG-FAILURE" event
+type GoNegError struct {
+	Status int
+}
+
+func (e *GoNegError) Error() string {
+	if e.Status > 0 && e.Status < len(p2pStatuses) {
+		return "go negotiation failed: " + p2pStatuses[e.Status]
+	}
+
+	return "go negotiation failed: status " + strconv.Itoa(e.Status)
+}
+
+// P2PMethod is the WPS provisioning method of a P2P connection
+type P2PMethod int
+
+// P2P provisioning methods
+const (
+	// P2PPBC uses push button
+	P2PPBC P2PMethod = iota
+	// P2PPIN uses a PIN known by both devices
+	P2PPIN
+	// P2PDisplay displays the PIN on this device, to be entered on the peer
+	P2PDisplay
+	// P2PKeypad enters the PIN displayed on the peer
+	P2PKeypad
+)
+
+// NoGoIntent requests the client role, see ConnectOptions
+const NoGoIntent = -1
+
+// ConnectOptions are the options of "P2P_CONNECT" command
+type ConnectOptions struct {
+	Method P2PMethod
+	// PIN is required by every method but P2PPBC, WPSGeneratePin
+	// can generate one for P2PDisplay
+	PIN Secret
+	// GoIntent is the willingness to be the group owner, from 1 to 15.
+	// Default of wpa_supplicant is used if zero, NoGoIntent sends zero.
+	GoIntent int
+	// Persistent requests a persistent group
+	Persistent bool
+	// Join joins a group the peer is running instead of negotiating one
+	Join bool
+}
+
+func (o ConnectOptions) args(peer string) ([]string, error) {
+	args := []string{peer}
+	if o.Method == P2PPBC {
+		args = append(args, "pbc")
+	} else {
+		if o.PIN == "" {
+			return nil, errors.New("pin required")
+		}
+		args = append(args, o.PIN.Reveal())
+	}
+
+	switch o.Method {
+	case P2PDisplay:
+		args = append(args, "display")
+	case P2PKeypad:
+		args = append(args, "keypad")
+	}
+
+	if o.Persistent {
+		args = append(args, "persistent")
+	}
+	if o.Join {
+		args = append(args, "join")
+	}
+
+	switch {
+	case o.GoIntent == NoGoIntent:
+		args = append(args, "go_intent=0")
+	case o.GoIntent < 0 || o.GoIntent > 15:
+		return nil, fmt.Errorf("invalid go intent %d", o.GoIntent)
+	case o.GoIntent > 0:
+		args = append(args, "go_intent="+strconv.Itoa(o.GoIntent))
+	}
+
+	return args, nil
+}
+
+// Group roles
+const (
+	GroupRoleGO     = "GO"
+	GroupRoleClient = "client"
+)
+
+// Group represents "P2P-GROUP-STARTED" event
+type Group struct {
+	// Ifname is the group interface, like "p2p-wlan0-0"
+	Ifname string
+	// Role is either GroupRoleGO or GroupRoleClient
+	Role       string
+	SSID       string
+	Freq       int
+	Passphrase Secret
+	// PSK is reported instead of Passphrase to clients of some groups, in hex
+	PSK        Secret
+	GOAddr     net.HardwareAddr
+	Persistent bool
+}
+
+// ParseGroupStarted parses a "P2P-GROUP-STARTED p2p-wlan0-0 GO ssid="DIRECT-xy" freq=2412
+// passphrase="secret12" go_dev_addr=02:00:00:00:00:01 [PERSISTENT]" event
+func ParseGroupStarted(ev Event) (*Group, error) {
+	if !strings.HasPrefix(ev.Message, P2pEventGroupStarted) {
+		return nil, fmt.Errorf("not a %s event", strings.TrimSpace(P2pEventGroupStarted))
+	}
+
+	msg := strings.TrimPrefix(ev.Message, P2pEventGroupStarted)
+	fs := strings.SplitN(msg, " ", 3)
+	if len(fs) < 3 {
+		return nil, fmt.Errorf("parse group: %s", msg)
+	}
+
+	g := &Group{Ifname: fs[0], Role: fs[1]}
+	msg = fs[2]
+
+	// ssid can hold spaces and is followed by freq
+	if i, j := strings.Index(msg, `ssid="`), strings.Index(msg, `" freq=`); i >= 0 && j > i {
+		g.SSID, msg = msg[i+len(`ssid="`):j], msg[:i]+msg[j+2:]
+	}
+
+	if strings.Contains(msg, "[PERSISTENT]") {
+		g.Persistent, msg = true, strings.Replace(msg, "[PERSISTENT]", "", 1)
+	}
+
+	// passphrase can hold spaces and quotes and is followed by go_dev_addr
+	if i := strings.Index(msg, `passphrase="`); i >= 0 {
+		j := strings.Index(msg[i:], `" go_dev_addr=`)
+		if j < 0 {
+			j = strings.LastIndex(msg[i:], `"`)
+		}
+		if j < len(`passphrase="`) {
+			return nil, fmt.Errorf("parse passphrase: %s", msg)
+		}
+		g.Passphrase, msg = Secret(msg[i+len(`passphrase="`):i+j]), msg[:i]+msg[i+j+1:]
+	}
+
+	ps := parseQuotedParams(msg)
+	g.PSK = Secret(ps["psk"])
+
+	var err error
+	if s, ok := ps["freq"]; ok {
+		if g.Freq, err = strconv.Atoi(s); err != nil {
+			return nil, fmt.Errorf("parse freq: %w", err)
+		}
+	}
+
+	if s, ok := ps["go_dev_addr"]; ok {
+		if g.GOAddr, err = net.ParseMAC(s); err != nil {
+			return nil, fmt.Errorf("parse mac: %w", err)
+		}
+	}
+
+	return g, nil
+}
+
+// Connect executes "P2P_CONNECT" command with peer, waits for group owner
+// negotiation and group formation, and returns the started group.
+// Connection attempt is canceled with "P2P_CANCEL" when ctx is done.
+func (p *P2P) Connect(ctx context.Context, peer string, opts ConnectOptions) (*Group, error) {
+	args, err := opts.args(peer)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.group(ctx, CmdP2pConnect, args...)
+}
+
+// GroupAdd executes "P2P_GROUP_ADD" command, starts a group as its owner on
+// freq, any channel if zero, and waits for the group to start
+func (p *P2P) GroupAdd(ctx context.Context, persistent bool, freq int) (*Group, error) {
+	args := []string{}
+	if persistent {
+		args = append(args, "persistent")
+	}
+	if freq > 0 {
+		args = append(args, "freq="+strconv.Itoa(freq))
+	}
+
+	return p.group(ctx, CmdP2pGroupAdd, args...)
+}
+
+// GroupAddPersistent executes "P2P_GROUP_ADD persistent=<id>" command,
+// restarts persistent group of network id, see PersistentGroups
+func (p *P2P) GroupAddPersistent(ctx context.Context, id int) (*Group, error) {
+	return p.group(ctx, CmdP2pGroupAdd, "persistent="+strconv.Itoa(id))
+}
+
+// group executes cmd and waits for the group to start
+func (p *P2P) group(ctx context.Context, cmd string, args ...string) (*Group, error) {
+	ch, err := p.c.Notify(P2pEventGoNegFailure, strings.TrimSpace(P2pEventGroupFormationFailure),
+		P2pEventGroupStarted)
+	if err != nil {
+		return nil, err
+	}
+	defer p.c.Stop(ch)
+
+	if _, err := p.c.Execute(cmd, args...); err != nil {
+		return nil, err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			p.c.Execute(CmdP2pCancel)
+			return nil, ctx.Err()
+		case ev, ok := <-ch:
+			if !ok {
+				return nil, errors.New("event channel closed")
+			}
+
+			switch {
+			case strings.HasPrefix(ev.Message, P2pEventGoNegFailure):
+				ps := parseQuotedParams(strings.TrimPrefix(ev.Message, P2pEventGoNegFailure))
+				st, err := strconv.Atoi(ps["status"])
+				if err != nil {
+					return nil, fmt.Errorf("parse status: %w", err)
+				}
+				return nil, &GoNegError{Status: st}
+			case strings.HasPrefix(ev.Message, strings.TrimSpace(P2pEventGroupFormationFailure)):
+				return nil, ErrP2PGroupFormation
+			case strings.HasPrefix(ev.Message, P2pEventGroupStarted):
+				return ParseGroupStarted(ev)
+			}
+		}
+	}
+}
+
+// GroupRemove executes "P2P_GROUP_REMOVE" command, removes group on ifname,
+// every group if ifname is "*"
+func (p *P2P) GroupRemove(ifname string) error {
+	_, err := p.c.Execute(CmdP2pGroupRemove, ifname)
+	return err
+}
+
+// Invite executes "P2P_INVITE" command, invites peer to the running group on ifname
+func (p *P2P) Invite(ifname, peer string) error {
+	_, err := p.c.Execute(CmdP2pInvite, "group="+ifname, "peer="+peer)
+	return err
+}
+
+// InvitePersistent executes "P2P_INVITE" command, invites peer to restart
+// persistent group of network id
+func (p *P2P) InvitePersistent(id int, peer string) error {
+	_, err := p.c.Execute(CmdP2pInvite, "persistent="+strconv.Itoa(id), "peer="+peer)
+	return err
+}
+
+// Reject executes "P2P_REJECT" command, rejects connection attempt of peer
+func (p *P2P) Reject(peer string) error {
+	_, err := p.c.Execute(CmdP2pReject, peer)
+	return err
+}
+
+// RemoveClient executes "P2P_REMOVE_CLIENT" command, removes peer from
+// groups and persistent groups this device owns
+func (p *P2P) RemoveClient(peer string) error {
+	_, err := p.c.Execute(CmdP2pRemoveClient, peer)
+	return err
+}
+
+// PersistentGroups returns the networks flagged "P2P-PERSISTENT"
+func (p *P2P) PersistentGroups() ([]Network, error) {
+	nets, err := p.c.ListNetworks()
+	if err != nil {
+		return nil, err
+	}
+
+	groups := []Network{}
+	for _, n := range nets {
+		for _, f := range n.Flags {
+			if f == "P2P-PERSISTENT" {
+				groups = append(groups, n)
+				break
+			}
+		}
+	}
+
+	return groups, nil
+}
+
+// GroupClient returns a Client for the interface of g. It is routed through
+// the global control interface if P2P uses one, otherwise the socket of the
+// interface is dialed next to the socket of P2P. Recorder, hooks and
+// CredentialPrompter of P2P are used by the returned Client.
+func (p *P2P) GroupClient(g *Group) (*Client, error) {
+	if p.c.global != nil {
+		return (&Global{Client: p.c.global}).Interface(g.Ifname), nil
+	}
+
+	if strings.HasPrefix(p.c.addr, udpPrefix) {
+		return nil, errors.New("group interface is not reachable over udp")
+	}
+
+	addr := g.Ifname
+	if strings.Contains(p.c.addr, "/") {
+		addr = path.Join(path.Dir(p.c.addr), g.Ifname)
+	}
+
+	opts := []Option{}
+	if p.c.prompter != nil {
+		opts = append(opts, WithCredentialPrompter(p.c.prompter))
+	}
+
+	c, err := New(addr, opts...)
+	if err != nil {
+		return nil, err
+	}
+	c.rec, c.hooks = p.c.rec, p.c.hooks
+
+	return c, nil
+}
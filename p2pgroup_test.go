@@ -0,0 +1,245 @@
+package wpaclient
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestP2PConnect(t *testing.T) {
+	ts, cleanup := newTestServer(t)
+	defer cleanup()
+
+	for _, cmd := range []string{CmdP2pConnect, CmdP2pGroupAdd, CmdP2pCancel} {
+		ts.setCmd(cmd, "OK")
+	}
+
+	c, err := New(ts.addr())
+	if err != nil {
+		t.Fatalf("New Client failed, %v", err)
+	}
+	defer c.Close()
+
+	p := NewP2P(c)
+	peer := "02:00:00:00:00:01"
+	started := P2pEventGroupStarted + `p2p-wlan0-0 GO ssid="DIRECT-xy TV" freq=2437 passphrase="s3cr3t12" ` +
+		"go_dev_addr=02:00:00:00:00:02 [PERSISTENT]"
+	goAddr, _ := net.ParseMAC("02:00:00:00:00:02")
+	group := &Group{Ifname: "p2p-wlan0-0", Role: GroupRoleGO, SSID: "DIRECT-xy TV", Freq: 2437,
+		Passphrase: "s3cr3t12", GOAddr: goAddr, Persistent: true}
+
+	tests := []struct {
+		name    string
+		fn      func(context.Context) (*Group, error)
+		started string
+		events  []string
+		group   *Group
+		err     error
+		last    string
+	}{
+		{name: "pbc", fn: func(ctx context.Context) (*Group, error) {
+			return p.Connect(ctx, peer, ConnectOptions{Persistent: true, GoIntent: 15})
+		}, started: CmdP2pConnect + " " + peer + " pbc persistent go_intent=15",
+			events: []string{P2pEventGoNegSuccess + "role=GO freq=2437", "P2P-GROUP-FORMATION-SUCCESS", started},
+			group:  group},
+		{name: "keypad join", fn: func(ctx context.Context) (*Group, error) {
+			return p.Connect(ctx, peer, ConnectOptions{Method: P2PKeypad, PIN: "12345670", Join: true, GoIntent: NoGoIntent})
+		}, started: CmdP2pConnect + " " + peer + " 12345670 keypad join go_intent=0",
+			events: []string{started}, group: group},
+		{name: "negotiation failure", fn: func(ctx context.Context) (*Group, error) {
+			return p.Connect(ctx, peer, ConnectOptions{Method: P2PDisplay, PIN: "12345670"})
+		}, started: CmdP2pConnect + " " + peer + " 12345670 display",
+			events: []string{P2pEventGoNegFailure + "status=9"}, err: &GoNegError{Status: 9}},
+		{name: "formation failure", fn: func(ctx context.Context) (*Group, error) {
+			return p.GroupAdd(ctx, false, 2412)
+		}, started: CmdP2pGroupAdd + " freq=2412",
+			events: []string{"P2P-GROUP-FORMATION-FAILURE"}, err: ErrP2PGroupFormation},
+		{name: "canceled", fn: func(ctx context.Context) (*Group, error) {
+			return p.GroupAddPersistent(ctx, 2)
+		}, started: CmdP2pGroupAdd + " persistent=2", err: context.Canceled, last: CmdP2pCancel},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts.mut.Lock()
+			ts.last = ""
+			ts.mut.Unlock()
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			type result struct {
+				g   *Group
+				err error
+			}
+			res := make(chan result, 1)
+			go func() {
+				g, err := tt.fn(ctx)
+				res <- result{g, err}
+			}()
+
+			for i := 0; i < 100 && ts.lastCmd() != tt.started; i++ {
+				time.Sleep(time.Millisecond * 10)
+			}
+			if ts.lastCmd() != tt.started {
+				t.Fatalf("expected %s to be sent, got %s", tt.started, ts.lastCmd())
+			}
+
+			if len(tt.events) == 0 {
+				cancel()
+			}
+			ts.sendMsg(3, tt.events...)
+
+			var r result
+			select {
+			case r = <-res:
+			case <-time.After(time.Second):
+				t.Fatal("connect not finished")
+			}
+
+			if !reflect.DeepEqual(r.g, tt.group) || !reflect.DeepEqual(r.err, tt.err) {
+				t.Errorf("expected %+v, %v, got %+v, %v", tt.group, tt.err, r.g, r.err)
+			}
+
+			if tt.last != "" && ts.lastCmd() != tt.last {
+				t.Errorf("expected %s to be sent last, got %s", tt.last, ts.lastCmd())
+			}
+		})
+	}
+
+	if _, err := p.Connect(context.Background(), peer, ConnectOptions{Method: P2PPIN}); err == nil {
+		t.Error("Connect expect an error without pin")
+	}
+}
+
+func TestParseGroupStarted(t *testing.T) {
+	goAddr, _ := net.ParseMAC("02:00:00:00:00:02")
+	tests := []struct {
+		name string
+		msg  string
+		exp  *Group
+		err  bool
+	}{
+		{name: "passphrase with spaces", msg: `p2p-wlan0-0 GO ssid="DIRECT-xy" freq=2412 passphrase="my secret pass" ` +
+			"go_dev_addr=02:00:00:00:00:02",
+			exp: &Group{Ifname: "p2p-wlan0-0", Role: GroupRoleGO, SSID: "DIRECT-xy", Freq: 2412,
+				Passphrase: "my secret pass", GOAddr: goAddr}},
+		{name: "passphrase with quotes", msg: `p2p-wlan0-0 GO ssid="DIRECT-xy" freq=2412 passphrase="a" b=c" ` +
+			"go_dev_addr=02:00:00:00:00:02 [PERSISTENT]",
+			exp: &Group{Ifname: "p2p-wlan0-0", Role: GroupRoleGO, SSID: "DIRECT-xy", Freq: 2412,
+				Passphrase: `a" b=c`, GOAddr: goAddr, Persistent: true}},
+		{name: "psk", msg: `p2p-wlan0-0 client ssid="DIRECT-xy" freq=2437 psk=0011 go_dev_addr=02:00:00:00:00:02`,
+			exp: &Group{Ifname: "p2p-wlan0-0", Role: GroupRoleClient, SSID: "DIRECT-xy", Freq: 2437,
+				PSK: "0011", GOAddr: goAddr}},
+		{name: "unterminated passphrase", msg: `p2p-wlan0-0 GO ssid="DIRECT-xy" freq=2412 passphrase="`, err: true},
+		{name: "freq", msg: `p2p-wlan0-0 GO ssid="DIRECT-xy" freq=x`, err: true},
+		{name: "short", msg: "p2p-wlan0-0 GO", err: true},
+	}
+
+	for _, tt := range tests {
+		g, err := ParseGroupStarted(Event{Message: P2pEventGroupStarted + tt.msg})
+		if (err != nil) != tt.err {
+			t.Errorf("%s expect error %v, got %v", tt.name, tt.err, err)
+			continue
+		}
+		if !reflect.DeepEqual(g, tt.exp) {
+			t.Errorf("%s expected %+v, got %+v", tt.name, tt.exp, g)
+		}
+	}
+}
+
+func TestP2PGroupCommands(t *testing.T) {
+	ts, cleanup := newTestServer(t)
+	defer cleanup()
+
+	for _, cmd := range []string{CmdP2pGroupRemove, CmdP2pInvite, CmdP2pReject, CmdP2pRemoveClient} {
+		ts.setCmd(cmd, "OK")
+	}
+	ts.setCmd(CmdListNetworks, netheader+"\n0\tDIRECT-xy\t02:00:00:00:00:02\t[DISABLED][P2P-PERSISTENT]"+
+		"\n1\thome\tany\t[CURRENT]")
+
+	g, err := NewGlobal(ts.addr())
+	if err != nil {
+		t.Fatalf("NewGlobal failed, %v", err)
+	}
+	defer g.Close()
+
+	p := NewP2P(g.Interface("p2p-dev-wlan0"))
+	tests := []struct {
+		name string
+		fn   func() error
+		last string
+	}{
+		{name: "remove", fn: func() error { return p.GroupRemove("p2p-wlan0-0") },
+			last: "IFNAME=p2p-dev-wlan0 " + CmdP2pGroupRemove + " p2p-wlan0-0"},
+		{name: "invite", fn: func() error { return p.Invite("p2p-wlan0-0", "02:00:00:00:00:01") },
+			last: "IFNAME=p2p-dev-wlan0 " + CmdP2pInvite + " group=p2p-wlan0-0 peer=02:00:00:00:00:01"},
+		{name: "invite persistent", fn: func() error { return p.InvitePersistent(0, "02:00:00:00:00:01") },
+			last: "IFNAME=p2p-dev-wlan0 " + CmdP2pInvite + " persistent=0 peer=02:00:00:00:00:01"},
+		{name: "reject", fn: func() error { return p.Reject("02:00:00:00:00:01") },
+			last: "IFNAME=p2p-dev-wlan0 " + CmdP2pReject + " 02:00:00:00:00:01"},
+		{name: "remove client", fn: func() error { return p.RemoveClient("02:00:00:00:00:01") },
+			last: "IFNAME=p2p-dev-wlan0 " + CmdP2pRemoveClient + " 02:00:00:00:00:01"},
+	}
+
+	for _, tt := range tests {
+		if err := tt.fn(); err != nil {
+			t.Errorf("%s not expect an error, got %v", tt.name, err)
+		}
+		if ts.lastCmd() != tt.last {
+			t.Errorf("%s expected %s to be sent, got %s", tt.name, tt.last, ts.lastCmd())
+		}
+	}
+
+	groups, err := p.PersistentGroups()
+	if err != nil {
+		t.Fatalf("PersistentGroups not expect an error, got %v", err)
+	}
+	if len(groups) != 1 || groups[0].SSID != "DIRECT-xy" {
+		t.Errorf("unexpected persistent groups %+v", groups)
+	}
+
+	gc, err := p.GroupClient(&Group{Ifname: "p2p-wlan0-0"})
+	if err != nil {
+		t.Fatalf("GroupClient not expect an error, got %v", err)
+	}
+	gc.Execute(CmdPing)
+	if exp := "IFNAME=p2p-wlan0-0 " + CmdPing; ts.lastCmd() != exp {
+		t.Errorf("expected %s to be sent, got %s", exp, ts.lastCmd())
+	}
+}
+
+func TestRedactP2P(t *testing.T) {
+	if _, args := redact(CmdP2pConnect, []string{"02:00:00:00:00:01 12345670 display"}); !reflect.DeepEqual(args,
+		[]string{"02:00:00:00:00:01", Redacted}) {
+		t.Errorf("unexpected redacted args %v", args)
+	}
+	if _, args := redact(CmdP2pConnect, []string{"02:00:00:00:00:01 pbc"}); !reflect.DeepEqual(args,
+		[]string{"02:00:00:00:00:01", "pbc"}) {
+		t.Errorf("unexpected redacted args %v", args)
+	}
+
+	ev := "<3>" + P2pEventGroupStarted + `p2p-wlan0-0 client ssid="DIRECT-xy" freq=2437 passphrase="s3cr3t12" ` +
+		"go_dev_addr=02:00:00:00:00:02"
+	exp := "<3>" + P2pEventGroupStarted + `p2p-wlan0-0 client ssid="DIRECT-xy" freq=2437 passphrase="` + Redacted +
+		`" go_dev_addr=02:00:00:00:00:02`
+	if s := redactEvent(ev); s != exp {
+		t.Errorf("expected %s, got %s", exp, s)
+	}
+
+	ev = P2pEventGroupStarted + `p2p-wlan0-0 GO ssid="DIRECT-xy" freq=2437 passphrase="a" b c" ` +
+		"go_dev_addr=02:00:00:00:00:02 [PERSISTENT]"
+	exp = P2pEventGroupStarted + `p2p-wlan0-0 GO ssid="DIRECT-xy" freq=2437 passphrase="` + Redacted +
+		`" go_dev_addr=02:00:00:00:00:02 [PERSISTENT]`
+	if s := redactEvent(ev); s != exp {
+		t.Errorf("expected %s, got %s", exp, s)
+	}
+
+	ev = P2pEventGroupStarted + "p2p-wlan0-0 client ssid=\"DIRECT-xy\" freq=2437 psk=0011 go_dev_addr=02:00:00:00:00:02"
+	exp = P2pEventGroupStarted + "p2p-wlan0-0 client ssid=\"DIRECT-xy\" freq=2437 psk=" + Redacted +
+		" go_dev_addr=02:00:00:00:00:02"
+	if s := redactEvent(ev); s != exp {
+		t.Errorf("expected %s, got %s", exp, s)
+	}
+}
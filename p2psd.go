@@ -0,0 +1,465 @@
+package wpaclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ServiceProtocol is the protocol type of a P2P service discovery TLV
+type ServiceProtocol uint8
+
+// Service protocol types
+const (
+	ServiceAll         ServiceProtocol = 0
+	ServiceBonjour     ServiceProtocol = 1
+	ServiceUPnP        ServiceProtocol = 2
+	ServiceWSDiscovery ServiceProtocol = 3
+	ServiceWFD         ServiceProtocol = 4
+	ServiceP2PS        ServiceProtocol = 11
+)
+
+// Service discovery response statuses
+const (
+	ServiceStatusSuccess           = 0
+	ServiceStatusProtoNotAvailable = 1
+	ServiceStatusInfoNotAvailable  = 2
+	ServiceStatusBadRequest        = 3
+)
+
+// P2PService is a local service advertised with "P2P_SERVICE_ADD" command
+type P2PService interface {
+	// AddArgs returns the arguments of "P2P_SERVICE_ADD" command
+	AddArgs() []string
+	// DeleteArgs returns the arguments of "P2P_SERVICE_DEL" command
+	DeleteArgs() []string
+}
+
+// BonjourService is a DNS resource record advertised over Bonjour
+type BonjourService struct {
+	// Name is the DNS name of the record, like "_ipp._tcp.local"
+	Name string
+	// Type is the DNS record type, like 12 for PTR and 16 for TXT
+	Type uint16
+	// RData is the DNS wire format data of the record
+	RData []byte
+}
+
+// AddArgs implements P2PService
+func (s BonjourService) AddArgs() []string {
+	return []string{"bonjour", hex.EncodeToString(bonjourKey(s.Name, s.Type)), hex.EncodeToString(s.RData)}
+}
+
+// DeleteArgs implements P2PService
+func (s BonjourService) DeleteArgs() []string {
+	return []string{"bonjour", hex.EncodeToString(bonjourKey(s.Name, s.Type))}
+}
+
+// UPnPService is a UPnP service or device advertised over P2P
+type UPnPService struct {
+	// Version is the UPnP version, 0x10 for UPnP 1.0
+	Version uint8
+	// Service is like "uuid:<uuid>::urn:schemas-upnp-org:service:ContentDirectory:2"
+	Service string
+}
+
+// AddArgs implements P2PService
+func (s UPnPService) AddArgs() []string {
+	return []string{"upnp", strconv.FormatUint(uint64(s.Version), 16), s.Service}
+}
+
+// DeleteArgs implements P2PService
+func (s UPnPService) DeleteArgs() []string { return s.AddArgs() }
+
+// ASPService is an Application Service Platform service advertised over P2PS
+type ASPService struct {
+	AutoAccept    bool
+	AdvID         uint32
+	State         uint8
+	ConfigMethods uint16
+	// Name is like "org.wi-fi.wfds.print.tx"
+	Name string
+	Info string
+}
+
+// AddArgs implements P2PService
+func (s ASPService) AddArgs() []string {
+	aa := "0"
+	if s.AutoAccept {
+		aa = "1"
+	}
+
+	args := []string{"asp", aa, strconv.FormatUint(uint64(s.AdvID), 16), strconv.FormatUint(uint64(s.State), 16),
+		strconv.FormatUint(uint64(s.ConfigMethods), 16), s.Name}
+	if s.Info != "" {
+		args = append(args, s.Info)
+	}
+
+	return args
+}
+
+// DeleteArgs implements P2PService
+func (s ASPService) DeleteArgs() []string {
+	return []string{"asp", strconv.FormatUint(uint64(s.AdvID), 16)}
+}
+
+// AddService executes "P2P_SERVICE_ADD" command
+func (p *P2P) AddService(s P2PService) error {
+	_, err := p.c.Execute(CmdP2pServiceAdd, s.AddArgs()...)
+	return err
+}
+
+// DeleteService executes "P2P_SERVICE_DEL" command
+func (p *P2P) DeleteService(s P2PService) error {
+	_, err := p.c.Execute(CmdP2pServiceDel, s.DeleteArgs()...)
+	return err
+}
+
+// FlushServices executes "P2P_SERVICE_FLUSH" command, removes every local service
+func (p *P2P) FlushServices() error {
+	_, err := p.c.Execute(CmdP2pServiceFlush)
+	return err
+}
+
+// ServiceQuery is a query TLV of "P2P_SERV_DISC_REQ" command
+type ServiceQuery struct {
+	Protocol ServiceProtocol
+	Data     []byte
+}
+
+// AllServicesQuery queries every service of every protocol
+func AllServicesQuery() ServiceQuery {
+	return ServiceQuery{Protocol: ServiceAll}
+}
+
+// BonjourQuery queries Bonjour records of name and type, like "_ipp._tcp.local" and 12,
+// every Bonjour record if name is empty
+func BonjourQuery(name string, typ uint16) ServiceQuery {
+	if name == "" {
+		return ServiceQuery{Protocol: ServiceBonjour}
+	}
+
+	return ServiceQuery{Protocol: ServiceBonjour, Data: bonjourKey(name, typ)}
+}
+
+// UPnPQuery queries UPnP services matching search target st, like "ssdp:all"
+func UPnPQuery(version uint8, st string) ServiceQuery {
+	return ServiceQuery{Protocol: ServiceUPnP, Data: append([]byte{version}, st...)}
+}
+
+// ASPQuery queries ASP services with name, a trailing "*" matches a prefix,
+// and info substring if not empty
+func ASPQuery(name, info string) ServiceQuery {
+	b := append([]byte{byte(len(name))}, name...)
+	b = append(b, byte(len(info)))
+	return ServiceQuery{Protocol: ServiceP2PS, Data: append(b, info...)}
+}
+
+// encodeQueries encodes qs as query TLVs in hex, transaction ids start from one
+func encodeQueries(qs []ServiceQuery) string {
+	b := []byte{}
+	for i, q := range qs {
+		tlv := make([]byte, 4, 4+len(q.Data))
+		binary.LittleEndian.PutUint16(tlv, uint16(2+len(q.Data)))
+		tlv[2], tlv[3] = byte(q.Protocol), byte(i+1)
+		b = append(b, append(tlv, q.Data...)...)
+	}
+
+	return hex.EncodeToString(b)
+}
+
+// ServiceRecord is a response TLV of "P2P-SERV-DISC-RESP" event, Bonjour and
+// UPnP are set by protocol if data can be parsed, it is left in Data otherwise
+type ServiceRecord struct {
+	Protocol      ServiceProtocol
+	TransactionID uint8
+	Status        uint8
+	Data          []byte
+	Bonjour       *BonjourService
+	UPnP          []UPnPService
+}
+
+// ServiceResponse represents "P2P-SERV-DISC-RESP" event
+type ServiceResponse struct {
+	Peer            net.HardwareAddr
+	UpdateIndicator int
+	Records         []ServiceRecord
+}
+
+// ParseServiceResponse parses a "P2P-SERV-DISC-RESP <addr> <update indicator> <tlvs>" event
+func ParseServiceResponse(ev Event) (*ServiceResponse, error) {
+	if !strings.HasPrefix(ev.Message, P2pEventServDiscResp) {
+		return nil, fmt.Errorf("not a %s event", strings.TrimSpace(P2pEventServDiscResp))
+	}
+
+	fs := strings.Fields(strings.TrimPrefix(ev.Message, P2pEventServDiscResp))
+	if len(fs) < 2 {
+		return nil, fmt.Errorf("parse service response: %s", ev.Message)
+	}
+	if len(fs) == 2 {
+		fs = append(fs, "")
+	}
+
+	addr, err := net.ParseMAC(fs[0])
+	if err != nil {
+		return nil, fmt.Errorf("parse mac: %w", err)
+	}
+
+	ui, err := strconv.Atoi(fs[1])
+	if err != nil {
+		return nil, fmt.Errorf("parse update indicator: %w", err)
+	}
+
+	b, err := hex.DecodeString(fs[2])
+	if err != nil {
+		return nil, fmt.Errorf("parse tlvs: %w", err)
+	}
+
+	sr := &ServiceResponse{Peer: addr, UpdateIndicator: ui, Records: []ServiceRecord{}}
+	for len(b) > 0 {
+		if len(b) < 5 {
+			return nil, errors.New("parse tlvs: short tlv")
+		}
+
+		l := int(binary.LittleEndian.Uint16(b))
+		if l < 3 || len(b) < 2+l {
+			return nil, fmt.Errorf("parse tlvs: invalid length %d", l)
+		}
+
+		r := ServiceRecord{Protocol: ServiceProtocol(b[2]), TransactionID: b[3], Status: b[4],
+			Data: append([]byte{}, b[5:2+l]...)}
+		b = b[2+l:]
+
+		if r.Status == ServiceStatusSuccess && len(r.Data) > 0 {
+			r.parse()
+		}
+
+		sr.Records = append(sr.Records, r)
+	}
+
+	return sr, nil
+}
+
+func (r *ServiceRecord) parse() {
+	switch r.Protocol {
+	case ServiceBonjour:
+		// <dns name> <type> <version> <rdata>
+		name, n, err := decodeDNSName(r.Data)
+		if err != nil || len(r.Data) < n+3 {
+			return
+		}
+
+		r.Bonjour = &BonjourService{Name: name, Type: binary.BigEndian.Uint16(r.Data[n:]),
+			RData: r.Data[n+3:]}
+	case ServiceUPnP:
+		// <version> <comma separated services>
+		for _, s := range strings.Split(string(r.Data[1:]), ",") {
+			if s != "" {
+				r.UPnP = append(r.UPnP, UPnPService{Version: r.Data[0], Service: s})
+			}
+		}
+	}
+}
+
+// ParseASPResponse parses a "P2P-SERV-ASP-RESP <addr> <transaction id> <adv id>
+// <status> <config methods> <name> '<info>'" event, services found with ASPQuery
+// are reported with it
+func ParseASPResponse(ev Event) (net.HardwareAddr, *ASPService, error) {
+	if !strings.HasPrefix(ev.Message, P2pEventServAspResp) {
+		return nil, nil, fmt.Errorf("not a %s event", strings.TrimSpace(P2pEventServAspResp))
+	}
+
+	msg := strings.TrimPrefix(ev.Message, P2pEventServAspResp)
+	fs := strings.SplitN(msg, " ", 7)
+	if len(fs) < 6 {
+		return nil, nil, fmt.Errorf("parse asp response: %s", msg)
+	}
+
+	addr, err := net.ParseMAC(fs[0])
+	if err != nil {
+		return nil, nil, fmt.Errorf("parse mac: %w", err)
+	}
+
+	s := &ASPService{Name: fs[5]}
+	for i, bits := range []int{32, 8, 16} {
+		n, err := strconv.ParseUint(fs[i+2], 16, bits)
+		if err != nil {
+			return nil, nil, fmt.Errorf("parse asp response: %w", err)
+		}
+
+		switch i {
+		case 0:
+			s.AdvID = uint32(n)
+		case 1:
+			s.State = uint8(n)
+		case 2:
+			s.ConfigMethods = uint16(n)
+		}
+	}
+
+	if len(fs) == 7 {
+		s.Info = strings.TrimSuffix(strings.TrimPrefix(fs[6], "'"), "'")
+	}
+
+	return addr, s, nil
+}
+
+// DiscoverServices executes "P2P_SERV_DISC_REQ" command with queries to peer,
+// all services are queried if none given. It waits for the response of peer,
+// the request is canceled with "P2P_SERV_DISC_CANCEL_REQ" when ctx is done.
+// Use DiscoverAllServices to query every peer with the broadcast address.
+func (p *P2P) DiscoverServices(ctx context.Context, peer string, queries ...ServiceQuery) ([]ServiceRecord, error) {
+	addr, err := net.ParseMAC(peer)
+	if err != nil {
+		return nil, fmt.Errorf("parse mac: %w", err)
+	}
+	if bytes.Equal(addr, make(net.HardwareAddr, len(addr))) {
+		return nil, errors.New("broadcast address has no single response, use DiscoverAllServices")
+	}
+
+	var recs []ServiceRecord
+	err = p.discover(ctx, peer, queries, func(sr *ServiceResponse) bool {
+		if sr.Peer.String() != addr.String() {
+			return false
+		}
+		recs = sr.Records
+		return true
+	})
+
+	return recs, err
+}
+
+// DiscoverAllServices executes "P2P_SERV_DISC_REQ" command with queries to
+// every peer, all services are queried if none given. Peers answer whenever
+// they are found, so responses are collected for window and the request is
+// canceled with "P2P_SERV_DISC_CANCEL_REQ". Collected responses are returned
+// when ctx is done before window ends.
+func (p *P2P) DiscoverAllServices(ctx context.Context, window time.Duration, queries ...ServiceQuery) ([]ServiceResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, window)
+	defer cancel()
+
+	srs := []ServiceResponse{}
+	err := p.discover(ctx, "00:00:00:00:00:00", queries, func(sr *ServiceResponse) bool {
+		srs = append(srs, *sr)
+		return false
+	})
+	if err == context.DeadlineExceeded || err == context.Canceled {
+		return srs, nil
+	}
+
+	return srs, err
+}
+
+// discover requests queries from peer and passes responses to fn until it
+// returns true or ctx is done. Malformed responses are skipped.
+func (p *P2P) discover(ctx context.Context, peer string, queries []ServiceQuery, fn func(*ServiceResponse) bool) error {
+	if len(queries) == 0 {
+		queries = []ServiceQuery{AllServicesQuery()}
+	}
+
+	ch, err := p.c.Notify(P2pEventServDiscResp)
+	if err != nil {
+		return err
+	}
+	defer p.c.Stop(ch)
+
+	res, err := p.c.Execute(CmdP2pServDiscReq, peer, encodeQueries(queries))
+	if err != nil {
+		return err
+	}
+	id := strings.TrimSpace(string(res))
+
+	for {
+		select {
+		case <-ctx.Done():
+			p.c.Execute(CmdP2pServDiscCancelReq, id)
+			return ctx.Err()
+		case ev, ok := <-ch:
+			if !ok {
+				return errors.New("event channel closed")
+			}
+
+			sr, err := ParseServiceResponse(ev)
+			if err != nil {
+				continue
+			}
+			if fn(sr) {
+				return nil
+			}
+		}
+	}
+}
+
+// bonjourDict is the fake DNS message Bonjour names are compressed against,
+// "_tcp.local" at 0x0c, "local" at 0x11 and "_udp.local" at 0x18
+var bonjourDict = []byte("\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00" +
+	"\x04_tcp\x05local\x00" + "\x04_udp\xc0\x11")
+
+// bonjourKey returns name in DNS wire format, compressed against bonjourDict,
+// followed by typ and version 1
+func bonjourKey(name string, typ uint16) []byte {
+	name = strings.TrimSuffix(name, ".")
+
+	b, ptr := []byte{}, []byte{0}
+	for suffix, p := range map[string][]byte{"._tcp.local": {0xc0, 0x0c}, "._udp.local": {0xc0, 0x18}} {
+		if strings.HasSuffix(name, suffix) {
+			name, ptr = strings.TrimSuffix(name, suffix), p
+		}
+	}
+	if len(ptr) == 1 && strings.HasSuffix(name, ".local") {
+		name, ptr = strings.TrimSuffix(name, ".local"), []byte{0xc0, 0x11}
+	}
+
+	for _, l := range strings.Split(name, ".") {
+		if l != "" {
+			b = append(append(b, byte(len(l))), l...)
+		}
+	}
+	b = append(b, ptr...)
+
+	return append(b, byte(typ>>8), byte(typ), 1)
+}
+
+// decodeDNSName decodes a DNS name at the start of b, pointers are resolved
+// against bonjourDict, it returns the name and the length it takes in b
+func decodeDNSName(b []byte) (string, int, error) {
+	labels := []string{}
+	msg, i, n := b, 0, -1
+
+	for hops := 0; ; {
+		if i >= len(msg) {
+			return "", 0, errors.New("parse dns name: short name")
+		}
+
+		l := int(msg[i])
+		switch {
+		case l == 0:
+			if n < 0 {
+				n = i + 1
+			}
+			return strings.Join(labels, "."), n, nil
+		case l&0xc0 == 0xc0:
+			if i+1 >= len(msg) || hops > 10 {
+				return "", 0, errors.New("parse dns name: invalid pointer")
+			}
+			if n < 0 {
+				n = i + 2
+			}
+			msg, i = bonjourDict, (l&0x3f)<<8|int(msg[i+1])
+			hops++
+		default:
+			if i+1+l > len(msg) {
+				return "", 0, errors.New("parse dns name: short label")
+			}
+			labels = append(labels, string(msg[i+1:i+1+l]))
+			i += 1 + l
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package wpaclient
+
+import (
+	"context"
+	"encoding/hex"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestP2PServices(t *testing.T) {
+	ts, cleanup := newTestServer(t)
+	defer cleanup()
+
+	for _, cmd := range []string{CmdP2pServiceAdd, CmdP2pServiceDel, CmdP2pServiceFlush} {
+		ts.setCmd(cmd, "OK")
+	}
+
+	c, err := New(ts.addr())
+	if err != nil {
+		t.Fatalf("New Client failed, %v", err)
+	}
+	defer c.Close()
+
+	p := NewP2P(c)
+	bonjour := BonjourService{Name: "_ipp._tcp.local", Type: 12, RData: []byte("\x09MyPrinter\xc0\x27")}
+	upnp := UPnPService{Version: 0x10, Service: "uuid:6859dede-8574-59ab-9332-123456789012::upnp:rootdevice"}
+	asp := ASPService{AutoAccept: true, AdvID: 0x1234, State: 1, ConfigMethods: 0x108, Name: "org.wi-fi.wfds.print.tx",
+		Info: "color"}
+
+	tests := []struct {
+		name string
+		fn   func() error
+		last string
+	}{
+		{name: "add bonjour", fn: func() error { return p.AddService(bonjour) },
+			last: CmdP2pServiceAdd + " bonjour 045f697070c00c000c01 094d795072696e746572c027"},
+		{name: "delete bonjour", fn: func() error { return p.DeleteService(bonjour) },
+			last: CmdP2pServiceDel + " bonjour 045f697070c00c000c01"},
+		{name: "add upnp", fn: func() error { return p.AddService(upnp) },
+			last: CmdP2pServiceAdd + " upnp 10 " + upnp.Service},
+		{name: "add asp", fn: func() error { return p.AddService(asp) },
+			last: CmdP2pServiceAdd + " asp 1 1234 1 108 org.wi-fi.wfds.print.tx color"},
+		{name: "delete asp", fn: func() error { return p.DeleteService(asp) },
+			last: CmdP2pServiceDel + " asp 1234"},
+		{name: "flush", fn: p.FlushServices, last: CmdP2pServiceFlush},
+	}
+
+	for _, tt := range tests {
+		if err := tt.fn(); err != nil {
+			t.Errorf("%s not expect an error, got %v", tt.name, err)
+		}
+		if ts.lastCmd() != tt.last {
+			t.Errorf("%s expected %s to be sent, got %s", tt.name, tt.last, ts.lastCmd())
+		}
+	}
+}
+
+func TestEncodeQueries(t *testing.T) {
+	tests := []struct {
+		name    string
+		queries []ServiceQuery
+		exp     string
+	}{
+		{name: "all", queries: []ServiceQuery{AllServicesQuery()}, exp: "02000001"},
+		{name: "bonjour all", queries: []ServiceQuery{BonjourQuery("", 0)}, exp: "02000101"},
+		{name: "bonjour and upnp", queries: []ServiceQuery{BonjourQuery("_ipp._tcp.local", 12), UPnPQuery(0x10, "ssdp:all")},
+			exp: "0c0001010" + "45f697070c00c000c01" + "0b00020210" + hex.EncodeToString([]byte("ssdp:all"))},
+		{name: "asp", queries: []ServiceQuery{ASPQuery("org.wi-fi.wfds.print*", "")},
+			exp: "19000b0115" + hex.EncodeToString([]byte("org.wi-fi.wfds.print*")) + "00"},
+	}
+
+	for _, tt := range tests {
+		if s := encodeQueries(tt.queries); s != tt.exp {
+			t.Errorf("%s expected %s, got %s", tt.name, tt.exp, s)
+		}
+	}
+}
+
+func TestParseServiceResponse(t *testing.T) {
+	bonjour := "045f697070c00c000c01" + "094d795072696e746572c027"
+	upnp := "10" + hex.EncodeToString([]byte("uuid:1::upnp:rootdevice,uuid:1::urn:schemas-upnp-org:device:MediaRenderer:1"))
+	tlv := func(hdr, data string) string {
+		return hex.EncodeToString([]byte{byte(3 + len(data)/2), 0}) + hdr + data
+	}
+	tlvs := tlv("010100", bonjour) + tlv("020200", upnp) + tlv("030301", "")
+
+	sr, err := ParseServiceResponse(Event{Message: P2pEventServDiscResp + "02:00:00:00:00:01 3 " + tlvs})
+	if err != nil {
+		t.Fatalf("ParseServiceResponse not expect an error, got %v", err)
+	}
+
+	if sr.Peer.String() != "02:00:00:00:00:01" || sr.UpdateIndicator != 3 || len(sr.Records) != 3 {
+		t.Fatalf("unexpected response %+v", sr)
+	}
+
+	exp := &BonjourService{Name: "_ipp._tcp.local", Type: 12, RData: []byte("\x09MyPrinter\xc0\x27")}
+	if !reflect.DeepEqual(sr.Records[0].Bonjour, exp) {
+		t.Errorf("expected %+v, got %+v", exp, sr.Records[0].Bonjour)
+	}
+
+	expUPnP := []UPnPService{{Version: 0x10, Service: "uuid:1::upnp:rootdevice"},
+		{Version: 0x10, Service: "uuid:1::urn:schemas-upnp-org:device:MediaRenderer:1"}}
+	if !reflect.DeepEqual(sr.Records[1].UPnP, expUPnP) {
+		t.Errorf("expected %+v, got %+v", expUPnP, sr.Records[1].UPnP)
+	}
+
+	if r := sr.Records[2]; r.Protocol != ServiceWSDiscovery || r.Status != ServiceStatusProtoNotAvailable {
+		t.Errorf("unexpected record %+v", r)
+	}
+
+	if _, err := ParseServiceResponse(Event{Message: P2pEventServDiscResp + "02:00:00:00:00:01 3 0f0001"}); err == nil {
+		t.Error("ParseServiceResponse expect an error")
+	}
+}
+
+func TestParseASPResponse(t *testing.T) {
+	addr, s, err := ParseASPResponse(Event{Message: P2pEventServAspResp +
+		"02:00:00:00:00:01 1 1234 1 108 org.wi-fi.wfds.print.tx 'color duplex'"})
+	if err != nil {
+		t.Fatalf("ParseASPResponse not expect an error, got %v", err)
+	}
+
+	mac, _ := net.ParseMAC("02:00:00:00:00:01")
+	exp := &ASPService{AdvID: 0x1234, State: 1, ConfigMethods: 0x108, Name: "org.wi-fi.wfds.print.tx", Info: "color duplex"}
+	if !reflect.DeepEqual(addr, mac) || !reflect.DeepEqual(s, exp) {
+		t.Errorf("expected %s %+v, got %s %+v", mac, exp, addr, s)
+	}
+}
+
+func TestDiscoverServices(t *testing.T) {
+	ts, cleanup := newTestServer(t)
+	defer cleanup()
+
+	ts.setCmd(CmdP2pServDiscReq, "1f77628")
+	ts.setCmd(CmdP2pServDiscCancelReq, "OK")
+
+	c, err := New(ts.addr())
+	if err != nil {
+		t.Fatalf("New Client failed, %v", err)
+	}
+	defer c.Close()
+
+	p := NewP2P(c)
+	peer := "02:00:00:00:00:01"
+
+	type result struct {
+		recs []ServiceRecord
+		err  error
+	}
+	discover := func(ctx context.Context) chan result {
+		ts.mut.Lock()
+		ts.last = ""
+		ts.mut.Unlock()
+		res := make(chan result, 1)
+		go func() {
+			recs, err := p.DiscoverServices(ctx, peer)
+			res <- result{recs, err}
+		}()
+
+		started := CmdP2pServDiscReq + " " + peer + " 02000001"
+		for i := 0; i < 100 && ts.lastCmd() != started; i++ {
+			time.Sleep(time.Millisecond * 10)
+		}
+		if ts.lastCmd() != started {
+			t.Fatalf("expected %s to be sent, got %s", started, ts.lastCmd())
+		}
+
+		return res
+	}
+
+	res := discover(context.Background())
+	ts.sendMsg(3, P2pEventServDiscResp+"02:00:00:00:00:02 1 0300010101", P2pEventServDiscResp+peer+" 1 zz",
+		P2pEventServDiscResp+peer+" 1 0300020101")
+
+	select {
+	case r := <-res:
+		if r.err != nil || len(r.recs) != 1 || r.recs[0].Protocol != ServiceUPnP {
+			t.Errorf("unexpected records %+v, %v", r.recs, r.err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("discovery not finished")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	res = discover(ctx)
+	cancel()
+
+	select {
+	case r := <-res:
+		if r.err != context.Canceled {
+			t.Errorf("expected error %v, got %v", context.Canceled, r.err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("discovery not finished")
+	}
+	if exp := CmdP2pServDiscCancelReq + " 1f77628"; ts.lastCmd() != exp {
+		t.Errorf("expected %s to be sent, got %s", exp, ts.lastCmd())
+	}
+
+	if _, err := p.DiscoverServices(context.Background(), "00:00:00:00:00:00"); err == nil {
+		t.Error("DiscoverServices expect an error for broadcast address")
+	}
+
+	all := make(chan []ServiceResponse, 1)
+	go func() {
+		srs, err := p.DiscoverAllServices(context.Background(), time.Millisecond*300, UPnPQuery(0x10, "ssdp:all"))
+		if err != nil {
+			t.Errorf("DiscoverAllServices not expect an error, got %v", err)
+		}
+		all <- srs
+	}()
+
+	started := CmdP2pServDiscReq + " 00:00:00:00:00:00 " + encodeQueries([]ServiceQuery{UPnPQuery(0x10, "ssdp:all")})
+	for i := 0; i < 100 && ts.lastCmd() != started; i++ {
+		time.Sleep(time.Millisecond * 10)
+	}
+	if ts.lastCmd() != started {
+		t.Fatalf("expected %s to be sent, got %s", started, ts.lastCmd())
+	}
+	ts.sendMsg(3, P2pEventServDiscResp+"02:00:00:00:00:02 1 0300020101", P2pEventServDiscResp+peer+" 1 zz",
+		P2pEventServDiscResp+peer+" 1 0300020101")
+
+	select {
+	case srs := <-all:
+		if len(srs) != 2 || srs[0].Peer.String() != "02:00:00:00:00:02" || srs[1].Peer.String() != peer {
+			t.Errorf("unexpected responses %+v", srs)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("discovery not finished")
+	}
+	if exp := CmdP2pServDiscCancelReq + " 1f77628"; ts.lastCmd() != exp {
+		t.Errorf("expected %s to be sent, got %s", exp, ts.lastCmd())
+	}
+}
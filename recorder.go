@@ -0,0 +1,247 @@
+package wpaclient
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Record kinds
+const (
+	RecordCommand = "command"
+	RecordEvent   = "event"
+)
+
+// Record represents a single recorded exchange,
+// Data holds the raw command or event datagram
+type Record struct {
+	Time     time.Time `json:"time"`
+	Kind     string    `json:"kind"`
+	Data     string    `json:"data"`
+	Response string    `json:"response,omitempty"`
+	Err      string    `json:"error,omitempty"`
+}
+
+// EventRecorder writes every command/response exchange and
+// raw event datagram of a Client as timestamped JSON lines,
+// secret command arguments, WPS PIN responses and credentials are redacted
+type EventRecorder struct {
+	mut sync.Mutex
+	enc *json.Encoder
+	err error
+	now func() time.Time
+}
+
+// NewEventRecorder returns an EventRecorder writing to w
+func NewEventRecorder(w io.Writer) *EventRecorder {
+	return &EventRecorder{enc: json.NewEncoder(w), now: time.Now}
+}
+
+// Err returns the first write error occurred, if any
+func (r *EventRecorder) Err() error {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	return r.err
+}
+
+func (r *EventRecorder) command(cmd, res []byte, err error) {
+	if r == nil {
+		return
+	}
+
+	data, secretRes := redactCommand(string(cmd))
+	rec := Record{Kind: RecordCommand, Data: data, Response: string(res)}
+	if secretRes && res != nil {
+		rec.Response = Redacted
+	}
+	if err != nil {
+		rec.Err = err.Error()
+	}
+
+	r.write(rec)
+}
+
+func (r *EventRecorder) event(b []byte) {
+	if r == nil {
+		return
+	}
+
+	r.write(Record{Kind: RecordEvent, Data: redactEvent(string(b))})
+}
+
+func (r *EventRecorder) write(rec Record) {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	if r.err != nil {
+		return
+	}
+
+	rec.Time = r.now()
+	if err := r.enc.Encode(rec); err != nil {
+		r.err = fmt.Errorf("write record: %w", err)
+	}
+}
+
+// ReadRecords reads JSON lines written by an EventRecorder
+func ReadRecords(r io.Reader) ([]Record, error) {
+	recs := []Record{}
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 4096), 1024*1024)
+	for i := 1; sc.Scan(); i++ {
+		if len(strings.TrimSpace(sc.Text())) == 0 {
+			continue
+		}
+
+		var rec Record
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			return nil, fmt.Errorf("parse record %d: %w", i, err)
+		}
+		recs = append(recs, rec)
+	}
+
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	return recs, nil
+}
+
+// EventReplayer serves a recording as a fake wpa_supplicant control socket.
+// Recorded responses are returned for matching commands, in recorded order,
+// and recorded events are sent to attached monitors with their original timing.
+type EventReplayer struct {
+	// Speed scales the delay between events, 2 plays twice as fast.
+	// Zero or less sends events without delay.
+	Speed float64
+
+	events []Record
+	resp   map[string][]string
+	// time of the first record, events are delayed from it
+	start time.Time
+
+	mut      sync.Mutex
+	monitors map[string]net.Addr
+	started  bool
+	done     chan struct{}
+}
+
+// NewEventReplayer reads a recording from r and returns an EventReplayer
+func NewEventReplayer(r io.Reader) (*EventReplayer, error) {
+	recs, err := ReadRecords(r)
+	if err != nil {
+		return nil, err
+	}
+
+	rp := &EventReplayer{Speed: 1, resp: make(map[string][]string),
+		monitors: make(map[string]net.Addr), done: make(chan struct{})}
+
+	if len(recs) > 0 {
+		rp.start = recs[0].Time
+	}
+
+	for _, rec := range recs {
+		switch rec.Kind {
+		case RecordEvent:
+			rp.events = append(rp.events, rec)
+		case RecordCommand:
+			if rec.Err != "" && rec.Response == "" {
+				continue
+			}
+			rp.resp[rec.Data] = append(rp.resp[rec.Data], rec.Response)
+		}
+	}
+
+	return rp, nil
+}
+
+// Done returns a channel closed after all recorded events are sent
+func (rp *EventReplayer) Done() <-chan struct{} {
+	return rp.done
+}
+
+// Serve answers commands received on conn until conn is closed.
+// Event playback starts when the first monitor attaches.
+func (rp *EventReplayer) Serve(conn net.PacketConn) error {
+	b := make([]byte, maxDatagram)
+	for {
+		n, addr, err := conn.ReadFrom(b)
+		if err != nil {
+			if err == io.EOF || strings.Contains(err.Error(), "use of closed network connection") {
+				return nil
+			}
+			return err
+		}
+
+		cmd := string(b[:n])
+		switch cmd {
+		case cmdAttach:
+			rp.mut.Lock()
+			rp.monitors[addr.String()] = addr
+			start := !rp.started
+			rp.started = true
+			rp.mut.Unlock()
+
+			conn.WriteTo([]byte("OK\n"), addr)
+			if start {
+				go rp.play(conn)
+			}
+		case cmdDetach:
+			rp.mut.Lock()
+			delete(rp.monitors, addr.String())
+			rp.mut.Unlock()
+
+			conn.WriteTo([]byte("OK\n"), addr)
+		default:
+			conn.WriteTo([]byte(rp.response(cmd)), addr)
+		}
+	}
+}
+
+// response pops the next recorded response of cmd,
+// the last one is repeated when the queue is exhausted.
+// Secrets are redacted in recordings, so is cmd before matching.
+func (rp *EventReplayer) response(cmd string) string {
+	cmd, _ = redactCommand(cmd)
+
+	rp.mut.Lock()
+	defer rp.mut.Unlock()
+
+	q := rp.resp[cmd]
+	switch len(q) {
+	case 0:
+		return "UNKNOWN COMMAND\n"
+	case 1:
+		return q[0]
+	}
+
+	rp.resp[cmd] = q[1:]
+	return q[0]
+}
+
+func (rp *EventReplayer) play(conn net.PacketConn) {
+	defer close(rp.done)
+
+	// the first event is delayed by its offset in the recording too
+	prev := rp.start
+	for _, ev := range rp.events {
+		if rp.Speed > 0 {
+			gap := ev.Time.Sub(prev)
+			time.Sleep(time.Duration(float64(gap) / rp.Speed))
+		}
+		prev = ev.Time
+
+		rp.mut.Lock()
+		for _, addr := range rp.monitors {
+			conn.WriteTo([]byte(ev.Data), addr)
+		}
+		rp.mut.Unlock()
+	}
+}
@@ -0,0 +1,202 @@
+package wpaclient
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEventRecorder(t *testing.T) {
+	ts, close := newTestServer(t)
+	defer close()
+
+	buf := &bytes.Buffer{}
+	rec := NewEventRecorder(buf)
+
+	c, err := New(ts.addr(), WithRecorder(rec))
+	if err != nil {
+		t.Fatalf("New Client failed, %v", err)
+	}
+	defer c.Close()
+
+	ch, err := c.Notify(WpaEventConnected)
+	if err != nil {
+		t.Fatalf("Notify not expect an error, got %v", err)
+	}
+
+	if _, err := c.Execute(CmdPing); err != nil {
+		t.Errorf("Execute not expect an error, got %v", err)
+	}
+
+	if _, err := c.Execute("EVENTS"); err != nil {
+		t.Errorf("Execute not expect an error, got %v", err)
+	}
+
+	select {
+	case <-ch:
+	case <-time.After(time.Second):
+		t.Fatal("event not received")
+	}
+	time.Sleep(time.Millisecond * 10)
+
+	if err := rec.Err(); err != nil {
+		t.Errorf("Err not expect an error, got %v", err)
+	}
+
+	recs, err := ReadRecords(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("ReadRecords not expect an error, got %v", err)
+	}
+
+	if len(recs) < 3 {
+		t.Fatalf("expect at least 3 records, got %d", len(recs))
+	}
+
+	exp := Record{Kind: RecordCommand, Data: CmdPing, Response: "PONG\n"}
+	if recs[0].Time.IsZero() {
+		t.Errorf("expect record time to be set")
+	}
+	recs[0].Time = time.Time{}
+	if !reflect.DeepEqual(recs[0], exp) {
+		t.Errorf("Record expected %#v, got %#v", exp, recs[0])
+	}
+
+	evs := 0
+	for _, r := range recs {
+		if r.Kind == RecordEvent {
+			evs++
+		}
+	}
+	if evs != 11 {
+		t.Errorf("expect 11 event records, got %d", evs)
+	}
+}
+
+func TestReadRecords(t *testing.T) {
+	if _, err := ReadRecords(bytes.NewBufferString("{\"kind\":\n")); err == nil {
+		t.Error("ReadRecords expect an error, got <nil>")
+	}
+
+	recs, err := ReadRecords(bytes.NewBufferString("\n"))
+	if err != nil || len(recs) != 0 {
+		t.Errorf("ReadRecords expect no records, got %v, %v", recs, err)
+	}
+}
+
+func TestEventReplayer(t *testing.T) {
+	start := time.Now()
+	recs := []Record{
+		{Time: start, Kind: RecordCommand, Data: CmdPing, Response: "PONG\n"},
+		{Time: start, Kind: RecordCommand, Data: CmdListNetworks, Response: netheader + "\n"},
+		{Time: start, Kind: RecordCommand, Data: CmdListNetworks, Response: netheader + "\n0\tAP0\tany\t[CURRENT]\n"},
+		{Time: start.Add(time.Second), Kind: RecordEvent, Data: "<2>" + WpaEventDisconnected + "bssid=00:1f:1f:37:42:d9 reason=3\n"},
+		{Time: start.Add(time.Second * 2), Kind: RecordEvent, Data: "<2>" + WpaEventConnected + "- Connection to 00:1f:1f:37:42:d9 completed\n"},
+		{Time: start.Add(time.Second * 2), Kind: RecordCommand, Data: "NOPE", Err: "read failed"},
+		{Time: start.Add(time.Second * 2), Kind: RecordCommand, Data: CmdNote + " " + strings.Repeat("a", 5000), Response: "OK\n"},
+	}
+
+	buf := &bytes.Buffer{}
+	rec := NewEventRecorder(buf)
+	for _, r := range recs {
+		rec.now = func() time.Time { return r.Time }
+		rec.write(r)
+	}
+
+	rp, err := NewEventReplayer(buf)
+	if err != nil {
+		t.Fatalf("NewEventReplayer not expect an error, got %v", err)
+	}
+	rp.Speed = 100
+
+	conn, fn, err := testServerConn()
+	if err != nil {
+		t.Fatalf("listen failed, %v", err)
+	}
+	defer fn()
+
+	go rp.Serve(conn)
+
+	c, err := New(conn.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("New Client failed, %v", err)
+	}
+	defer c.Close()
+
+	for i, n := range []int{0, 1, 1} {
+		ns, err := c.ListNetworks()
+		if err != nil {
+			t.Fatalf("ListNetworks not expect an error, got %v", err)
+		}
+		if len(ns) != n {
+			t.Errorf("ListNetworks call %d expects %d networks, got %d", i, n, len(ns))
+		}
+	}
+
+	if _, err := c.Execute("NOPE"); !errors.Is(err, ErrUnknownCmd) {
+		t.Errorf("Execute expect error %v, got %v", ErrUnknownCmd, err)
+	}
+
+	// commands longer than 4095 bytes are read whole
+	if _, err := c.Execute(CmdNote, strings.Repeat("a", 5000)); err != nil {
+		t.Errorf("Execute not expect an error, got %v", err)
+	}
+
+	ch, err := c.Notify()
+	if err != nil {
+		t.Fatalf("Notify not expect an error, got %v", err)
+	}
+
+	st := time.Now()
+	for _, exp := range []string{WpaEventDisconnected, WpaEventConnected} {
+		select {
+		case ev := <-ch:
+			if !bytes.HasPrefix([]byte(ev.Message), []byte(exp)) {
+				t.Errorf("expect event %s, got %s", exp, ev.Message)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("event %s not received", exp)
+		}
+	}
+
+	// first event is recorded a second after start, the second one a second later
+	if d := time.Since(st); d < time.Millisecond*15 {
+		t.Errorf("expect events to be delayed, took %s", d)
+	}
+
+	select {
+	case <-rp.Done():
+	case <-time.After(time.Second):
+		t.Error("replay not done")
+	}
+}
+
+func TestEventRecorderRedact(t *testing.T) {
+	buf := &bytes.Buffer{}
+	rec := NewEventRecorder(buf)
+	rec.command([]byte(CmdIfname+`=wlan0 `+CmdSetNetwork+` 0 psk "secret"`), []byte("OK\n"), nil)
+	rec.command([]byte(CmdWpsPin+" any"), []byte("12345670\n"), nil)
+	rec.command([]byte(CmdSetNetwork+` 0 ssid "my  home"`), []byte("OK\n"), nil)
+
+	if strings.Contains(buf.String(), "secret") || strings.Contains(buf.String(), "12345670") {
+		t.Errorf("expected secrets to be redacted, got %s", buf)
+	}
+
+	rp, err := NewEventReplayer(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("NewEventReplayer not expect an error, got %v", err)
+	}
+
+	for cmd, exp := range map[string]string{
+		CmdIfname + `=wlan0 ` + CmdSetNetwork + ` 0 psk "other"`: "OK\n",
+		CmdWpsPin + " any":                   Redacted,
+		CmdSetNetwork + ` 0 ssid "my  home"`: "OK\n",
+		CmdSetNetwork + ` 0 ssid "my home"`:  "UNKNOWN COMMAND\n",
+	} {
+		if res := rp.response(cmd); res != exp {
+			t.Errorf("response of %s expected %q, got %q", cmd, exp, res)
+		}
+	}
+}
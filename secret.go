@@ -0,0 +1,51 @@
+package wpaclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+)
+
+// Secret is a password, passphrase, key or PIN. It is printed, logged
+// and marshaled as Redacted so it does not leak, Reveal returns its value.
+// An empty Secret is printed as an empty string.
+type Secret string
+
+// Reveal returns the value of s
+func (s Secret) Reveal() string { return string(s) }
+
+// String returns Redacted
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+
+	return Redacted
+}
+
+// Format implements fmt.Formatter, every verb prints Redacted
+func (s Secret) Format(f fmt.State, verb rune) {
+	if verb == 'q' || verb == 'v' && f.Flag('#') {
+		io.WriteString(f, strconv.Quote(s.String()))
+		return
+	}
+
+	io.WriteString(f, s.String())
+}
+
+// MarshalJSON marshals s as Redacted
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// MarshalText marshals s as Redacted
+func (s Secret) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// LogValue implements slog.LogValuer
+func (s Secret) LogValue() slog.Value {
+	return slog.StringValue(s.String())
+}
@@ -0,0 +1,40 @@
+package wpaclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestSecret(t *testing.T) {
+	s := Secret("p4ssw0rd")
+	if s.Reveal() != "p4ssw0rd" {
+		t.Errorf("Reveal expected p4ssw0rd, got %s", s.Reveal())
+	}
+
+	cfg := NetworkConfig{SSID: "home", PSK: s}
+	for _, f := range []string{"%s", "%v", "%+v", "%#v", "%q", "%x", "%d"} {
+		if out := fmt.Sprintf(f, cfg); !strings.Contains(out, Redacted) || strings.Contains(out, "p4ssw0rd") {
+			t.Errorf("format %s expected secret to be redacted, got %s", f, out)
+		}
+	}
+
+	b, err := json.Marshal(cfg)
+	if err != nil {
+		t.Fatalf("Marshal not expect an error, got %v", err)
+	}
+
+	if !strings.Contains(string(b), `"PSK":"`+Redacted+`"`) || strings.Contains(string(b), "p4ssw0rd") {
+		t.Errorf("Marshal expected secret to be redacted, got %s", b)
+	}
+
+	if out := fmt.Sprint(Secret("")); out != "" {
+		t.Errorf("empty Secret expected to be printed empty, got %s", out)
+	}
+
+	var u NetworkConfig
+	if err := json.Unmarshal([]byte(`{"PSK":"p4ssw0rd"}`), &u); err != nil || u.PSK != s {
+		t.Errorf("Unmarshal expected PSK to be set, got %v, %v", u.PSK.Reveal(), err)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"net"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -17,11 +18,16 @@ type testConn interface {
 }
 
 type testServer struct {
+	// mut guards the fields below conn, they are shared with the serve goroutine
+	mut      sync.Mutex
 	conn     testConn
 	subAddr  map[string]net.Addr
 	networks []Network
+	p2pPeers []string
 	scanned  bool
 	cmdMap   map[string]string
+	last     string
+	cookie   string
 	t        *testing.T
 }
 
@@ -61,10 +67,8 @@ func unmarshalNetwork(ns []Network) string {
 }
 
 func (ts *testServer) run() {
-	scanheader := "bssid / frequency / signal level / flags / ssid"
-
 	go func() {
-		b := make([]byte, 4095)
+		b := make([]byte, maxDatagram)
 		for {
 			n, raddr, err := ts.conn.ReadFrom(b)
 			if err != nil {
@@ -74,114 +78,183 @@ func (ts *testServer) run() {
 				ts.t.Errorf("TestServer Read error: %s", err)
 			}
 
-			sc := strings.Split(string(b[:n]), " ")
-			scmd := sc[0]
-			args := []string{}
-			if len(sc) > 1 && sc[1] != "" {
-				args = sc[1:]
-			}
+			ts.handle(string(b[:n]), raddr)
+		}
+	}()
+}
 
-			if res, ok := ts.cmdMap[scmd]; ok {
-				ts.write(res, raddr)
-				continue
-			}
+func (ts *testServer) handle(req string, raddr net.Addr) {
+	scanheader := "bssid / frequency / signal level / flags / ssid"
 
-			switch scmd {
-			case cmdAttach:
-				if _, ok := ts.subAddr[raddr.String()]; !ok {
-					ts.subAddr[raddr.String()] = raddr
-					ts.write("OK", raddr)
-				}
-			case cmdDetach:
-				if _, ok := ts.subAddr[raddr.String()]; ok {
-					delete(ts.subAddr, raddr.String())
-					ts.write("OK", raddr)
-				}
-			// not a standart wpa command
-			case "CLOSE":
-				if err := ts.conn.Close(); err != nil {
-					ts.t.Errorf("Close error, %s", err)
-				}
-			// not a standart wpa command
-			case "EVENTS":
-				ts.write("OK", raddr)
-				evs := []string{
-					WpaEventAvoidFreq,
-					WpaEventBeaconLoss,
-					WpaEventBssAdded,
-					WpaEventBssAdded,
-					WpaEventChannelSwitch,
-					WpaEventConnected,
-					WpaEventDisconnected,
-					WpaEventEapFailure,
-					WpaEventEapNotification,
-					WpaEventNetworkNotFound,
-					WpaEventPasswordChanged,
-				}
-				ts.sendMsg(2, evs...)
-			case CmdScanResults:
-				if ts.scanned {
-					ts.write(fmt.Sprintf("%s\n%s", scanheader, scanRes), raddr)
-					continue
-				}
-				ts.write(scanheader, raddr)
-			case CmdScan:
-				msg := []string{
-					WpaEventScanStarted,
-					WpaEventScanResults,
-					WpsEventApAvailable,
-				}
-				ts.write("OK", raddr)
-				ts.sendMsg(3, msg...)
-				ts.scanned = true
-			case CmdAddNetwork:
-				id := len(ts.networks)
-				ts.networks = append(ts.networks, Network{
-					ID:    id,
-					BSSID: "any",
-					Flags: []string{"DISABLED"},
-				})
-				ts.write(fmt.Sprint(id), raddr)
-			case CmdRemoveNetwork:
-				if len(args) == 0 {
-					ts.write(rmNetworkFail, raddr)
-					continue
-				}
-				id, err := strconv.Atoi(args[0])
-				if err != nil || (id < 0 || id >= len(ts.networks)) {
-					ts.write("FAIL", raddr)
-					continue
-				}
-				ts.networks = ts.networks[:id]
-				ts.write("OK", raddr)
-			case CmdSetNetwork:
-				if len(args) == 0 {
-					ts.write(setNetworkUsage, raddr)
-					continue
-				}
-				if len(args) != 3 {
-					ts.write(setNetworkFail, raddr)
-					continue
-				}
+	ts.mut.Lock()
+	defer ts.mut.Unlock()
 
-				id, err := strconv.Atoi(args[0])
-				if err != nil || args[1] != "ssid" {
-					ts.write(setNetworkFail, raddr)
-					continue
-				}
-				if id < 0 || id >= len(ts.networks) {
-					ts.write("FAIL", raddr)
-					continue
-				}
-				ts.networks[id].SSID = strings.Trim(args[2], "\"")
-				ts.write("OK", raddr)
-			case CmdListNetworks:
-				ts.write(netheader+unmarshalNetwork(ts.networks), raddr)
-			default:
-				ts.write("UNKNOWN COMMAND", raddr)
-			}
+	ts.last = req
+	if req == cmdGetCookie {
+		ts.write("COOKIE="+ts.cookie, raddr)
+		return
+	}
+
+	sc := strings.Split(req, " ")
+	// udp control interface
+	if ts.cookie != "" {
+		if sc[0] != "COOKIE="+ts.cookie || len(sc) < 2 {
+			ts.write("FAIL", raddr)
+			return
 		}
-	}()
+		sc = sc[1:]
+	}
+
+	// routed through global control interface
+	if strings.HasPrefix(sc[0], CmdIfname+"=") && len(sc) > 1 {
+		sc = sc[1:]
+	}
+	scmd := sc[0]
+	args := []string{}
+	if len(sc) > 1 && sc[1] != "" {
+		args = sc[1:]
+	}
+
+	if res, ok := ts.cmdMap[scmd]; ok {
+		ts.write(res, raddr)
+		return
+	}
+
+	switch scmd {
+	case cmdAttach:
+		if _, ok := ts.subAddr[raddr.String()]; !ok {
+			ts.subAddr[raddr.String()] = raddr
+			ts.write("OK", raddr)
+		}
+	case cmdDetach:
+		if _, ok := ts.subAddr[raddr.String()]; ok {
+			delete(ts.subAddr, raddr.String())
+			ts.write("OK", raddr)
+		}
+	// not a standart wpa command
+	case "CLOSE":
+		if err := ts.conn.Close(); err != nil {
+			ts.t.Errorf("Close error, %s", err)
+		}
+	// not a standart wpa command
+	case "EVENTS":
+		ts.write("OK", raddr)
+		evs := []string{
+			WpaEventAvoidFreq,
+			WpaEventBeaconLoss,
+			WpaEventBssAdded,
+			WpaEventBssAdded,
+			WpaEventChannelSwitch,
+			WpaEventConnected,
+			WpaEventDisconnected,
+			WpaEventEapFailure,
+			WpaEventEapNotification,
+			WpaEventNetworkNotFound,
+			WpaEventPasswordChanged,
+		}
+		ts.sendMsg(2, evs...)
+	// not a standart wpa command
+	case "IFEVENTS":
+		ts.write("OK", raddr)
+		for _, addr := range ts.subAddr {
+			ts.write("IFNAME=wlan0 <3>"+WpaEventConnected, addr)
+			ts.write("IFNAME=wlan1 <3>"+WpaEventDisconnected, addr)
+		}
+	case CmdScanResults:
+		if ts.scanned {
+			ts.write(fmt.Sprintf("%s\n%s", scanheader, scanRes), raddr)
+			return
+		}
+		ts.write(scanheader, raddr)
+	case CmdScan:
+		msg := []string{
+			WpaEventScanStarted,
+			WpaEventScanResults,
+			WpsEventApAvailable,
+		}
+		ts.write("OK", raddr)
+		ts.sendMsg(3, msg...)
+		ts.scanned = true
+	case CmdAddNetwork:
+		id := len(ts.networks)
+		ts.networks = append(ts.networks, Network{
+			ID:    id,
+			BSSID: "any",
+			Flags: []string{"DISABLED"},
+		})
+		ts.write(fmt.Sprint(id), raddr)
+	case CmdRemoveNetwork:
+		if len(args) == 0 {
+			ts.write(rmNetworkFail, raddr)
+			return
+		}
+		id, err := strconv.Atoi(args[0])
+		if err != nil || (id < 0 || id >= len(ts.networks)) {
+			ts.write("FAIL", raddr)
+			return
+		}
+		ts.networks = ts.networks[:id]
+		ts.write("OK", raddr)
+	case CmdSetNetwork:
+		if len(args) == 0 {
+			ts.write(setNetworkUsage, raddr)
+			return
+		}
+		if len(args) != 3 {
+			ts.write(setNetworkFail, raddr)
+			return
+		}
+
+		id, err := strconv.Atoi(args[0])
+		if err != nil {
+			ts.write(setNetworkFail, raddr)
+			return
+		}
+		if id < 0 || id >= len(ts.networks) {
+			ts.write("FAIL", raddr)
+			return
+		}
+		if args[1] == "ssid" {
+			ts.networks[id].SSID = strings.Trim(args[2], "\"")
+		}
+		ts.write("OK", raddr)
+	case CmdSelectNetwork:
+		id, err := strconv.Atoi(strings.Join(args, ""))
+		if err != nil || id < 0 || id >= len(ts.networks) {
+			ts.write("FAIL", raddr)
+			return
+		}
+		ts.write("OK", raddr)
+	case CmdListNetworks:
+		ts.write(netheader+unmarshalNetwork(ts.networks), raddr)
+	case CmdP2pPeer:
+		ts.write(ts.p2pPeer(strings.Join(args, " ")), raddr)
+	default:
+		ts.write("UNKNOWN COMMAND", raddr)
+	}
+}
+
+// p2pPeer returns "P2P_PEER" output of arg, which is either "FIRST",
+// "NEXT-<addr>" or "<addr>"
+func (ts *testServer) p2pPeer(arg string) string {
+	next := strings.HasPrefix(arg, "NEXT-")
+	addr := strings.TrimPrefix(arg, "NEXT-")
+	for i, p := range ts.p2pPeers {
+		if arg == "FIRST" {
+			return p
+		}
+		if strings.SplitN(p, "\n", 2)[0] != addr {
+			continue
+		}
+		if !next {
+			return p
+		}
+		if i+1 < len(ts.p2pPeers) {
+			return ts.p2pPeers[i+1]
+		}
+	}
+
+	return "FAIL"
 }
 
 func (ts *testServer) write(s string, addr net.Addr) {
@@ -194,8 +267,15 @@ func (ts *testServer) write(s string, addr net.Addr) {
 
 func (ts *testServer) sendMsg(sev int, msg ...string) {
 	go func() {
+		ts.mut.Lock()
+		addrs := make([]net.Addr, 0, len(ts.subAddr))
+		for _, addr := range ts.subAddr {
+			addrs = append(addrs, addr)
+		}
+		ts.mut.Unlock()
+
 		for _, s := range msg {
-			for _, addr := range ts.subAddr {
+			for _, addr := range addrs {
 				if sev < 0 {
 					ts.write(s, addr)
 				}
@@ -205,10 +285,55 @@ func (ts *testServer) sendMsg(sev int, msg ...string) {
 	}()
 }
 
+// setCmd sets the response of cmd
+func (ts *testServer) setCmd(cmd, res string) {
+	ts.mut.Lock()
+	defer ts.mut.Unlock()
+	ts.cmdMap[cmd] = res
+}
+
+// delCmd deletes the response of cmd
+func (ts *testServer) delCmd(cmd string) {
+	ts.mut.Lock()
+	defer ts.mut.Unlock()
+	delete(ts.cmdMap, cmd)
+}
+
+// lastCmd returns the last received request
+func (ts *testServer) lastCmd() string {
+	ts.mut.Lock()
+	defer ts.mut.Unlock()
+	return ts.last
+}
+
+// setCookie sets the cookie of udp control interface
+func (ts *testServer) setCookie(cookie string) {
+	ts.mut.Lock()
+	defer ts.mut.Unlock()
+	ts.cookie = cookie
+}
+
 func (ts *testServer) addr() string {
 	return ts.conn.LocalAddr().String()
 }
 
+func newUDPTestServer(t *testing.T) (*testServer, func()) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("New UDP Test Server failed with error: %s", err)
+	}
+
+	m := map[string]string{
+		CmdPing: "PONG",
+	}
+
+	ts := &testServer{conn: conn, subAddr: make(map[string]net.Addr), networks: []Network{},
+		cmdMap: m, cookie: "0123456789abcdef", t: t}
+	ts.run()
+
+	return ts, func() { conn.Close() }
+}
+
 func newTestServer(t *testing.T) (*testServer, func()) {
 	conn, fn, err := testServerConn()
 	if err != nil {
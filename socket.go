@@ -1,17 +1,43 @@
 package wpaclient
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"net"
+	"os"
+	"strings"
+	"time"
 )
 
+// udpPrefix marks UDP control interface addresses, like "udp:192.168.1.1:9877"
+const udpPrefix = "udp:"
+
+// socket is the Transport over unix datagram and UDP control interfaces
 type socket struct {
 	c net.Conn
 	f string
+
+	// "COOKIE=<hex> " prefix required by UDP control interface
+	cookie string
 }
 
-func (s *socket) send(b []byte) error {
+// dial dials UDP control interface if addr has udpPrefix,
+// otherwise the local one of the platform
+func dial(addr string) (*socket, error) {
+	if strings.HasPrefix(addr, udpPrefix) {
+		return dialUDP(strings.TrimPrefix(addr, udpPrefix))
+	}
+
+	return dialLocal(addr)
+}
+
+// Send writes b to socket, prefixed with cookie if required
+func (s *socket) Send(b []byte) error {
+	if s.cookie != "" {
+		b = append([]byte(s.cookie), b...)
+	}
+
 	n, err := s.c.Write(b)
 	if err != nil {
 		return fmt.Errorf("write to socket failed: %w", err)
@@ -24,8 +50,13 @@ func (s *socket) send(b []byte) error {
 	return nil
 }
 
-func (s *socket) receive() ([]byte, error) {
-	b := make([]byte, 4095)
+// maxDatagram is the receive buffer size, events carrying
+// a certificate like "CTRL-EVENT-EAP-PEER-CERT" exceed 4K
+const maxDatagram = 16384
+
+// Receive reads a datagram from socket
+func (s *socket) Receive() ([]byte, error) {
+	b := make([]byte, maxDatagram)
 
 	n, err := s.c.Read(b[:])
 	if err != nil {
@@ -35,16 +66,58 @@ func (s *socket) receive() ([]byte, error) {
 	return b[:n], nil
 }
 
-func (s *socket) execute(cmd []byte) ([]byte, error) {
-	err := s.send(cmd)
-	if err != nil {
+// executeUDP executes cmd with a response timeout, since requests with
+// a stale cookie are dropped or failed, cookie is refreshed and cmd retried once
+func (s *socket) executeUDP(cmd []byte) ([]byte, error) {
+	defer s.c.SetReadDeadline(time.Time{})
+
+	buf, rerr := s.roundTrip(cmd)
+	if rerr == nil && string(buf) != "FAIL\n" {
+		return buf, nil
+	}
+
+	var ne net.Error
+	if rerr != nil && !(errors.As(rerr, &ne) && ne.Timeout()) {
+		return nil, rerr
+	}
+
+	old := s.cookie
+	if err := s.fetchCookie(); err != nil {
+		return nil, err
+	}
+
+	// cookie is still valid, command really failed
+	if rerr == nil && s.cookie == old {
+		return buf, nil
+	}
+
+	return s.roundTrip(cmd)
+}
+
+func (s *socket) roundTrip(cmd []byte) ([]byte, error) {
+	if err := s.Send(cmd); err != nil {
 		return nil, fmt.Errorf("send failed: %w", err)
 	}
 
-	buf, err := s.receive()
+	s.c.SetReadDeadline(time.Now().Add(UDPTimeout))
+	buf, err := s.Receive()
 	if err != nil {
 		return nil, fmt.Errorf("read failed: %w", err)
 	}
 
 	return buf, nil
 }
+
+// SetDeadline sets read and write deadline of socket
+func (s *socket) SetDeadline(t time.Time) error {
+	return s.c.SetDeadline(t)
+}
+
+// Close closes socket and removes its local socket file
+func (s *socket) Close() error {
+	if s.f != "" {
+		defer os.Remove(s.f)
+	}
+
+	return s.c.Close()
+}
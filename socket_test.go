@@ -24,7 +24,7 @@ func TestConnDial(t *testing.T) {
 			}},
 		{name: "check file", skip: socketType == "UDP",
 			clean: func(s *socket) {
-				s.close()
+				s.Close()
 				if _, err := os.Stat(localSocket(0)); !os.IsNotExist(err) {
 					t.Errorf("Expect Close() to remove socket file, it exists")
 				}
@@ -81,7 +81,7 @@ func TestSocketExecute(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Dial failed, %v", err)
 	}
-	defer soc.close()
+	defer soc.Close()
 
 	tests := []struct {
 		name string
@@ -89,18 +89,18 @@ func TestSocketExecute(t *testing.T) {
 		err  string
 	}{
 		{name: "success", fn: func() error {
-			_, err := soc.execute([]byte(CmdPing))
+			_, err := execute(soc, []byte(CmdPing))
 			return err
 		}},
 		{name: "receive fail", err: "use of closed network connection",
 			fn: func() error {
-				soc.close()
-				_, err := soc.receive()
+				soc.Close()
+				_, err := soc.Receive()
 				return err
 			}},
 		{name: "send fail", err: "use of closed network connection",
 			fn: func() error {
-				_, err := soc.execute([]byte(CmdPing))
+				_, err := execute(soc, []byte(CmdPing))
 				return err
 			}},
 	}
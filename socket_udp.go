@@ -0,0 +1,76 @@
+package wpaclient
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const cmdGetCookie = "GET_COOKIE"
+
+// UDPTimeout is the time UDP control interface has to respond,
+// requests with an invalid cookie are silently dropped
+var UDPTimeout = time.Second * 3
+
+// UDPAddr returns the address of the UDP control interface of
+// the interface with index on host, to be used with New.
+// wpa_supplicant assigns WpaCtrlIfacePort + index to its interfaces.
+func UDPAddr(host string, index int) (string, error) {
+	if index < 0 || index >= WpaCtrlIfacePortLimit {
+		return "", fmt.Errorf("interface index %d out of range [0, %d)", index, WpaCtrlIfacePortLimit)
+	}
+
+	return udpPrefix + net.JoinHostPort(host, strconv.Itoa(WpaCtrlIfacePort+index)), nil
+}
+
+// dialUDP dials UDP control interface at addr, "host:port" or "host",
+// WpaCtrlIfacePort is used if port is missing. IPv6 hosts must be in brackets.
+func dialUDP(addr string) (*socket, error) {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(strings.Trim(addr, "[]"), strconv.Itoa(WpaCtrlIfacePort))
+	}
+
+	remote, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve udp address: %w", err)
+	}
+
+	c, err := net.DialUDP("udp", nil, remote)
+	if err != nil {
+		return nil, fmt.Errorf("dial failed: %w", err)
+	}
+
+	s := &socket{c: c}
+	if err := s.fetchCookie(); err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// fetchCookie gets the cookie every request has to be prefixed with
+func (s *socket) fetchCookie() error {
+	defer s.c.SetReadDeadline(time.Time{})
+
+	if _, err := s.c.Write([]byte(cmdGetCookie)); err != nil {
+		return fmt.Errorf("get cookie failed: %w", err)
+	}
+
+	s.c.SetReadDeadline(time.Now().Add(UDPTimeout))
+	b, err := s.Receive()
+	if err != nil {
+		return fmt.Errorf("get cookie failed: %w", err)
+	}
+
+	b = bytes.TrimSpace(b)
+	if !bytes.HasPrefix(b, []byte("COOKIE=")) || len(b) == len("COOKIE=") {
+		return fmt.Errorf("get cookie failed: unexpected response %q", b)
+	}
+	s.cookie = string(b) + " "
+
+	return nil
+}
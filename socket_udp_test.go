@@ -0,0 +1,107 @@
+package wpaclient
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestUDPAddr(t *testing.T) {
+	tests := []struct {
+		host  string
+		index int
+		exp   string
+		err   bool
+	}{
+		{host: "127.0.0.1", exp: "udp:127.0.0.1:9877"},
+		{host: "::1", index: 2, exp: "udp:[::1]:9879"},
+		{host: "127.0.0.1", index: -1, err: true},
+		{host: "127.0.0.1", index: WpaCtrlIfacePortLimit, err: true},
+	}
+
+	for _, tt := range tests {
+		a, err := UDPAddr(tt.host, tt.index)
+		if (err != nil) != tt.err {
+			t.Errorf("UDPAddr(%s, %d) expected error %v, got %v", tt.host, tt.index, tt.err, err)
+		}
+
+		if a != tt.exp {
+			t.Errorf("UDPAddr(%s, %d) expected %s, got %s", tt.host, tt.index, tt.exp, a)
+		}
+	}
+}
+
+func TestUDPClient(t *testing.T) {
+	ts, close := newUDPTestServer(t)
+	defer close()
+
+	c, err := New(udpPrefix + ts.addr())
+	if err != nil {
+		t.Fatalf("New Client failed, %v", err)
+	}
+	defer c.Close()
+
+	if _, err := c.Execute(CmdPing); err != nil {
+		t.Errorf("Execute not expect an error, got %v", err)
+	}
+
+	if exp := "COOKIE=" + ts.cookie + " " + CmdPing; ts.lastCmd() != exp {
+		t.Errorf("expected to send %q, got %q", exp, ts.lastCmd())
+	}
+
+	// cookie changed, client refreshes it and retries
+	ts.setCookie("fedcba9876543210")
+	if _, err := c.Execute(CmdPing); err != nil {
+		t.Errorf("Execute not expect an error, got %v", err)
+	}
+
+	if exp := "COOKIE=" + ts.cookie + " " + CmdPing; ts.lastCmd() != exp {
+		t.Errorf("expected to send %q, got %q", exp, ts.lastCmd())
+	}
+
+	// command failed with a valid cookie
+	ts.setCmd(CmdReconnect, "FAIL")
+	if _, err := c.Execute(CmdReconnect); !errors.Is(err, ErrCmdFailed) {
+		t.Errorf("Execute expect error %v, got %v", ErrCmdFailed, err)
+	}
+
+	ch, err := c.Notify(WpaEventConnected)
+	if err != nil {
+		t.Fatalf("Notify not expect an error, got %v", err)
+	}
+
+	if _, err := c.Execute("EVENTS"); err != nil {
+		t.Errorf("Execute not expect an error, got %v", err)
+	}
+
+	select {
+	case ev := <-ch:
+		if ev.Message != WpaEventConnected {
+			t.Errorf("expected event %s, got %s", WpaEventConnected, ev.Message)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("event not received")
+	}
+}
+
+func TestUDPDial(t *testing.T) {
+	UDPTimeout = time.Millisecond * 50
+	defer func() { UDPTimeout = time.Second * 3 }()
+
+	// nobody answers GET_COOKIE
+	if _, err := New(udpPrefix + "127.0.0.1:1"); err == nil {
+		t.Error("New expect an error, got <nil>")
+	}
+
+	if _, err := New(udpPrefix + "[::1"); err == nil {
+		t.Error("New expect an error, got <nil>")
+	}
+
+	ts, close := newUDPTestServer(t)
+	defer close()
+
+	ts.setCookie("")
+	if _, err := New(udpPrefix + ts.addr()); err == nil {
+		t.Error("New expect an error for missing cookie, got <nil>")
+	}
+}
@@ -1,3 +1,6 @@
+//go:build !windows
+// +build !windows
+
 package wpaclient
 
 import (
@@ -9,11 +12,20 @@ import (
 
 var socketType = "UNIX"
 
+var globalAddr = "/var/run/wpa_supplicant-global"
+
 func localSocket(i int) string {
 	return fmt.Sprintf("/tmp/wpa_ctrl_%d-%d", os.Getpid(), i)
 }
 
-func dial(addr string) (*socket, error) {
+// dialLocal dials unix datagram control interface
+func dialLocal(addr string) (*socket, error) {
+	return dialUnix(addr)
+}
+
+// dialUnix dials unix datagram socket at addr, addr is either
+// a path or an interface name in default control directories
+func dialUnix(addr string) (*socket, error) {
 	var (
 		ad  string
 		err error
@@ -66,11 +78,6 @@ func dial(addr string) (*socket, error) {
 	return &socket{c: c, f: lf}, nil
 }
 
-func (s *socket) close() error {
-	defer os.Remove(s.f)
-	return s.c.Close()
-}
-
 func testServerConn() (*net.UnixConn, func(), error) {
 	lf := fmt.Sprintf("/tmp/wpa_test_listen_%d", os.Getpid())
 	addr, err := net.ResolveUnixAddr("unixgram", lf)
@@ -1,39 +1,30 @@
 package wpaclient
 
 import (
-	"fmt"
+	"errors"
 	"net"
 )
 
 var socketType = "UDP"
 
+var globalAddr = "127.0.0.1:9878"
+
 func localSocket(i int) string {
 	return ""
 }
 
-func dial(addr string) (*socket, error) {
+// dialLocal dials UDP control interface, global one if addr is empty
+func dialLocal(addr string) (*socket, error) {
 	if addr == "" {
-		addr = "127.0.0.1:9878"
-	}
-
-	remote, err := net.ResolveUDPAddr("udp", addr)
-	if err != nil {
-		return nil, fmt.Errorf("could not resolve udp address: %w", err)
-	}
-
-	c, err := net.DialUDP("udp",
-		&net.UDPAddr{},
-		remote,
-	)
-	if err != nil {
-		return nil, fmt.Errorf("dial failed: %w", err)
+		addr = globalAddr
 	}
 
-	return &socket{c: c}, nil
+	return dialUDP(addr)
 }
 
-func (s *Socket) close() error {
-	return s.c.close()
+// dialUnix fails, unix datagram sockets are not supported on windows
+func dialUnix(addr string) (*socket, error) {
+	return nil, errors.New("unix datagram sockets are not supported")
 }
 
 func testServerConn() (*net.UDPConn, func(), error) {
@@ -0,0 +1,221 @@
+package wpaclient
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Station represents a connected station returned from "STA",
+// "STA-FIRST" and "STA-NEXT" commands in AP mode
+type Station struct {
+	Addr           net.HardwareAddr
+	Flags          []string
+	AID            int
+	Capability     uint16
+	ListenInterval int
+	// SupportedRates in units of 500 kbit/s, basic rates have the high bit set
+	SupportedRates []byte
+	RxBytes        uint64
+	TxBytes        uint64
+	RxPackets      uint64
+	TxPackets      uint64
+	InactiveMs     int
+	Signal         int
+	// RxRate and TxRate in kbit/s
+	RxRate        int
+	TxRate        int
+	ConnectedTime int
+	HTCapsInfo    uint16
+	VHTCapsInfo   uint32
+	// HECapab is the HE Capabilities element of an HE station, without element header
+	HECapab []byte
+	// Params holds every returned field
+	Params map[string]string
+}
+
+// HasFlag reports whether station has flag f, like "AUTHORIZED", "HT", "VHT" or "HE"
+func (s *Station) HasFlag(f string) bool {
+	for _, fl := range s.Flags {
+		if fl == f {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Station executes "STA" command and returns station with addr
+func (c *Client) Station(addr string) (*Station, error) {
+	res, err := c.Execute(CmdSta, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseStation(res)
+}
+
+// StationFirst executes "STA-FIRST" command, returns nil if there are no stations
+func (c *Client) StationFirst() (*Station, error) {
+	res, err := c.Execute(CmdStaFirst)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseStation(res)
+}
+
+// StationNext executes "STA-NEXT" command, returns the station after addr
+// or nil if addr is the last one
+func (c *Client) StationNext(addr string) (*Station, error) {
+	res, err := c.Execute(CmdStaNext, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseStation(res)
+}
+
+// Stations returns every connected station iterating with "STA-FIRST" and "STA-NEXT"
+func (c *Client) Stations() ([]Station, error) {
+	stas := []Station{}
+
+	s, err := c.StationFirst()
+	for ; s != nil && err == nil; s, err = c.StationNext(s.Addr.String()) {
+		stas = append(stas, *s)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return stas, nil
+}
+
+func parseStation(b []byte) (*Station, error) {
+	b = bytes.TrimSpace(b)
+	if len(b) == 0 {
+		return nil, nil
+	}
+
+	i := bytes.IndexByte(b, '\n')
+	if i < 0 {
+		i = len(b)
+	}
+
+	addr, err := net.ParseMAC(string(b[:i]))
+	if err != nil {
+		return nil, fmt.Errorf("parse mac: %w", err)
+	}
+
+	kv := ParseKV(b[i:])
+	s := &Station{Addr: addr, Params: kv}
+
+	if v := kv["flags"]; v != "" {
+		s.Flags = parseFlags(v)
+	}
+
+	for k, p := range map[string]*int{"aid": &s.AID, "listen_interval": &s.ListenInterval,
+		"inactive_msec": &s.InactiveMs, "signal": &s.Signal, "connected_time": &s.ConnectedTime} {
+		if err := KVInt(kv, k, p); err != nil {
+			return nil, err
+		}
+	}
+
+	for k, p := range map[string]*uint64{"rx_bytes": &s.RxBytes, "tx_bytes": &s.TxBytes,
+		"rx_packets": &s.RxPackets, "tx_packets": &s.TxPackets} {
+		v, ok := kv[k]
+		if !ok {
+			continue
+		}
+
+		if *p, err = strconv.ParseUint(v, 10, 64); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", k, err)
+		}
+	}
+
+	for k, p := range map[string]*int{"rx_rate_info": &s.RxRate, "tx_rate_info": &s.TxRate} {
+		// rate in units of 100 kbit/s followed by mcs, nss and width info
+		f := strings.Fields(kv[k])
+		if len(f) == 0 {
+			continue
+		}
+
+		r, err := strconv.Atoi(f[0])
+		if err != nil {
+			return nil, fmt.Errorf("parse %s: %w", k, err)
+		}
+		*p = r * 100
+	}
+
+	if v, ok := kv["capability"]; ok {
+		c, err := strconv.ParseUint(v, 0, 16)
+		if err != nil {
+			return nil, fmt.Errorf("parse capability: %w", err)
+		}
+		s.Capability = uint16(c)
+	}
+
+	if v, ok := kv["ht_caps_info"]; ok {
+		c, err := strconv.ParseUint(v, 0, 16)
+		if err != nil {
+			return nil, fmt.Errorf("parse ht_caps_info: %w", err)
+		}
+		s.HTCapsInfo = uint16(c)
+	}
+
+	if v, ok := kv["vht_caps_info"]; ok {
+		c, err := strconv.ParseUint(v, 0, 32)
+		if err != nil {
+			return nil, fmt.Errorf("parse vht_caps_info: %w", err)
+		}
+		s.VHTCapsInfo = uint32(c)
+	}
+
+	if v, ok := kv["he_capab"]; ok {
+		if s.HECapab, err = hex.DecodeString(v); err != nil {
+			return nil, fmt.Errorf("parse he_capab: %w", err)
+		}
+	}
+
+	for _, r := range strings.Fields(kv["supported_rates"]) {
+		v, err := strconv.ParseUint(r, 16, 8)
+		if err != nil {
+			return nil, fmt.Errorf("parse supported_rates: %w", err)
+		}
+		s.SupportedRates = append(s.SupportedRates, byte(v))
+	}
+
+	return s, nil
+}
+
+// ParseKV parses key=value lines of a response, like the ones of "STATUS"
+func ParseKV(b []byte) map[string]string {
+	kv := map[string]string{}
+	for _, l := range bytes.Split(b, []byte("\n")) {
+		if i := bytes.IndexByte(l, '='); i > 0 {
+			kv[string(l[:i])] = string(bytes.TrimRight(l[i+1:], "\r"))
+		}
+	}
+
+	return kv
+}
+
+// KVInt parses kv[k], a value returned from ParseKV, to p if it exists
+func KVInt(kv map[string]string, k string, p *int) error {
+	v, ok := kv[k]
+	if !ok {
+		return nil
+	}
+
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return fmt.Errorf("parse %s: %w", k, err)
+	}
+	*p = i
+
+	return nil
+}
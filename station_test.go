@@ -0,0 +1,139 @@
+package wpaclient
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+var staRes = `02:00:00:00:01:00
+flags=[AUTH][ASSOC][AUTHORIZED][WMM][HT][VHT]
+aid=1
+capability=0x431
+listen_interval=10
+supported_rates=82 84 8b 96 0c 12 18 24
+timeout_next=NULLFUNC POLL
+rx_packets=12
+tx_packets=7
+rx_bytes=1650
+tx_bytes=980
+inactive_msec=340
+signal=-42
+rx_rate_info=650 mcs 7 shortGI
+tx_rate_info=866 vhtmcs 9 vhtnss 2
+connected_time=35
+ht_caps_info=0x006f
+vht_caps_info=0x0f8259b2
+`
+
+func TestParseStation(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		exp   *Station
+		err   bool
+	}{
+		{name: "empty"},
+		{name: "newline", input: "\n"},
+		{
+			name:  "station",
+			input: staRes,
+			exp: &Station{
+				Addr:           net.HardwareAddr{2, 0, 0, 0, 1, 0},
+				Flags:          []string{"AUTH", "ASSOC", "AUTHORIZED", "WMM", "HT", "VHT"},
+				AID:            1,
+				Capability:     0x431,
+				ListenInterval: 10,
+				SupportedRates: []byte{0x82, 0x84, 0x8b, 0x96, 0x0c, 0x12, 0x18, 0x24},
+				RxBytes:        1650,
+				TxBytes:        980,
+				RxPackets:      12,
+				TxPackets:      7,
+				InactiveMs:     340,
+				Signal:         -42,
+				RxRate:         65000,
+				TxRate:         86600,
+				ConnectedTime:  35,
+				HTCapsInfo:     0x6f,
+				VHTCapsInfo:    0x0f8259b2,
+			},
+		},
+		{
+			name: "he station",
+			input: "02:00:00:00:01:01\nflags=[AUTH][ASSOC][AUTHORIZED][WMM][HT][VHT][HE]\naid=2\n" +
+				"he_capab=0308120000000000000000000000fafffaff",
+			exp: &Station{
+				Addr:    net.HardwareAddr{2, 0, 0, 0, 1, 1},
+				Flags:   []string{"AUTH", "ASSOC", "AUTHORIZED", "WMM", "HT", "VHT", "HE"},
+				AID:     2,
+				HECapab: []byte{0x03, 0x08, 0x12, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0xfa, 0xff, 0xfa, 0xff},
+			},
+		},
+		{name: "mac error", input: "02:00:00\naid=1", err: true},
+		{name: "aid error", input: "02:00:00:00:01:00\naid=a", err: true},
+		{name: "bytes error", input: "02:00:00:00:01:00\nrx_bytes=-1", err: true},
+		{name: "rate error", input: "02:00:00:00:01:00\nrx_rate_info=x mcs 7", err: true},
+		{name: "capability error", input: "02:00:00:00:01:00\ncapability=0xfffff", err: true},
+		{name: "he_capab error", input: "02:00:00:00:01:00\nhe_capab=0x12", err: true},
+		{name: "rates error", input: "02:00:00:00:01:00\nsupported_rates=82 zz", err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := parseStation([]byte(tt.input))
+			if (err != nil) != tt.err {
+				t.Fatalf("Expected error %v, got %v", tt.err, err)
+			}
+
+			if s != nil {
+				s.Params = nil
+			}
+
+			if !reflect.DeepEqual(s, tt.exp) {
+				t.Errorf("Expected %#v\ngot %#v", tt.exp, s)
+			}
+
+			if s != nil && (!s.HasFlag("VHT") || s.HasFlag("HE") != (s.HECapab != nil)) {
+				t.Errorf("HasFlag returned unexpected result for %v", s.Flags)
+			}
+		})
+	}
+}
+
+func TestStations(t *testing.T) {
+	ts, close := newTestServer(t)
+	defer close()
+
+	c, err := New(ts.addr())
+	if err != nil {
+		t.Fatalf("New Client failed, %v", err)
+	}
+	defer c.Close()
+
+	ts.setCmd(CmdStaFirst, "")
+	stas, err := c.Stations()
+	if err != nil || len(stas) != 0 {
+		t.Errorf("Stations expected no stations, got %v, %v", stas, err)
+	}
+
+	ts.setCmd(CmdStaFirst, staRes)
+	ts.setCmd(CmdStaNext, "")
+	stas, err = c.Stations()
+	if err != nil || len(stas) != 1 || stas[0].Signal != -42 {
+		t.Errorf("Stations expected a station, got %v, %v", stas, err)
+	}
+
+	if ts.lastCmd() != CmdStaNext+" 02:00:00:00:01:00" {
+		t.Errorf("Stations expected to send STA-NEXT, got %s", ts.lastCmd())
+	}
+
+	ts.setCmd(CmdStaNext, "FAIL")
+	if _, err := c.Stations(); err != ErrCmdFailed {
+		t.Errorf("Stations expect error %v, got %v", ErrCmdFailed, err)
+	}
+
+	ts.setCmd(CmdSta, staRes)
+	if s, err := c.Station("02:00:00:00:01:00"); err != nil || s.AID != 1 {
+		t.Errorf("Station returned %v, %v", s, err)
+	}
+}
@@ -0,0 +1,109 @@
+package wpaclient
+
+import (
+	"fmt"
+	"net"
+)
+
+// Status represents data returned from "STATUS" command
+type Status struct {
+	WpaState string
+	BSSID    net.HardwareAddr
+	SSID     string
+	Freq     int
+	// ID of the current network, -1 if there is none
+	ID             int
+	Mode           string
+	KeyMgmt        string
+	PairwiseCipher string
+	GroupCipher    string
+	IPAddress      string
+	Address        net.HardwareAddr
+	// Params holds every returned field
+	Params map[string]string
+}
+
+// Status executes "STATUS" command and returns parsed status
+func (c *Client) Status() (*Status, error) {
+	res, err := c.Execute(CmdStatus)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseStatus(res)
+}
+
+func parseStatus(b []byte) (*Status, error) {
+	kv := ParseKV(b)
+	s := &Status{
+		WpaState:       kv["wpa_state"],
+		SSID:           kv["ssid"],
+		ID:             -1,
+		Mode:           kv["mode"],
+		KeyMgmt:        kv["key_mgmt"],
+		PairwiseCipher: kv["pairwise_cipher"],
+		GroupCipher:    kv["group_cipher"],
+		IPAddress:      kv["ip_address"],
+		Params:         kv,
+	}
+
+	for k, p := range map[string]*net.HardwareAddr{"bssid": &s.BSSID, "address": &s.Address} {
+		v := kv[k]
+		if v == "" {
+			continue
+		}
+
+		mac, err := net.ParseMAC(v)
+		if err != nil {
+			return nil, fmt.Errorf("parse %s: %w", k, err)
+		}
+		*p = mac
+	}
+
+	for k, p := range map[string]*int{"freq": &s.Freq, "id": &s.ID} {
+		if err := KVInt(kv, k, p); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// Signal represents data returned from "SIGNAL_POLL" command
+type Signal struct {
+	// RSSI in dBm
+	RSSI int
+	// LinkSpeed in Mbit/s
+	LinkSpeed int
+	Noise     int
+	Frequency int
+	Width     string
+	// CenterFreq1 and CenterFreq2 are reported for wide channels
+	CenterFreq1 int
+	CenterFreq2 int
+	AvgRSSI     int
+	// Params holds every returned field
+	Params map[string]string
+}
+
+// SignalPoll executes "SIGNAL_POLL" command and returns signal parameters
+// of the current connection
+func (c *Client) SignalPoll() (*Signal, error) {
+	res, err := c.Execute(CmdSignalPoll)
+	if err != nil {
+		return nil, err
+	}
+
+	kv := ParseKV(res)
+	s := &Signal{Width: kv["WIDTH"], Params: kv}
+
+	for k, p := range map[string]*int{"RSSI": &s.RSSI, "LINKSPEED": &s.LinkSpeed, "NOISE": &s.Noise,
+		"FREQUENCY": &s.Frequency, "CENTER_FRQ1": &s.CenterFreq1, "CENTER_FRQ2": &s.CenterFreq2,
+		"AVG_RSSI": &s.AvgRSSI} {
+		if err := KVInt(kv, k, p); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
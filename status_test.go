@@ -0,0 +1,106 @@
+package wpaclient
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+var statusRes = `bssid=02:00:00:00:01:00
+freq=2412
+ssid=AP0
+id=1
+mode=station
+pairwise_cipher=CCMP
+group_cipher=CCMP
+key_mgmt=WPA2-PSK
+wpa_state=COMPLETED
+ip_address=192.168.1.20
+address=02:00:00:00:00:01
+`
+
+func TestParseStatus(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		exp   *Status
+		err   bool
+	}{
+		{
+			name:  "completed",
+			input: statusRes,
+			exp: &Status{
+				WpaState:       "COMPLETED",
+				BSSID:          net.HardwareAddr{2, 0, 0, 0, 1, 0},
+				SSID:           "AP0",
+				Freq:           2412,
+				ID:             1,
+				Mode:           "station",
+				KeyMgmt:        "WPA2-PSK",
+				PairwiseCipher: "CCMP",
+				GroupCipher:    "CCMP",
+				IPAddress:      "192.168.1.20",
+				Address:        net.HardwareAddr{2, 0, 0, 0, 0, 1},
+			},
+		},
+		{
+			name:  "disconnected",
+			input: "wpa_state=DISCONNECTED\naddress=02:00:00:00:00:01",
+			exp: &Status{
+				WpaState: "DISCONNECTED",
+				ID:       -1,
+				Address:  net.HardwareAddr{2, 0, 0, 0, 0, 1},
+			},
+		},
+		{name: "bssid error", input: "bssid=02:00", err: true},
+		{name: "freq error", input: "freq=x", err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := parseStatus([]byte(tt.input))
+			if (err != nil) != tt.err {
+				t.Fatalf("Expected error %v, got %v", tt.err, err)
+			}
+
+			if s != nil {
+				s.Params = nil
+			}
+
+			if !reflect.DeepEqual(s, tt.exp) {
+				t.Errorf("Expected %#v\ngot %#v", tt.exp, s)
+			}
+		})
+	}
+}
+
+func TestSignalPoll(t *testing.T) {
+	ts, close := newTestServer(t)
+	defer close()
+
+	c, err := New(ts.addr())
+	if err != nil {
+		t.Fatalf("New Client failed, %v", err)
+	}
+	defer c.Close()
+
+	ts.setCmd(CmdSignalPoll, "RSSI=-52\nLINKSPEED=866\nNOISE=9999\nFREQUENCY=5180\n"+
+		"WIDTH=80 MHz\nCENTER_FRQ1=5210\nAVG_RSSI=-53")
+
+	s, err := c.SignalPoll()
+	if err != nil {
+		t.Fatalf("SignalPoll not expect an error, got %v", err)
+	}
+	s.Params = nil
+
+	exp := &Signal{RSSI: -52, LinkSpeed: 866, Noise: 9999, Frequency: 5180,
+		Width: "80 MHz", CenterFreq1: 5210, AvgRSSI: -53}
+	if !reflect.DeepEqual(s, exp) {
+		t.Errorf("Expected %#v\ngot %#v", exp, s)
+	}
+
+	ts.setCmd(CmdSignalPoll, "FAIL")
+	if _, err := c.SignalPoll(); err != ErrCmdFailed {
+		t.Errorf("SignalPoll expect error %v, got %v", ErrCmdFailed, err)
+	}
+}
@@ -0,0 +1,192 @@
+package wpaclient
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Transport carries control interface datagrams, every Send and Receive
+// transfers a single whole message, like a unix datagram socket does.
+// It lets the control protocol be tunneled over other channels,
+// like an SSH channel or a vsock to a virtual machine.
+type Transport interface {
+	// Send sends message b
+	Send(b []byte) error
+	// Receive blocks until a message is received
+	Receive() ([]byte, error)
+	// Close closes transport, blocked Receive calls return an error
+	Close() error
+	// SetDeadline sets the deadline of Send and Receive calls,
+	// zero value means no deadline
+	SetDeadline(t time.Time) error
+}
+
+// DialUnix returns a Transport over unix datagram socket, addr is either
+// a socket path or an interface name in default control directories
+func DialUnix(addr string) (Transport, error) {
+	s, err := dialUnix(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// DialUDP returns a Transport over UDP control interface, addr is "host:port" or "host"
+func DialUDP(addr string) (Transport, error) {
+	s, err := dialUDP(strings.TrimPrefix(addr, udpPrefix))
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Exchange sends cmd over t and returns the response, a stale cookie of
+// UDP control interface is refreshed and cmd retried like Client.Execute does
+func Exchange(t Transport, cmd []byte) ([]byte, error) {
+	return execute(t, cmd)
+}
+
+// execute sends cmd over t and receives the response
+func execute(t Transport, cmd []byte) ([]byte, error) {
+	if s, ok := t.(*socket); ok && s.cookie != "" {
+		return s.executeUDP(cmd)
+	}
+
+	if err := t.Send(cmd); err != nil {
+		return nil, fmt.Errorf("send failed: %w", err)
+	}
+
+	buf, err := t.Receive()
+	if err != nil {
+		return nil, fmt.Errorf("read failed: %w", err)
+	}
+
+	return buf, nil
+}
+
+// pipeSize is the number of messages a pipe buffers in each direction
+const pipeSize = 16
+
+// errTimeout is returned by pipe when deadline is exceeded
+var errTimeout = &timeoutError{}
+
+type timeoutError struct{}
+
+func (e *timeoutError) Error() string   { return "i/o timeout" }
+func (e *timeoutError) Timeout() bool   { return true }
+func (e *timeoutError) Temporary() bool { return true }
+
+// Pipe returns both ends of an in-memory Transport, messages sent on
+// one end are received on the other. Closing either end closes both,
+// Receive returns io.EOF on the remote end and io.ErrClosedPipe on the local one.
+func Pipe() (Transport, Transport) {
+	a, b := make(chan []byte, pipeSize), make(chan []byte, pipeSize)
+	done, once := make(chan struct{}), &sync.Once{}
+
+	return &pipe{in: a, out: b, done: done, once: once},
+		&pipe{in: b, out: a, done: done, once: once}
+}
+
+type pipe struct {
+	in   <-chan []byte
+	out  chan<- []byte
+	done chan struct{}
+	once *sync.Once
+
+	// true for the end Close called on
+	closed bool
+
+	mut      sync.Mutex
+	deadline time.Time
+}
+
+// timer returns a channel fired on deadline, nil if there is no deadline
+func (p *pipe) timer() (<-chan time.Time, func()) {
+	p.mut.Lock()
+	d := p.deadline
+	p.mut.Unlock()
+
+	if d.IsZero() {
+		return nil, func() {}
+	}
+
+	t := time.NewTimer(time.Until(d))
+	return t.C, func() { t.Stop() }
+}
+
+func (p *pipe) err() error {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	if p.closed {
+		return io.ErrClosedPipe
+	}
+
+	return io.EOF
+}
+
+// Send sends a copy of b to the other end
+func (p *pipe) Send(b []byte) error {
+	tc, stop := p.timer()
+	defer stop()
+
+	select {
+	case <-p.done:
+		return p.err()
+	default:
+	}
+
+	select {
+	case p.out <- append([]byte{}, b...):
+		return nil
+	case <-p.done:
+		return p.err()
+	case <-tc:
+		return errTimeout
+	}
+}
+
+// Receive receives a message sent by the other end
+func (p *pipe) Receive() ([]byte, error) {
+	tc, stop := p.timer()
+	defer stop()
+
+	select {
+	case b := <-p.in:
+		return b, nil
+	case <-p.done:
+		return nil, p.err()
+	case <-tc:
+		return nil, errTimeout
+	}
+}
+
+// Close closes both ends of pipe
+func (p *pipe) Close() error {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	if p.closed {
+		return errors.New("pipe already closed")
+	}
+	p.closed = true
+
+	p.once.Do(func() { close(p.done) })
+
+	return nil
+}
+
+// SetDeadline sets the deadline of Send and Receive calls
+func (p *pipe) SetDeadline(t time.Time) error {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	p.deadline = t
+	return nil
+}
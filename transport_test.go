@@ -0,0 +1,138 @@
+package wpaclient
+
+import (
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestPipe(t *testing.T) {
+	a, b := Pipe()
+
+	if err := a.Send([]byte(CmdPing)); err != nil {
+		t.Fatalf("Send not expect an error, got %v", err)
+	}
+
+	res, err := b.Receive()
+	if err != nil {
+		t.Fatalf("Receive not expect an error, got %v", err)
+	}
+
+	if string(res) != CmdPing {
+		t.Errorf("expected %q, got %q", CmdPing, res)
+	}
+
+	a.SetDeadline(time.Now().Add(time.Millisecond * 10))
+	_, err = a.Receive()
+
+	var ne net.Error
+	if !errors.As(err, &ne) || !ne.Timeout() {
+		t.Errorf("expected timeout error, got %v", err)
+	}
+	a.SetDeadline(time.Time{})
+
+	done := make(chan error)
+	go func() {
+		_, err := b.Receive()
+		done <- err
+	}()
+
+	if err := a.Close(); err != nil {
+		t.Errorf("Close not expect an error, got %v", err)
+	}
+
+	select {
+	case err := <-done:
+		if err != io.EOF {
+			t.Errorf("expected %v, got %v", io.EOF, err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Receive not unblocked by Close")
+	}
+
+	if err := a.Send([]byte(CmdPing)); err != io.ErrClosedPipe {
+		t.Errorf("expected %v, got %v", io.ErrClosedPipe, err)
+	}
+
+	if err := a.Close(); err == nil {
+		t.Error("Close expect an error on closed pipe, got <nil>")
+	}
+}
+
+// pipeServer answers commands received on t from res
+func pipeServer(t Transport, res map[string]string) {
+	for {
+		b, err := t.Receive()
+		if err != nil {
+			return
+		}
+
+		r, ok := res[string(b)]
+		if !ok {
+			r = "UNKNOWN COMMAND"
+		}
+		t.Send([]byte(r + "\n"))
+	}
+}
+
+func TestNewWithTransport(t *testing.T) {
+	cmd, cmdSrv := Pipe()
+	ev, evSrv := Pipe()
+
+	go pipeServer(cmdSrv, map[string]string{CmdPing: "PONG"})
+	go pipeServer(evSrv, map[string]string{cmdAttach: "OK", cmdDetach: "OK"})
+
+	c, err := NewWithTransport(cmd, ev)
+	if err != nil {
+		t.Fatalf("NewWithTransport not expect an error, got %v", err)
+	}
+
+	res, err := c.Execute(CmdPing)
+	if err != nil {
+		t.Fatalf("Execute not expect an error, got %v", err)
+	}
+
+	if string(res) != "PONG\n" {
+		t.Errorf("expected PONG, got %q", res)
+	}
+
+	ch, err := c.Notify(WpaEventScanResults)
+	if err != nil {
+		t.Fatalf("Notify not expect an error, got %v", err)
+	}
+
+	evSrv.Send([]byte("<2>" + WpaEventScanResults))
+
+	select {
+	case e := <-ch:
+		if e.Message != WpaEventScanResults {
+			t.Errorf("expected %s, got %#v", WpaEventScanResults, e)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("event not received")
+	}
+
+	if err := c.Close(); err != nil {
+		t.Errorf("Close not expect an error, got %v", err)
+	}
+
+	if _, err := c.Execute(CmdPing); err == nil {
+		t.Error("Execute expect an error after Close, got <nil>")
+	}
+}
+
+func TestNewWithTransportNoEvents(t *testing.T) {
+	cmd, _ := Pipe()
+
+	c, err := NewWithTransport(cmd, nil)
+	if err != nil {
+		t.Fatalf("NewWithTransport not expect an error, got %v", err)
+	}
+	defer c.Close()
+
+	if _, err := c.Notify(); err == nil {
+		t.Error("Notify expect an error without event transport, got <nil>")
+	}
+}
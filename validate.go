@@ -9,6 +9,11 @@ import (
 // since we are covering very limited return values
 
 func validate(cmd string, buf []byte) error {
+	// responses carry secrets, keep them out of errors
+	if strings.HasPrefix(cmd, WpaCtrlRsp) {
+		cmd, _ = redact(cmd, nil)
+	}
+
 	sb := strings.TrimSuffix(string(buf), "\n")
 
 	switch sb {
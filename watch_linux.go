@@ -0,0 +1,101 @@
+package wpaclient
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// watchDirs notifies on returned channel whenever an entry is
+// created or removed in one of dirs, using inotify. Parents of dirs
+// not existing yet are watched, dirs are watched once they are created.
+func watchDirs(ctx context.Context, dirs []string) (<-chan struct{}, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("inotify init failed: %w", err)
+	}
+
+	// non blocking file is handled by runtime poller, so Close unblocks Read
+	f := os.NewFile(uintptr(fd), "inotify")
+
+	mask := uint32(syscall.IN_CREATE | syscall.IN_DELETE | syscall.IN_MOVED_TO | syscall.IN_MOVED_FROM)
+
+	// missing dirs by watch descriptor of their parent
+	missing := map[int][]string{}
+	for _, d := range dirs {
+		_, err := syscall.InotifyAddWatch(fd, d, mask)
+		if err == nil {
+			continue
+		}
+		if err != syscall.ENOENT {
+			f.Close()
+			return nil, fmt.Errorf("watch %s failed: %w", d, err)
+		}
+
+		// parent can be one of dirs, its mask is extended
+		wd, err := syscall.InotifyAddWatch(fd, filepath.Dir(d),
+			syscall.IN_CREATE|syscall.IN_MOVED_TO|syscall.IN_MASK_ADD)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("watch %s failed: %w", filepath.Dir(d), err)
+		}
+		missing[wd] = append(missing[wd], d)
+	}
+
+	// added starts watching a created dir if it was missing
+	added := func(wd int, name string) {
+		ds := missing[wd]
+		for i, d := range ds {
+			if filepath.Base(d) != name {
+				continue
+			}
+
+			if _, err := syscall.InotifyAddWatch(fd, d, mask); err == nil {
+				missing[wd] = append(ds[:i], ds[i+1:]...)
+			}
+			return
+		}
+	}
+
+	go func() {
+		<-ctx.Done()
+		f.Close()
+	}()
+
+	ch := make(chan struct{}, 1)
+	go func() {
+		defer close(ch)
+
+		b := make([]byte, syscall.SizeofInotifyEvent*64+syscall.NAME_MAX+1)
+		for {
+			n, err := f.Read(b)
+			if err != nil {
+				return
+			}
+
+			for i := 0; i+syscall.SizeofInotifyEvent <= n; {
+				ev := (*syscall.InotifyEvent)(unsafe.Pointer(&b[i]))
+				name := b[i+syscall.SizeofInotifyEvent : i+syscall.SizeofInotifyEvent+int(ev.Len)]
+				if ev.Mask&syscall.IN_ISDIR != 0 {
+					added(int(ev.Wd), string(bytes.TrimRight(name, "\x00")))
+				}
+				i += syscall.SizeofInotifyEvent + int(ev.Len)
+			}
+
+			// give a created socket time to be bound
+			time.Sleep(time.Millisecond * 10)
+
+			select {
+			case ch <- struct{}{}:
+			default:
+			}
+		}
+	}()
+
+	return ch, nil
+}
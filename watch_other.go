@@ -0,0 +1,37 @@
+//go:build !linux
+// +build !linux
+
+package wpaclient
+
+import (
+	"context"
+	"time"
+)
+
+// WatchInterval is the polling interval of Watch on platforms without inotify
+var WatchInterval = time.Second
+
+// watchDirs notifies on returned channel every WatchInterval
+func watchDirs(ctx context.Context, dirs []string) (<-chan struct{}, error) {
+	ch := make(chan struct{}, 1)
+	go func() {
+		defer close(ch)
+
+		t := time.NewTicker(WatchInterval)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-t.C:
+				select {
+				case ch <- struct{}{}:
+				default:
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return ch, nil
+}
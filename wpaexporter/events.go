@@ -0,0 +1,65 @@
+package wpaexporter
+
+import (
+	"strings"
+
+	"github.com/brlbil/wpaclient"
+)
+
+// knownEvents are the event names counted by name, other messages are counted as "other"
+// so label values stay bounded. It holds the CTRL-EVENT-*, WPS-* and P2P-* events of const.go.
+var knownEvents = eventNames(
+	strings.TrimSuffix(wpaclient.WpaCtrlReq, "-"),
+	wpaclient.WpaEventConnected, wpaclient.WpaEventDisconnected, wpaclient.WpaEventAssocReject,
+	wpaclient.WpaEventAuthReject, wpaclient.WpaEventTerminating, wpaclient.WpaEventPasswordChanged,
+	wpaclient.WpaEventEapNotification, wpaclient.WpaEventEapStarted,
+	wpaclient.WpaEventEapProposedMethod, wpaclient.WpaEventEapMethod, wpaclient.WpaEventEapPeerCert,
+	wpaclient.WpaEventEapPeerAlt, wpaclient.WpaEventEapTLSCertError, wpaclient.WpaEventEapStatus,
+	wpaclient.WpaEventEapRetransmit, wpaclient.WpaEventEapRetransmit2, wpaclient.WpaEventEapSuccess,
+	wpaclient.WpaEventEapSuccess2, wpaclient.WpaEventEapFailure, wpaclient.WpaEventEapFailure2,
+	wpaclient.WpaEventEapTimeoutFailure, wpaclient.WpaEventEapTimeoutFailure2,
+	wpaclient.WpaEventTempDisabled, wpaclient.WpaEventReenabled, wpaclient.WpaEventScanStarted,
+	wpaclient.WpaEventScanResults, wpaclient.WpaEventScanFailed, wpaclient.WpaEventStateChange,
+	wpaclient.WpaEventBssAdded, wpaclient.WpaEventBssRemoved, wpaclient.WpaEventNetworkNotFound,
+	wpaclient.WpaEventSignalChange, wpaclient.WpaEventBeaconLoss, wpaclient.WpaEventRegdomChange,
+	wpaclient.WpaEventChannelSwitch, wpaclient.WpaEventSubnetStatusUpdate,
+	wpaclient.WpaEventFreqConflict, wpaclient.WpaEventAvoidFreq, wpaclient.WpsEventOverlap,
+	wpaclient.WpsEventApAvailablePbc, wpaclient.WpsEventApAvailableAuth,
+	wpaclient.WpsEventApAvailablePin, wpaclient.WpsEventApAvailable, wpaclient.WpsEventCredReceived,
+	wpaclient.WpsEventM2d, wpaclient.WpsEventFail, wpaclient.WpsEventSuccess,
+	wpaclient.WpsEventTimeout, wpaclient.WpsEventActive, wpaclient.WpsEventDisable,
+	wpaclient.WpsEventEnrolleeSeen, wpaclient.WpsEventOpenNetwork, wpaclient.WpsEventErApAdd,
+	wpaclient.WpsEventErApRemove, wpaclient.WpsEventErEnrolleeAdd, wpaclient.WpsEventErEnrolleeRemove,
+	wpaclient.WpsEventErApSettings, wpaclient.WpsEventErSetSelReg, wpaclient.P2pEventDeviceFound,
+	wpaclient.P2pEventDeviceLost, wpaclient.P2pEventGoNegRequest, wpaclient.P2pEventGoNegSuccess,
+	wpaclient.P2pEventGoNegFailure, wpaclient.P2pEventGroupFormationSuccess,
+	wpaclient.P2pEventGroupFormationFailure, wpaclient.P2pEventGroupStarted,
+	wpaclient.P2pEventGroupRemoved, wpaclient.P2pEventCrossConnectEnable,
+	wpaclient.P2pEventCrossConnectDisable, wpaclient.P2pEventProvDiscShowPin,
+	wpaclient.P2pEventProvDiscEnterPin, wpaclient.P2pEventProvDiscPbcReq,
+	wpaclient.P2pEventProvDiscPbcResp, wpaclient.P2pEventProvDiscFailure,
+	wpaclient.P2pEventServDiscReq, wpaclient.P2pEventServDiscResp, wpaclient.P2pEventServAspResp,
+	wpaclient.P2pEventInvitationReceived, wpaclient.P2pEventInvitationResult,
+	wpaclient.P2pEventInvitationAccepted, wpaclient.P2pEventFindStopped,
+	wpaclient.P2pEventPersistentPskFail, wpaclient.P2pEventPresenceResponse,
+	wpaclient.P2pEventNfcBothGo, wpaclient.P2pEventNfcPeerClient, wpaclient.P2pEventNfcWhileClient,
+	wpaclient.P2pEventFallbackToGoNeg, wpaclient.P2pEventFallbackToGoNegEnabled,
+	wpaclient.P2pEventRemoveAndReformGroup, wpaclient.WpsEventPinNeeded,
+	wpaclient.WpsEventNewApSettings, wpaclient.WpsEventRegSuccess, wpaclient.WpsEventApSetupLocked,
+	wpaclient.WpsEventApSetupUnlocked, wpaclient.WpsEventApPinEnabled,
+	wpaclient.WpsEventApPinDisabled, wpaclient.P2pEventListenOffloadStop,
+	wpaclient.P2pListenOffloadStopReason,
+)
+
+// otherEvent is the event label of messages not in knownEvents
+const otherEvent = "other"
+
+// eventNames returns the names of events, the first word of their prefixes
+func eventNames(evs ...string) map[string]bool {
+	names := map[string]bool{}
+	for _, ev := range evs {
+		names[strings.Fields(ev)[0]] = true
+	}
+
+	return names
+}
@@ -0,0 +1,362 @@
+// Package wpaexporter exports wpa_supplicant health of interfaces as Prometheus metrics.
+//
+// Every Interval STATUS, SIGNAL_POLL, PKTCNT_POLL, MIB and SCAN_RESULTS are
+// collected, events are counted as they arrive. Exporter is an http.Handler
+// serving the metrics in Prometheus text exposition format.
+package wpaexporter
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/brlbil/wpaclient"
+)
+
+// DefaultInterval is the collection interval used if zero is given to New
+const DefaultInterval = time.Second * 15
+
+// Exporter collects metrics of added interfaces
+type Exporter struct {
+	interval time.Duration
+
+	mut    sync.Mutex
+	ifaces map[string]*iface
+	done   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// New returns an Exporter collecting every interval
+func New(interval time.Duration) *Exporter {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	return &Exporter{interval: interval, ifaces: map[string]*iface{}, done: make(chan struct{})}
+}
+
+// Add starts collecting metrics of interface ifname through c,
+// c is not closed by Exporter
+func (e *Exporter) Add(ifname string, c *wpaclient.Client) error {
+	ch, err := c.Notify()
+	if err != nil {
+		return fmt.Errorf("notify failed: %w", err)
+	}
+
+	i := newIface(ifname, c)
+	i.collect()
+
+	e.mut.Lock()
+	if old, ok := e.ifaces[ifname]; ok {
+		old.c.Stop(old.ch)
+		close(old.stop)
+	}
+	i.ch = ch
+	e.ifaces[ifname] = i
+	e.mut.Unlock()
+
+	e.wg.Add(2)
+	go func() {
+		defer e.wg.Done()
+		for ev := range ch {
+			i.event(ev)
+		}
+	}()
+
+	go func() {
+		defer e.wg.Done()
+		t := time.NewTicker(e.interval)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-t.C:
+				i.collect()
+			case <-i.stop:
+				return
+			case <-e.done:
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Remove stops collecting metrics of interface ifname
+func (e *Exporter) Remove(ifname string) {
+	e.mut.Lock()
+	defer e.mut.Unlock()
+
+	if i, ok := e.ifaces[ifname]; ok {
+		i.c.Stop(i.ch)
+		close(i.stop)
+		delete(e.ifaces, ifname)
+	}
+}
+
+// Close stops collecting metrics of every interface
+func (e *Exporter) Close() {
+	e.mut.Lock()
+	for n, i := range e.ifaces {
+		i.c.Stop(i.ch)
+		delete(e.ifaces, n)
+	}
+	close(e.done)
+	e.mut.Unlock()
+
+	e.wg.Wait()
+}
+
+// Collect collects metrics of every interface immediately
+func (e *Exporter) Collect() {
+	for _, i := range e.list() {
+		i.collect()
+	}
+}
+
+// ServeHTTP writes metrics in Prometheus text format
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	e.WriteTo(w)
+}
+
+// WriteTo writes metrics in Prometheus text format to w
+func (e *Exporter) WriteTo(w io.Writer) (int64, error) {
+	m := newMetrics()
+	for _, i := range e.list() {
+		i.metrics(m)
+	}
+
+	return m.writeTo(w)
+}
+
+// list returns interfaces sorted by name
+func (e *Exporter) list() []*iface {
+	e.mut.Lock()
+	defer e.mut.Unlock()
+
+	is := []*iface{}
+	for _, i := range e.ifaces {
+		is = append(is, i)
+	}
+	sort.Slice(is, func(a, b int) bool { return is[a].name < is[b].name })
+
+	return is
+}
+
+// iface holds collected values and event counters of an interface
+type iface struct {
+	name string
+	c    *wpaclient.Client
+	ch   <-chan wpaclient.Event
+	stop chan struct{}
+
+	mut         sync.Mutex
+	up          bool
+	status      *wpaclient.Status
+	signal      *wpaclient.Signal
+	pkt         map[string]string
+	mib         map[string]string
+	scanResults int
+	scanned     bool
+
+	events       map[string]float64
+	disconnects  map[[2]string]float64
+	assocRejects map[string]float64
+	eapFailures  float64
+	scanFailures float64
+}
+
+func newIface(name string, c *wpaclient.Client) *iface {
+	return &iface{name: name, c: c, stop: make(chan struct{}),
+		events: map[string]float64{}, disconnects: map[[2]string]float64{}, assocRejects: map[string]float64{}}
+}
+
+// collect polls the interface, values of failed commands are not exported
+func (i *iface) collect() {
+	st, err := i.c.Status()
+	up := err == nil
+
+	var sg *wpaclient.Signal
+	if up && st.WpaState == "COMPLETED" {
+		sg, _ = i.c.SignalPoll()
+	}
+
+	pkt := map[string]string{}
+	if res, err := i.c.Execute(wpaclient.CmdPktcntPoll); err == nil {
+		pkt = wpaclient.ParseKV(res)
+	}
+
+	mib := map[string]string{}
+	if res, err := i.c.Execute(wpaclient.CmdMib); err == nil {
+		mib = wpaclient.ParseKV(res)
+	}
+
+	n, scanned := 0, false
+	if res, err := i.c.Execute(wpaclient.CmdScanResults); err == nil {
+		// first line is the header
+		n, scanned = strings.Count(strings.TrimSpace(string(res)), "\n"), true
+	}
+
+	i.mut.Lock()
+	defer i.mut.Unlock()
+
+	i.up, i.status, i.signal, i.pkt, i.mib = up, st, sg, pkt, mib
+	i.scanResults, i.scanned = n, scanned
+}
+
+// event counts ev by its name and details of failures
+func (i *iface) event(ev wpaclient.Event) {
+	if ev.Err != nil {
+		return
+	}
+
+	name := ev.Message
+	if ev.AuthReq != nil {
+		name = strings.TrimSuffix(wpaclient.WpaCtrlReq, "-")
+	}
+	if j := strings.IndexByte(name, ' '); j > 0 {
+		name = name[:j]
+	}
+	if !knownEvents[name] {
+		name = otherEvent
+	}
+	params := eventParams(ev.Message)
+
+	i.mut.Lock()
+	defer i.mut.Unlock()
+
+	i.events[name]++
+	switch name + " " {
+	case wpaclient.WpaEventDisconnected:
+		lg := params["locally_generated"]
+		if lg == "" {
+			lg = "0"
+		}
+		i.disconnects[[2]string{params["reason"], lg}]++
+	case wpaclient.WpaEventAssocReject:
+		i.assocRejects[params["status_code"]]++
+	case wpaclient.WpaEventEapFailure, wpaclient.WpaEventEapFailure2:
+		i.eapFailures++
+	case wpaclient.WpaEventScanFailed:
+		i.scanFailures++
+	}
+}
+
+func (i *iface) metrics(m *metrics) {
+	i.mut.Lock()
+	defer i.mut.Unlock()
+
+	l := []string{"ifname", i.name}
+
+	m.add("wpa_supplicant_up", "Whether the interface answered STATUS.", gauge, l, bool2f(i.up))
+
+	if st := i.status; i.up {
+		m.add("wpa_supplicant_connected", "Whether the interface is in COMPLETED state.", gauge, l,
+			bool2f(st.WpaState == "COMPLETED"))
+		m.add("wpa_supplicant_info", "Interface state and current network, always 1.", gauge,
+			append(l, "state", st.WpaState, "ssid", st.SSID, "bssid", st.BSSID.String(), "key_mgmt", st.KeyMgmt), 1)
+
+		if st.Freq > 0 {
+			m.add("wpa_supplicant_frequency_mhz", "Frequency of the current connection.", gauge, l, float64(st.Freq))
+		}
+	}
+
+	if sg := i.signal; sg != nil {
+		m.add("wpa_supplicant_signal_rssi_dbm", "RSSI of the current connection.", gauge, l, float64(sg.RSSI))
+		m.add("wpa_supplicant_link_speed_mbps", "Link speed of the current connection.", gauge, l, float64(sg.LinkSpeed))
+		// 9999 is reported if driver does not support noise
+		if sg.Noise != 9999 {
+			m.add("wpa_supplicant_noise_dbm", "Noise of the current connection.", gauge, l, float64(sg.Noise))
+		}
+		if _, ok := sg.Params["AVG_RSSI"]; ok {
+			m.add("wpa_supplicant_signal_avg_rssi_dbm", "Average RSSI of the current connection.", gauge, l, float64(sg.AvgRSSI))
+		}
+	}
+
+	for _, p := range []struct{ key, name, help string }{
+		{"TXGOOD", "wpa_supplicant_tx_packets_total", "Successfully transmitted packets."},
+		{"TXBAD", "wpa_supplicant_tx_failed_packets_total", "Failed transmitted packets."},
+		{"RXGOOD", "wpa_supplicant_rx_packets_total", "Successfully received packets."},
+	} {
+		if v, err := strconv.ParseFloat(i.pkt[p.key], 64); err == nil {
+			m.add(p.name, p.help, counter, l, v)
+		}
+	}
+
+	mib := map[string]float64{}
+	for k, s := range i.mib {
+		if v, ok := mibValue(s); ok {
+			mib[k] = v
+		}
+	}
+
+	for _, k := range sortedKeys(mib) {
+		m.add("wpa_supplicant_mib", "Numeric MIB variables.", gauge, append(l, "name", k), mib[k])
+	}
+
+	if i.scanned {
+		m.add("wpa_supplicant_scan_results", "Number of BSSs in scan results.", gauge, l, float64(i.scanResults))
+	}
+
+	for _, k := range sortedKeys(i.events) {
+		m.add("wpa_supplicant_events_total", "Received events by name.", counter, append(l, "event", k), i.events[k])
+	}
+
+	ds := [][2]string{}
+	for k := range i.disconnects {
+		ds = append(ds, k)
+	}
+	sort.Slice(ds, func(a, b int) bool { return ds[a][0]+" "+ds[a][1] < ds[b][0]+" "+ds[b][1] })
+	for _, k := range ds {
+		m.add("wpa_supplicant_disconnects_total", "Disconnections by reason code.", counter,
+			append(l, "reason", k[0], "locally_generated", k[1]), i.disconnects[k])
+	}
+
+	for _, k := range sortedKeys(i.assocRejects) {
+		m.add("wpa_supplicant_assoc_rejects_total", "Association rejections by status code.", counter,
+			append(l, "status_code", k), i.assocRejects[k])
+	}
+
+	m.add("wpa_supplicant_eap_failures_total", "EAP authentication failures.", counter, l, i.eapFailures)
+	m.add("wpa_supplicant_scan_failures_total", "Failed scan requests.", counter, l, i.scanFailures)
+}
+
+// mibValue parses numeric and boolean MIB values
+func mibValue(s string) (float64, bool) {
+	switch s {
+	case "TRUE":
+		return 1, true
+	case "FALSE":
+		return 0, true
+	}
+
+	v, err := strconv.ParseFloat(s, 64)
+	return v, err == nil
+}
+
+// eventParams returns key=value parameters of an event message
+func eventParams(msg string) map[string]string {
+	ps := map[string]string{}
+	for _, f := range strings.Fields(msg) {
+		if j := strings.IndexByte(f, '='); j > 0 {
+			ps[f[:j]] = f[j+1:]
+		}
+	}
+
+	return ps
+}
+
+func bool2f(b bool) float64 {
+	if b {
+		return 1
+	}
+
+	return 0
+}
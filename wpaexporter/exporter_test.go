@@ -0,0 +1,124 @@
+package wpaexporter
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brlbil/wpaclient"
+	"github.com/brlbil/wpaclient/wpatest"
+)
+
+func TestExporter(t *testing.T) {
+	s, err := wpatest.NewServer()
+	if err != nil {
+		t.Fatalf("NewServer failed, %v", err)
+	}
+	defer s.Close()
+
+	c, err := wpaclient.New(s.Addr())
+	if err != nil {
+		t.Fatalf("New failed, %v", err)
+	}
+	defer c.Close()
+
+	s.SetResponse(wpaclient.CmdStatus, "bssid=00:1f:1f:37:42:d9\nfreq=2442\nssid=home\nid=0\n"+
+		"key_mgmt=WPA2-PSK\nwpa_state=COMPLETED\naddress=02:00:00:00:01:00")
+	s.SetResponse(wpaclient.CmdSignalPoll, "RSSI=-52\nLINKSPEED=65\nNOISE=9999\nFREQUENCY=2442")
+	s.SetResponse(wpaclient.CmdPktcntPoll, "TXGOOD=120\nTXBAD=3\nRXGOOD=400")
+	s.SetResponse(wpaclient.CmdMib, "dot11RSNAOptionImplemented=TRUE\ndot11RSNAConfigVersion=1\n"+
+		"dot11RSNAConfigGroupCipher=00-0f-ac-4")
+	s.SetScanResults(
+		wpatest.BSS{BSSID: "00:1f:1f:37:42:d9", SSID: "home", Freq: 2442, Signal: -52},
+		wpatest.BSS{BSSID: "00:1f:1f:37:42:da", SSID: "home", Freq: 5180, Signal: -70},
+	)
+
+	e := New(time.Hour)
+	defer e.Close()
+
+	if err := e.Add("wlan0", c); err != nil {
+		t.Fatalf("Add not expect an error, got %v", err)
+	}
+
+	for _, ev := range []string{
+		wpaclient.WpaEventDisconnected + "bssid=00:1f:1f:37:42:d9 reason=3 locally_generated=1",
+		wpaclient.WpaEventDisconnected + "bssid=00:1f:1f:37:42:d9 reason=4",
+		wpaclient.WpaEventAssocReject + "bssid=00:1f:1f:37:42:d9 status_code=17",
+		wpaclient.WpaEventEapFailure + "EAP authentication failed",
+		"Trying to associate with 00:1f:1f:37:42:d9 (SSID='home' freq=2442 MHz)",
+		"CTRL-EVENT-MADE-UP x=1",
+		wpaclient.WpaEventScanFailed + "ret=-16 retry=1",
+	} {
+		s.SendEvent(wpatest.LevelInfo, ev)
+		// event channels are buffered for a few events
+		time.Sleep(time.Millisecond * 5)
+	}
+
+	exp := []string{
+		"# TYPE wpa_supplicant_up gauge",
+		`wpa_supplicant_up{ifname="wlan0"} 1`,
+		`wpa_supplicant_connected{ifname="wlan0"} 1`,
+		`wpa_supplicant_info{ifname="wlan0",state="COMPLETED",ssid="home",bssid="00:1f:1f:37:42:d9",key_mgmt="WPA2-PSK"} 1`,
+		`wpa_supplicant_frequency_mhz{ifname="wlan0"} 2442`,
+		`wpa_supplicant_signal_rssi_dbm{ifname="wlan0"} -52`,
+		`wpa_supplicant_link_speed_mbps{ifname="wlan0"} 65`,
+		"# TYPE wpa_supplicant_tx_packets_total counter",
+		`wpa_supplicant_tx_packets_total{ifname="wlan0"} 120`,
+		`wpa_supplicant_tx_failed_packets_total{ifname="wlan0"} 3`,
+		`wpa_supplicant_rx_packets_total{ifname="wlan0"} 400`,
+		`wpa_supplicant_mib{ifname="wlan0",name="dot11RSNAConfigVersion"} 1`,
+		`wpa_supplicant_mib{ifname="wlan0",name="dot11RSNAOptionImplemented"} 1`,
+		`wpa_supplicant_scan_results{ifname="wlan0"} 2`,
+		`wpa_supplicant_events_total{ifname="wlan0",event="CTRL-EVENT-DISCONNECTED"} 2`,
+		`wpa_supplicant_events_total{ifname="wlan0",event="other"} 2`,
+		`wpa_supplicant_disconnects_total{ifname="wlan0",reason="3",locally_generated="1"} 1`,
+		`wpa_supplicant_disconnects_total{ifname="wlan0",reason="4",locally_generated="0"} 1`,
+		`wpa_supplicant_assoc_rejects_total{ifname="wlan0",status_code="17"} 1`,
+		`wpa_supplicant_eap_failures_total{ifname="wlan0"} 1`,
+		`wpa_supplicant_scan_failures_total{ifname="wlan0"} 1`,
+	}
+
+	var out string
+	for i := 0; i < 100; i++ {
+		b := &bytes.Buffer{}
+		if _, err := e.WriteTo(b); err != nil {
+			t.Fatalf("WriteTo not expect an error, got %v", err)
+		}
+
+		// events are counted asynchronously
+		if out = b.String(); strings.Contains(out, "wpa_supplicant_scan_failures_total{ifname=\"wlan0\"} 1") {
+			break
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	for _, l := range exp {
+		if !strings.Contains(out, l+"\n") {
+			t.Errorf("expected metrics to contain %s", l)
+		}
+	}
+
+	for _, l := range []string{"wpa_supplicant_noise_dbm", "dot11RSNAConfigGroupCipher", `event="Trying"`} {
+		if strings.Contains(out, l) {
+			t.Errorf("expected metrics not to contain %s", l)
+		}
+	}
+
+	if t.Failed() {
+		t.Log(out)
+	}
+
+	e.Remove("wlan0")
+	b := &bytes.Buffer{}
+	e.WriteTo(b)
+	if b.Len() != 0 {
+		t.Errorf("expected no metrics after Remove, got %s", b)
+	}
+}
+
+func TestEscape(t *testing.T) {
+	if s := escape("a\"b\\c\nd"); s != `a\"b\\c\nd` {
+		t.Errorf("unexpected escape result %s", s)
+	}
+}
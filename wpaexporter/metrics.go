@@ -0,0 +1,103 @@
+package wpaexporter
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Metric types
+const (
+	gauge   = "gauge"
+	counter = "counter"
+)
+
+// metrics groups samples into families written in Prometheus text format
+type metrics struct {
+	names    []string
+	families map[string]*family
+}
+
+type family struct {
+	help    string
+	typ     string
+	samples []sample
+}
+
+type sample struct {
+	// label name and value pairs
+	labels []string
+	value  float64
+}
+
+func newMetrics() *metrics {
+	return &metrics{families: map[string]*family{}}
+}
+
+// add adds a sample to family name, labels are name and value pairs
+func (m *metrics) add(name, help, typ string, labels []string, v float64) {
+	f, ok := m.families[name]
+	if !ok {
+		f = &family{help: help, typ: typ}
+		m.families[name] = f
+		m.names = append(m.names, name)
+	}
+
+	f.samples = append(f.samples, sample{labels: labels, value: v})
+}
+
+// writeTo writes families in the order they are added
+func (m *metrics) writeTo(w io.Writer) (int64, error) {
+	cw := &countWriter{w: w}
+	bw := bufio.NewWriter(cw)
+
+	for _, n := range m.names {
+		f := m.families[n]
+		fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s %s\n", n, f.help, n, f.typ)
+
+		for _, s := range f.samples {
+			bw.WriteString(n)
+			if len(s.labels) > 0 {
+				ls := []string{}
+				for i := 0; i+1 < len(s.labels); i += 2 {
+					ls = append(ls, fmt.Sprintf("%s=\"%s\"", s.labels[i], escape(s.labels[i+1])))
+				}
+				bw.WriteString("{" + strings.Join(ls, ",") + "}")
+			}
+			bw.WriteString(" " + strconv.FormatFloat(s.value, 'g', -1, 64) + "\n")
+		}
+	}
+
+	err := bw.Flush()
+	return cw.n, err
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escape(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countWriter) Write(b []byte) (int, error) {
+	n, err := c.w.Write(b)
+	c.n += int64(n)
+	return n, err
+}
+
+func sortedKeys(m map[string]float64) []string {
+	ks := []string{}
+	for k := range m {
+		ks = append(ks, k)
+	}
+	sort.Strings(ks)
+
+	return ks
+}
@@ -0,0 +1,486 @@
+// Package wpahttp serves a JSON API over HTTP on top of wpaclient.Client.
+//
+//	GET    /status         current connection status
+//	GET    /scan           scan results, scanning if there are none
+//	GET    /signal         signal of the current connection
+//	GET    /networks       configured networks
+//	POST   /networks       adds a network, body is a Config
+//	PATCH  /networks/{id}  sets parameters of a network, body is a Config
+//	DELETE /networks/{id}  removes a network
+//	POST   /connect        connects to a network, body is a ConnectRequest
+//	POST   /disconnect     disconnects
+//	GET    /events         Server-Sent Events stream of events,
+//	                       filtered with "event" query parameters
+//
+// Errors are returned as an Error object with a matching status code.
+package wpahttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brlbil/wpaclient"
+)
+
+// Status is the response of GET /status
+type Status struct {
+	WpaState       string `json:"wpa_state"`
+	BSSID          string `json:"bssid,omitempty"`
+	SSID           string `json:"ssid,omitempty"`
+	Freq           int    `json:"freq,omitempty"`
+	ID             int    `json:"id"`
+	KeyMgmt        string `json:"key_mgmt,omitempty"`
+	PairwiseCipher string `json:"pairwise_cipher,omitempty"`
+	GroupCipher    string `json:"group_cipher,omitempty"`
+	IPAddress      string `json:"ip_address,omitempty"`
+	Address        string `json:"address,omitempty"`
+}
+
+// AP is an entry of GET /scan response
+type AP struct {
+	BSSID  string   `json:"bssid"`
+	SSID   string   `json:"ssid"`
+	Freq   int      `json:"freq"`
+	Signal int      `json:"signal"`
+	Flags  []string `json:"flags"`
+}
+
+// Network is an entry of GET /networks response
+type Network struct {
+	ID    int      `json:"id"`
+	SSID  string   `json:"ssid"`
+	BSSID string   `json:"bssid"`
+	Flags []string `json:"flags"`
+}
+
+// Signal is the response of GET /signal
+type Signal struct {
+	RSSI      int    `json:"rssi"`
+	LinkSpeed int    `json:"link_speed"`
+	Noise     int    `json:"noise"`
+	Freq      int    `json:"freq"`
+	Width     string `json:"width,omitempty"`
+}
+
+// Event is the data of an event sent on GET /events
+type Event struct {
+	Ifname  string `json:"ifname,omitempty"`
+	Level   int    `json:"level"`
+	Message string `json:"message"`
+}
+
+// Config holds network parameters with their wpa_supplicant names like
+// "ssid", "psk" or "key_mgmt". String parameters are given unquoted.
+type Config map[string]string
+
+// ConnectRequest is the body of POST /connect, either an existing network
+// is selected with ID, or a network is added with Config and selected
+type ConnectRequest struct {
+	ID     *int   `json:"id,omitempty"`
+	Config Config `json:"config,omitempty"`
+}
+
+// ID is the response of requests creating a network
+type ID struct {
+	ID int `json:"id"`
+}
+
+// Error is the response of failed requests
+type Error struct {
+	Error string `json:"error"`
+}
+
+// badRequestError is returned for requests rejected before reaching wpa_supplicant
+type badRequestError string
+
+func (e badRequestError) Error() string { return string(e) }
+
+// stringParams are the network parameters quoted before sent to wpa_supplicant,
+// besides the ones set by wpaclient.NetworkConfig fields
+var stringParams = map[string]bool{
+	"id_str": true, "anonymous_identity": true,
+	"ca_cert": true, "client_cert": true, "private_key": true, "private_key_passwd": true,
+	"phase1": true, "phase2": true, "subject_match": true, "altsubject_match": true,
+	"domain_match": true, "domain_suffix_match": true,
+}
+
+// ScanTimeout is the time GET /scan waits for a scan to complete
+var ScanTimeout = time.Second * 15
+
+// Server serves the JSON API
+type Server struct {
+	c   *wpaclient.Client
+	mux *http.ServeMux
+}
+
+// NewServer returns a Server controlling c
+func NewServer(c *wpaclient.Client) *Server {
+	s := &Server{c: c, mux: http.NewServeMux()}
+
+	s.mux.HandleFunc("/status", s.method(http.MethodGet, s.status))
+	s.mux.HandleFunc("/scan", s.method(http.MethodGet, s.scan))
+	s.mux.HandleFunc("/signal", s.method(http.MethodGet, s.signal))
+	s.mux.HandleFunc("/networks", s.networks)
+	s.mux.HandleFunc("/networks/", s.network)
+	s.mux.HandleFunc("/connect", s.method(http.MethodPost, s.connect))
+	s.mux.HandleFunc("/disconnect", s.method(http.MethodPost, s.disconnect))
+	s.mux.HandleFunc("/events", s.method(http.MethodGet, s.events))
+
+	return s
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) method(m string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != m {
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+			return
+		}
+
+		h(w, r)
+	}
+}
+
+func (s *Server) status(w http.ResponseWriter, r *http.Request) {
+	st, err := s.c.Status()
+	if err != nil {
+		writeError(w, statusCode(err), err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, Status{
+		WpaState:       st.WpaState,
+		BSSID:          st.BSSID.String(),
+		SSID:           st.SSID,
+		Freq:           st.Freq,
+		ID:             st.ID,
+		KeyMgmt:        st.KeyMgmt,
+		PairwiseCipher: st.PairwiseCipher,
+		GroupCipher:    st.GroupCipher,
+		IPAddress:      st.IPAddress,
+		Address:        st.Address.String(),
+	})
+}
+
+func (s *Server) scan(w http.ResponseWriter, r *http.Request) {
+	aps, err := s.c.ScanResults()
+	if err == nil && len(aps) == 0 {
+		if err = s.waitScan(r.Context()); err == nil {
+			aps, err = s.c.ScanResults()
+		}
+	}
+	if err != nil {
+		writeError(w, statusCode(err), err)
+		return
+	}
+
+	res := []AP{}
+	for _, ap := range aps {
+		res = append(res, AP{BSSID: ap.BSSID.String(), SSID: ap.SSID,
+			Freq: ap.Frequency, Signal: ap.SignalStrength, Flags: ap.Flags})
+	}
+
+	writeJSON(w, http.StatusOK, res)
+}
+
+// waitScan executes "SCAN" command and waits for the scan to complete
+func (s *Server) waitScan(ctx context.Context) error {
+	// scan events may have no parameters, so no trailing space
+	done, failed := strings.TrimSpace(wpaclient.WpaEventScanResults), strings.TrimSpace(wpaclient.WpaEventScanFailed)
+	ch, err := s.c.Notify(done, failed)
+	if err != nil {
+		return err
+	}
+	defer s.c.Stop(ch)
+
+	if _, err := s.c.Execute(wpaclient.CmdScan); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, ScanTimeout)
+	defer cancel()
+
+	for {
+		select {
+		case ev, ok := <-ch:
+			if !ok {
+				return errors.New("event channel closed")
+			}
+			if strings.HasPrefix(ev.Message, failed) {
+				return errors.New("scan failed")
+			}
+			if strings.HasPrefix(ev.Message, done) {
+				return nil
+			}
+		case <-ctx.Done():
+			return errors.New("scan timed out")
+		}
+	}
+}
+
+func (s *Server) signal(w http.ResponseWriter, r *http.Request) {
+	sg, err := s.c.SignalPoll()
+	if err != nil {
+		writeError(w, statusCode(err), err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, Signal{RSSI: sg.RSSI, LinkSpeed: sg.LinkSpeed,
+		Noise: sg.Noise, Freq: sg.Frequency, Width: sg.Width})
+}
+
+// networks serves /networks
+func (s *Server) networks(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		ns, err := s.c.ListNetworks()
+		if err != nil {
+			writeError(w, statusCode(err), err)
+			return
+		}
+
+		res := []Network{}
+		for _, n := range ns {
+			res = append(res, Network{ID: n.ID, SSID: n.SSID, BSSID: n.BSSID, Flags: n.Flags})
+		}
+
+		writeJSON(w, http.StatusOK, res)
+	case http.MethodPost:
+		var cf Config
+		if err := json.NewDecoder(r.Body).Decode(&cf); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		id, err := s.add(cf)
+		if err != nil {
+			writeError(w, statusCode(err), err)
+			return
+		}
+
+		writeJSON(w, http.StatusCreated, ID{ID: id})
+	default:
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+	}
+}
+
+// network serves /networks/{id}
+func (s *Server) network(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/networks/"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, errors.New("invalid network id"))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPatch:
+		var cf Config
+		if err := json.NewDecoder(r.Body).Decode(&cf); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := s.configure(id, cf); err != nil {
+			writeError(w, statusCode(err), err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodDelete:
+		if err := s.c.RemoveNetwork(id); err != nil {
+			writeError(w, statusCode(err), err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+	}
+}
+
+func (s *Server) connect(w http.ResponseWriter, r *http.Request) {
+	var req ConnectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if (req.ID == nil) == (len(req.Config) == 0) {
+		writeError(w, http.StatusBadRequest, errors.New("either id or config is required"))
+		return
+	}
+
+	id := 0
+	if req.ID != nil {
+		id = *req.ID
+	} else {
+		var err error
+		if id, err = s.add(req.Config); err != nil {
+			writeError(w, statusCode(err), err)
+			return
+		}
+	}
+
+	if err := s.c.SelectNetwork(id); err != nil {
+		writeError(w, statusCode(err), err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, ID{ID: id})
+}
+
+func (s *Server) disconnect(w http.ResponseWriter, r *http.Request) {
+	if err := s.c.Disconnect(); err != nil {
+		writeError(w, statusCode(err), err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) events(w http.ResponseWriter, r *http.Request) {
+	fl, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming not supported"))
+		return
+	}
+
+	ch, err := s.c.Notify(r.URL.Query()["event"]...)
+	if err != nil {
+		writeError(w, statusCode(err), err)
+		return
+	}
+	defer s.c.Stop(ch)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	fl.Flush()
+
+	for {
+		select {
+		case ev, ok := <-ch:
+			if !ok {
+				return
+			}
+
+			if ev.Err != nil {
+				continue
+			}
+
+			b, _ := json.Marshal(Event{Ifname: ev.Ifname, Level: ev.Sev, Message: ev.Message})
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", eventName(ev.Message), b); err != nil {
+				return
+			}
+			fl.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// add adds a network configured with cf, it is removed if configuring fails
+func (s *Server) add(cf Config) (int, error) {
+	cfg, err := cf.networkConfig()
+	if err != nil {
+		return 0, err
+	}
+
+	return s.c.AddNetworkConfig(cfg)
+}
+
+// configure sets the parameters of network id given in cf
+func (s *Server) configure(id int, cf Config) error {
+	cfg, err := cf.networkConfig()
+	if err != nil {
+		return err
+	}
+
+	// key management is kept, NetworkConfig sets a default otherwise
+	if cfg.KeyMgmt == "" {
+		if cfg.KeyMgmt, err = s.c.GetNetwork(id, "key_mgmt"); err != nil {
+			return fmt.Errorf("get key_mgmt: %w", err)
+		}
+	}
+
+	return s.c.ConfigureNetwork(id, cfg)
+}
+
+// networkConfig returns cf as a wpaclient.NetworkConfig, parameters without
+// a field are kept in Params and string ones are quoted
+func (cf Config) networkConfig() (wpaclient.NetworkConfig, error) {
+	cfg := wpaclient.NetworkConfig{Params: map[string]string{}}
+	for k, v := range cf {
+		switch k {
+		case "ssid":
+			cfg.SSID = v
+		case "bssid":
+			cfg.BSSID = v
+		case "psk":
+			cfg.PSK = wpaclient.Secret(v)
+		case "sae_password":
+			cfg.SAEPassword = wpaclient.Secret(v)
+		case "key_mgmt":
+			cfg.KeyMgmt = v
+		case "identity":
+			cfg.Identity = v
+		case "password":
+			cfg.Password = wpaclient.Secret(v)
+		default:
+			if k == "" || strings.IndexFunc(k, invalidParamRune) >= 0 {
+				return cfg, badRequestError(fmt.Sprintf("invalid parameter name %q", k))
+			}
+
+			if stringParams[k] {
+				v = wpaclient.Quote(v)
+			}
+			cfg.Params[k] = v
+		}
+	}
+
+	return cfg, nil
+}
+
+func invalidParamRune(r rune) bool {
+	return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_')
+}
+
+// eventName returns the name of the event in msg, like "CTRL-EVENT-CONNECTED"
+func eventName(msg string) string {
+	if i := strings.IndexByte(msg, ' '); i > 0 {
+		return msg[:i]
+	}
+
+	return msg
+}
+
+// statusCode maps control interface errors to HTTP status codes
+func statusCode(err error) int {
+	var ie *wpaclient.InvalidCmdError
+	var be badRequestError
+	if errors.Is(err, wpaclient.ErrCmdFailed) || errors.As(err, &ie) || errors.As(err, &be) {
+		return http.StatusBadRequest
+	}
+
+	return http.StatusBadGateway
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, Error{Error: err.Error()})
+}
@@ -0,0 +1,213 @@
+package wpahttp
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brlbil/wpaclient"
+	"github.com/brlbil/wpaclient/wpatest"
+)
+
+func newServer(t *testing.T) (*wpatest.Server, *httptest.Server, func()) {
+	s, err := wpatest.NewServer()
+	if err != nil {
+		t.Fatalf("NewServer failed, %v", err)
+	}
+
+	c, err := wpaclient.New(s.Addr())
+	if err != nil {
+		s.Close()
+		t.Fatalf("New failed, %v", err)
+	}
+
+	hs := httptest.NewServer(NewServer(c))
+
+	return s, hs, func() {
+		hs.Close()
+		c.Close()
+		s.Close()
+	}
+}
+
+// do sends a request with body and decodes the response to v if not nil
+func do(t *testing.T, method, url, body string, v interface{}) int {
+	t.Helper()
+
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s failed, %v", method, url, err)
+	}
+	defer res.Body.Close()
+
+	if v != nil {
+		if err := json.NewDecoder(res.Body).Decode(v); err != nil {
+			t.Fatalf("%s %s decode failed, %v", method, url, err)
+		}
+	}
+
+	return res.StatusCode
+}
+
+func TestNetworks(t *testing.T) {
+	s, hs, cleanup := newServer(t)
+	defer cleanup()
+
+	var id ID
+	if code := do(t, http.MethodPost, hs.URL+"/networks", `{"ssid":"home","psk":"secret","key_mgmt":"WPA-PSK"}`, &id); code != http.StatusCreated {
+		t.Fatalf("POST /networks expected %d, got %d", http.StatusCreated, code)
+	}
+
+	ns := s.Networks()
+	if len(ns) != 1 || ns[0].Vars["ssid"] != `"home"` || ns[0].Vars["psk"] != `"secret"` || ns[0].Vars["key_mgmt"] != "WPA-PSK" {
+		t.Errorf("expected network to be configured, got %#v", ns)
+	}
+
+	psk := strings.Repeat("0a", 32)
+	if code := do(t, http.MethodPatch, hs.URL+"/networks/0", `{"psk":"`+psk+`"}`, nil); code != http.StatusNoContent {
+		t.Errorf("PATCH /networks/0 expected %d, got %d", http.StatusNoContent, code)
+	}
+
+	if ns := s.Networks(); ns[0].Vars["psk"] != psk {
+		t.Errorf("expected hex psk not to be quoted, got %s", ns[0].Vars["psk"])
+	}
+
+	if ns := s.Networks(); ns[0].Vars["key_mgmt"] != "WPA-PSK" {
+		t.Errorf("expected key_mgmt to be kept, got %s", ns[0].Vars["key_mgmt"])
+	}
+
+	var e Error
+	if code := do(t, http.MethodPatch, hs.URL+"/networks/0", `{"bad key":"x"}`, &e); code != http.StatusBadRequest ||
+		e.Error != `invalid parameter name "bad key"` {
+		t.Errorf("PATCH /networks/0 expected %d with error, got %d %#v", http.StatusBadRequest, code, e)
+	}
+
+	var list []Network
+	if code := do(t, http.MethodGet, hs.URL+"/networks", "", &list); code != http.StatusOK || len(list) != 1 || list[0].SSID != "home" {
+		t.Errorf("GET /networks expected network home, got %d %#v", code, list)
+	}
+
+	if code := do(t, http.MethodDelete, hs.URL+"/networks/0", "", nil); code != http.StatusNoContent {
+		t.Errorf("DELETE /networks/0 expected %d, got %d", http.StatusNoContent, code)
+	}
+
+	if code := do(t, http.MethodDelete, hs.URL+"/networks/0", "", nil); code != http.StatusBadRequest {
+		t.Errorf("DELETE /networks/0 expected %d, got %d", http.StatusBadRequest, code)
+	}
+
+	if code := do(t, http.MethodPut, hs.URL+"/networks", "", nil); code != http.StatusMethodNotAllowed {
+		t.Errorf("PUT /networks expected %d, got %d", http.StatusMethodNotAllowed, code)
+	}
+}
+
+func TestScan(t *testing.T) {
+	s, hs, cleanup := newServer(t)
+	defer cleanup()
+
+	scans := make(chan struct{}, 2)
+	s.Handle(wpaclient.CmdScan, func([]string) string {
+		scans <- struct{}{}
+		s.SetScanResults(wpatest.BSS{BSSID: "00:1f:1f:37:42:d9", SSID: "home", Freq: 2442, Signal: -50, Flags: []string{"ESS"}})
+		go func() {
+			time.Sleep(time.Millisecond * 10)
+			s.SendEvent(wpatest.LevelInfo, wpaclient.WpaEventScanResults)
+		}()
+		return "OK"
+	})
+
+	exp := []AP{{BSSID: "00:1f:1f:37:42:d9", SSID: "home", Freq: 2442, Signal: -50, Flags: []string{"ESS"}}}
+	for i := 0; i < 2; i++ {
+		var aps []AP
+		if code := do(t, http.MethodGet, hs.URL+"/scan", "", &aps); code != http.StatusOK || !reflect.DeepEqual(aps, exp) {
+			t.Errorf("GET /scan expected %#v, got %d %#v", exp, code, aps)
+		}
+	}
+
+	// only scans when there are no results
+	if n := len(scans); n != 1 {
+		t.Errorf("expected 1 scan, got %d", n)
+	}
+
+	defer func(d time.Duration) { ScanTimeout = d }(ScanTimeout)
+	ScanTimeout = time.Millisecond * 100
+
+	s.SetScanResults()
+	s.SetResponse(wpaclient.CmdScan, "OK")
+	var e Error
+	if code := do(t, http.MethodGet, hs.URL+"/scan", "", &e); code == http.StatusOK || e.Error == "" {
+		t.Errorf("GET /scan expected an error when scan times out, got %d %#v", code, e)
+	}
+}
+
+func TestConnect(t *testing.T) {
+	s, hs, cleanup := newServer(t)
+	defer cleanup()
+
+	s.AddAP(wpatest.AP{BSSID: "00:1f:1f:37:42:d9", SSID: "home", Freq: 2442, Signal: -50, PSK: "secret"})
+
+	res, err := http.Get(hs.URL + "/events?event=" + url.QueryEscape(wpaclient.WpaEventConnected))
+	if err != nil {
+		t.Fatalf("GET /events failed, %v", err)
+	}
+	defer res.Body.Close()
+
+	if ct := res.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("expected event stream, got %s", ct)
+	}
+
+	var id ID
+	if code := do(t, http.MethodPost, hs.URL+"/connect", `{"config":{"ssid":"home","psk":"secret"}}`, &id); code != http.StatusOK {
+		t.Fatalf("POST /connect expected %d, got %d", http.StatusOK, code)
+	}
+
+	lines := make(chan string)
+	go func() {
+		sc := bufio.NewScanner(res.Body)
+		for sc.Scan() {
+			lines <- sc.Text()
+		}
+		close(lines)
+	}()
+
+	select {
+	case l := <-lines:
+		if l != "event: "+strings.TrimSpace(wpaclient.WpaEventConnected) {
+			t.Errorf("expected %s event, got %s", wpaclient.WpaEventConnected, l)
+		}
+	case <-time.After(time.Second * 2):
+		t.Fatal("event not received")
+	}
+
+	var st Status
+	if code := do(t, http.MethodGet, hs.URL+"/status", "", &st); code != http.StatusOK {
+		t.Fatalf("GET /status expected %d, got %d", http.StatusOK, code)
+	}
+
+	if st.WpaState != wpatest.StateCompleted || st.BSSID != "00:1f:1f:37:42:d9" || st.ID != id.ID {
+		t.Errorf("expected to be connected to home, got %#v", st)
+	}
+
+	var sg Signal
+	if code := do(t, http.MethodGet, hs.URL+"/signal", "", &sg); code != http.StatusOK || sg.RSSI != -50 {
+		t.Errorf("GET /signal expected rssi -50, got %d %#v", code, sg)
+	}
+
+	if code := do(t, http.MethodPost, hs.URL+"/disconnect", "", nil); code != http.StatusNoContent {
+		t.Errorf("POST /disconnect expected %d, got %d", http.StatusNoContent, code)
+	}
+
+	if code := do(t, http.MethodPost, hs.URL+"/connect", `{}`, nil); code != http.StatusBadRequest {
+		t.Errorf("POST /connect expected %d, got %d", http.StatusBadRequest, code)
+	}
+}
@@ -0,0 +1,156 @@
+package wpaproxy
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/brlbil/wpaclient"
+)
+
+// MaxFrameSize is the maximum size of a message
+const MaxFrameSize = 1 << 16
+
+// DialTimeout is the time Dial has to connect and authenticate
+var DialTimeout = time.Second * 10
+
+// Conn is a wpaclient.Transport framing control messages over a stream connection
+type Conn struct {
+	c net.Conn
+	r *bufio.Reader
+
+	// protects writes, a frame is written in two parts
+	wmut sync.Mutex
+}
+
+// NewConn returns a Conn over c
+func NewConn(c net.Conn) *Conn {
+	return &Conn{c: c, r: bufio.NewReader(c)}
+}
+
+// Dial connects to the Proxy at TCP address addr and authenticates with token,
+// TLS is used if cfg is not nil
+func Dial(addr, token string, cfg *tls.Config) (*Conn, error) {
+	d := &net.Dialer{Timeout: DialTimeout}
+
+	var (
+		nc  net.Conn
+		err error
+	)
+	if cfg != nil {
+		nc, err = tls.DialWithDialer(d, "tcp", addr, cfg)
+	} else {
+		nc, err = d.Dial("tcp", addr)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("dial failed: %w", err)
+	}
+
+	c := NewConn(nc)
+	if err := authenticate(c, token); err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// authenticate sends token over t, t has DialTimeout to accept it
+func authenticate(t wpaclient.Transport, token string) error {
+	t.SetDeadline(time.Now().Add(DialTimeout))
+	defer t.SetDeadline(time.Time{})
+
+	if err := t.Send([]byte(cmdAuth + " " + token)); err != nil {
+		return err
+	}
+
+	res, err := t.Receive()
+	if err != nil {
+		return err
+	}
+
+	if string(res) != "OK\n" {
+		return fmt.Errorf("authentication failed: %s", bytes.TrimSpace(res))
+	}
+
+	return nil
+}
+
+// NewClient returns a wpaclient.Client controlling the wpa_supplicant behind
+// the Proxy at addr, commands and events use separate connections
+func NewClient(addr, token string, cfg *tls.Config, opts ...wpaclient.Option) (*wpaclient.Client, error) {
+	return newClient(func() (wpaclient.Transport, error) { return Dial(addr, token, cfg) }, opts)
+}
+
+// newClient returns a wpaclient.Client over two transports returned from dial
+func newClient(dial func() (wpaclient.Transport, error), opts []wpaclient.Option) (*wpaclient.Client, error) {
+	cmd, err := dial()
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := dial()
+	if err != nil {
+		cmd.Close()
+		return nil, err
+	}
+
+	return wpaclient.NewWithTransport(cmd, events, opts...)
+}
+
+// Send writes b as a single frame
+func (c *Conn) Send(b []byte) error {
+	if len(b) > MaxFrameSize {
+		return fmt.Errorf("message size %d exceeds %d", len(b), MaxFrameSize)
+	}
+
+	buf := make([]byte, 4+len(b))
+	binary.BigEndian.PutUint32(buf, uint32(len(b)))
+	copy(buf[4:], b)
+
+	c.wmut.Lock()
+	defer c.wmut.Unlock()
+
+	if _, err := c.c.Write(buf); err != nil {
+		return fmt.Errorf("write failed: %w", err)
+	}
+
+	return nil
+}
+
+// Receive reads a frame
+func (c *Conn) Receive() ([]byte, error) {
+	var h [4]byte
+	if _, err := io.ReadFull(c.r, h[:]); err != nil {
+		return nil, err
+	}
+
+	n := binary.BigEndian.Uint32(h[:])
+	if n > MaxFrameSize {
+		return nil, fmt.Errorf("frame size %d exceeds %d", n, MaxFrameSize)
+	}
+
+	b := make([]byte, n)
+	if _, err := io.ReadFull(c.r, b); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// Close closes the connection
+func (c *Conn) Close() error {
+	return c.c.Close()
+}
+
+// SetDeadline sets read and write deadline of the connection
+func (c *Conn) SetDeadline(t time.Time) error {
+	return c.c.SetDeadline(t)
+}
@@ -0,0 +1,385 @@
+// Package wpaproxy exposes a local wpa_supplicant control interface over TCP or
+// WebSocket, optionally with TLS, so it can be managed from a remote host.
+//
+// Over TCP every control message is sent as a frame, a 4 byte big endian length
+// followed by the message. Over WebSocket every control message is a binary
+// message, Proxy is an http.Handler upgrading requests. The first frame of a connection must be "AUTH <token>",
+// answered with "OK" or "FAIL". After that the connection behaves like a local
+// control socket: commands are forwarded and "ATTACH" makes it an event monitor.
+// Events of the single local monitor are multiplexed to every remote one.
+package wpaproxy
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/brlbil/wpaclient"
+)
+
+const (
+	cmdAuth   = "AUTH"
+	cmdAttach = "ATTACH"
+	cmdDetach = "DETACH"
+	cmdLevel  = "LEVEL"
+)
+
+// queueSize is the number of messages buffered for a remote connection,
+// events are dropped when it is full
+const queueSize = 32
+
+// Timeout is the time a remote connection has to authenticate and
+// the local control interface has to respond to a forwarded command
+var Timeout = time.Second * 10
+
+// errEmptyToken is returned for an empty token, which would let anyone in
+var errEmptyToken = errors.New("empty token")
+
+// Proxy forwards commands of remote connections to a local control interface
+type Proxy struct {
+	token string
+
+	cmd    wpaclient.Transport
+	cmdmut sync.Mutex
+
+	// replies of timed out commands still to arrive on cmd
+	pending int
+
+	events   wpaclient.Transport
+	attached bool
+
+	mut sync.Mutex
+	// attached remote monitors
+	monitors map[*remote]struct{}
+	// authenticated remote connections
+	conns  map[*remote]struct{}
+	ls     []net.Listener
+	closed bool
+}
+
+// New returns a Proxy for the control interface at addr, a socket path,
+// an interface name or a "udp:" prefixed address. Remote connections have to
+// authenticate with token, which must not be empty.
+func New(addr, token string) (*Proxy, error) {
+	if token == "" {
+		return nil, errEmptyToken
+	}
+
+	dial := wpaclient.DialUnix
+	if strings.HasPrefix(addr, "udp:") {
+		dial = wpaclient.DialUDP
+	}
+
+	cmd, err := dial(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := dial(addr)
+	if err != nil {
+		cmd.Close()
+		return nil, err
+	}
+
+	return NewWithTransport(cmd, events, token)
+}
+
+// NewWithTransport returns a Proxy forwarding commands over cmd and
+// receiving events over events, Proxy owns the transports
+func NewWithTransport(cmd, events wpaclient.Transport, token string) (*Proxy, error) {
+	if token == "" {
+		return nil, errEmptyToken
+	}
+
+	return &Proxy{token: token, cmd: cmd, events: events,
+		monitors: map[*remote]struct{}{}, conns: map[*remote]struct{}{}}, nil
+}
+
+// Serve accepts connections on l until it fails or Proxy is closed,
+// l can be a tls listener
+func (p *Proxy) Serve(l net.Listener) error {
+	p.mut.Lock()
+	if p.closed {
+		p.mut.Unlock()
+		return errors.New("proxy closed")
+	}
+	p.ls = append(p.ls, l)
+	p.mut.Unlock()
+
+	for {
+		nc, err := l.Accept()
+		if err != nil {
+			p.mut.Lock()
+			closed := p.closed
+			p.mut.Unlock()
+
+			if closed {
+				return nil
+			}
+			return err
+		}
+
+		go p.handle(newRemote(NewConn(nc)))
+	}
+}
+
+// ListenAndServe listens on TCP address addr and calls Serve
+func (p *Proxy) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	return p.Serve(l)
+}
+
+// Close stops listeners, closes remote connections and local transports
+func (p *Proxy) Close() error {
+	p.mut.Lock()
+	p.closed = true
+	ls, conns := p.ls, p.conns
+	p.ls, p.conns = nil, map[*remote]struct{}{}
+	attached := p.attached
+	p.mut.Unlock()
+
+	for _, l := range ls {
+		l.Close()
+	}
+
+	for c := range conns {
+		c.close()
+	}
+
+	if attached {
+		p.events.Send([]byte(cmdDetach))
+	}
+
+	err := p.cmd.Close()
+	if e := p.events.Close(); err == nil {
+		err = e
+	}
+
+	return err
+}
+
+func (p *Proxy) handle(c *remote) {
+	defer func() {
+		p.mut.Lock()
+		delete(p.monitors, c)
+		delete(p.conns, c)
+		p.mut.Unlock()
+		c.close()
+	}()
+
+	if !p.auth(c) {
+		return
+	}
+
+	p.mut.Lock()
+	if p.closed {
+		p.mut.Unlock()
+		return
+	}
+	p.conns[c] = struct{}{}
+	p.mut.Unlock()
+
+	for {
+		b, err := c.t.Receive()
+		if err != nil {
+			return
+		}
+
+		res, err := p.process(c, b)
+		if err != nil {
+			return
+		}
+
+		if res != nil {
+			c.send(res, true)
+		}
+	}
+}
+
+// auth reads the first frame of c and checks the token, c has Timeout to send it
+func (p *Proxy) auth(c *remote) bool {
+	c.t.SetDeadline(time.Now().Add(Timeout))
+	b, err := c.t.Receive()
+	if err != nil {
+		return false
+	}
+	c.t.SetDeadline(time.Time{})
+
+	tok := bytes.TrimPrefix(b, []byte(cmdAuth+" "))
+	if !bytes.HasPrefix(b, []byte(cmdAuth+" ")) ||
+		subtle.ConstantTimeCompare(tok, []byte(p.token)) != 1 {
+		c.t.Send([]byte("FAIL\n"))
+		return false
+	}
+
+	return c.t.Send([]byte("OK\n")) == nil
+}
+
+// process handles monitor commands itself and forwards others,
+// a nil response is already queued to c
+func (p *Proxy) process(c *remote, b []byte) ([]byte, error) {
+	switch cmd := string(bytes.TrimSpace(b)); {
+	case cmd == cmdAttach:
+		if err := p.attach(); err != nil {
+			return []byte("FAIL\n"), nil
+		}
+
+		// reply is queued before events relayed to c
+		c.send([]byte("OK\n"), true)
+
+		p.mut.Lock()
+		p.monitors[c] = struct{}{}
+		p.mut.Unlock()
+
+		return nil, nil
+	case cmd == cmdDetach:
+		p.mut.Lock()
+		delete(p.monitors, c)
+		p.mut.Unlock()
+
+		return []byte("OK\n"), nil
+	case strings.HasPrefix(cmd, cmdLevel+" "):
+		// level of the shared local monitor is not changed by a remote one
+		return []byte("OK\n"), nil
+	}
+
+	p.cmdmut.Lock()
+	defer p.cmdmut.Unlock()
+
+	defer p.cmd.SetDeadline(time.Time{})
+
+	if err := p.drain(); err != nil {
+		return nil, err
+	}
+
+	p.cmd.SetDeadline(time.Now().Add(Timeout))
+	res, err := wpaclient.Exchange(p.cmd, b)
+	if err != nil {
+		// reply may still arrive, it must not answer the next command
+		p.pending++
+		return nil, fmt.Errorf("forward failed: %w", err)
+	}
+
+	return res, nil
+}
+
+// drain drops late replies of timed out commands, they are considered lost
+// if they do not arrive in Timeout
+func (p *Proxy) drain() error {
+	for ; p.pending > 0; p.pending-- {
+		p.cmd.SetDeadline(time.Now().Add(Timeout))
+		if _, err := p.cmd.Receive(); err != nil {
+			p.pending = 0
+			return fmt.Errorf("drain failed: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// attach attaches the local monitor once and starts multiplexing events
+func (p *Proxy) attach() error {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	if p.attached {
+		return nil
+	}
+
+	p.events.SetDeadline(time.Now().Add(Timeout))
+	res, err := wpaclient.Exchange(p.events, []byte(cmdAttach))
+	p.events.SetDeadline(time.Time{})
+	if err != nil {
+		return err
+	}
+
+	if string(res) != "OK\n" {
+		return fmt.Errorf("attach failed: %s", bytes.TrimSpace(res))
+	}
+
+	p.attached = true
+	go p.multiplex()
+
+	return nil
+}
+
+// multiplex relays events to monitors until receiving fails, the local monitor
+// is attached again with the next "ATTACH"
+func (p *Proxy) multiplex() {
+	for {
+		b, err := p.events.Receive()
+		if err != nil {
+			p.mut.Lock()
+			p.attached = false
+			p.mut.Unlock()
+			return
+		}
+
+		p.mut.Lock()
+		for c := range p.monitors {
+			c.send(b, false)
+		}
+		p.mut.Unlock()
+	}
+}
+
+// remote is a remote connection
+type remote struct {
+	t    wpaclient.Transport
+	out  chan []byte
+	done chan struct{}
+	once sync.Once
+}
+
+func newRemote(t wpaclient.Transport) *remote {
+	c := &remote{t: t, out: make(chan []byte, queueSize), done: make(chan struct{})}
+	go c.write()
+
+	return c
+}
+
+// send queues b, if wait is false b is dropped when queue is full
+func (c *remote) send(b []byte, wait bool) {
+	if !wait {
+		select {
+		case c.out <- b:
+		default:
+		}
+		return
+	}
+
+	select {
+	case c.out <- b:
+	case <-c.done:
+	}
+}
+
+func (c *remote) write() {
+	for {
+		select {
+		case b := <-c.out:
+			if err := c.t.Send(b); err != nil {
+				c.close()
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+func (c *remote) close() {
+	c.once.Do(func() {
+		close(c.done)
+		c.t.Close()
+	})
+}
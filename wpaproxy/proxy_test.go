@@ -0,0 +1,362 @@
+package wpaproxy
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/brlbil/wpaclient"
+	"github.com/brlbil/wpaclient/wpatest"
+)
+
+const token = "secret"
+
+func newProxy(t *testing.T, cfg *tls.Config) (*wpatest.Server, string, func()) {
+	s, err := wpatest.NewServer()
+	if err != nil {
+		t.Fatalf("NewServer failed, %v", err)
+	}
+
+	p, err := New(s.Addr(), token)
+	if err != nil {
+		s.Close()
+		t.Fatalf("New not expect an error, got %v", err)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed, %v", err)
+	}
+
+	if cfg != nil {
+		l = tls.NewListener(l, cfg)
+	}
+	go p.Serve(l)
+
+	return s, l.Addr().String(), func() {
+		p.Close()
+		s.Close()
+	}
+}
+
+func TestProxy(t *testing.T) {
+	s, addr, close := newProxy(t, nil)
+	defer close()
+
+	c1, err := NewClient(addr, token, nil)
+	if err != nil {
+		t.Fatalf("NewClient not expect an error, got %v", err)
+	}
+	defer c1.Close()
+
+	c2, err := NewClient(addr, token, nil)
+	if err != nil {
+		t.Fatalf("NewClient not expect an error, got %v", err)
+	}
+	defer c2.Close()
+
+	res, err := c1.Execute(wpaclient.CmdPing)
+	if err != nil {
+		t.Fatalf("Execute not expect an error, got %v", err)
+	}
+
+	if string(res) != "PONG\n" {
+		t.Errorf("expected PONG, got %q", res)
+	}
+
+	if _, err := c2.Execute("NOT_A_COMMAND"); err != wpaclient.ErrUnknownCmd {
+		t.Errorf("expected %v, got %v", wpaclient.ErrUnknownCmd, err)
+	}
+
+	ch1, err := c1.Notify(wpaclient.WpaEventConnected)
+	if err != nil {
+		t.Fatalf("Notify not expect an error, got %v", err)
+	}
+
+	ch2, err := c2.Notify(wpaclient.WpaEventConnected)
+	if err != nil {
+		t.Fatalf("Notify not expect an error, got %v", err)
+	}
+
+	// remote monitors share a single local one
+	if n := s.Monitors(); n != 1 {
+		t.Errorf("expected 1 monitor, got %d", n)
+	}
+
+	s.SendEvent(wpatest.LevelInfo, wpaclient.WpaEventConnected+" - Connection to 02:00:00:00:00:01 completed")
+
+	for i, ch := range []<-chan wpaclient.Event{ch1, ch2} {
+		select {
+		case <-ch:
+		case <-time.After(time.Second * 2):
+			t.Fatalf("event not received by client %d", i+1)
+		}
+	}
+}
+
+func TestProxyAuth(t *testing.T) {
+	_, addr, close := newProxy(t, nil)
+	defer close()
+
+	if _, err := Dial(addr, "wrong", nil); err == nil {
+		t.Error("Dial expect an error with wrong token, got <nil>")
+	}
+}
+
+func TestProxyTLS(t *testing.T) {
+	cert, pool := testCert(t)
+
+	_, addr, close := newProxy(t, &tls.Config{Certificates: []tls.Certificate{cert}})
+	defer close()
+
+	c, err := NewClient(addr, token, &tls.Config{RootCAs: pool, ServerName: "localhost"})
+	if err != nil {
+		t.Fatalf("NewClient not expect an error, got %v", err)
+	}
+	defer c.Close()
+
+	if _, err := c.Execute(wpaclient.CmdPing); err != nil {
+		t.Errorf("Execute not expect an error, got %v", err)
+	}
+
+	if _, err := Dial(addr, token, &tls.Config{ServerName: "localhost"}); err == nil {
+		t.Error("Dial expect an error with untrusted certificate, got <nil>")
+	}
+}
+
+// testCert returns a self signed certificate for localhost and a pool trusting it
+func testCert(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	crt, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(crt)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, pool
+}
+
+func TestProxyEmptyToken(t *testing.T) {
+	cmd, _ := wpaclient.Pipe()
+	defer cmd.Close()
+
+	if _, err := NewWithTransport(cmd, cmd, ""); err == nil {
+		t.Error("NewWithTransport expect an error with empty token, got <nil>")
+	}
+
+	if _, err := New("wlan0", ""); err == nil {
+		t.Error("New expect an error with empty token, got <nil>")
+	}
+}
+
+// brokenTransport fails Receive when "BROKEN" is received
+type brokenTransport struct {
+	wpaclient.Transport
+}
+
+func (t brokenTransport) Receive() ([]byte, error) {
+	b, err := t.Transport.Receive()
+	if err == nil && string(b) == "BROKEN" {
+		return nil, errors.New("broken")
+	}
+
+	return b, err
+}
+
+func TestProxyTimeout(t *testing.T) {
+	defer func(d time.Duration) { Timeout = d }(Timeout)
+	Timeout = time.Millisecond * 200
+
+	cmd, cmdSrv := wpaclient.Pipe()
+	ev, evSrv := wpaclient.Pipe()
+
+	attached := make(chan struct{}, 2)
+	go func() {
+		for {
+			b, err := evSrv.Receive()
+			if err != nil {
+				return
+			}
+			if string(b) == cmdAttach {
+				attached <- struct{}{}
+				evSrv.Send([]byte("OK\n"))
+			}
+		}
+	}()
+
+	p, err := NewWithTransport(cmd, brokenTransport{ev}, token)
+	if err != nil {
+		t.Fatalf("NewWithTransport not expect an error, got %v", err)
+	}
+	defer p.Close()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed, %v", err)
+	}
+	go p.Serve(l)
+
+	// unauthenticated connections are closed
+	nc, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("dial failed, %v", err)
+	}
+	defer nc.Close()
+
+	nc.SetReadDeadline(time.Now().Add(time.Second * 2))
+	if _, err := nc.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("expected unauthenticated connection to be closed, got %v", err)
+	}
+
+	// local control interface does not respond
+	c, err := Dial(l.Addr().String(), token, nil)
+	if err != nil {
+		t.Fatalf("Dial not expect an error, got %v", err)
+	}
+	defer c.Close()
+
+	c.SetDeadline(time.Now().Add(time.Second * 2))
+	if err := c.Send([]byte(wpaclient.CmdPing)); err != nil {
+		t.Fatalf("Send not expect an error, got %v", err)
+	}
+	if _, err := cmdSrv.Receive(); err != nil {
+		t.Fatalf("Receive not expect an error, got %v", err)
+	}
+	if _, err := c.Receive(); err == nil {
+		t.Error("expected connection to be closed when forwarding times out")
+	}
+
+	// late reply is dropped, not sent as the reply of the next command
+	cmdSrv.Send([]byte("LATE\n"))
+
+	c2, err := Dial(l.Addr().String(), token, nil)
+	if err != nil {
+		t.Fatalf("Dial not expect an error, got %v", err)
+	}
+	defer c2.Close()
+
+	c2.SetDeadline(time.Now().Add(time.Second * 2))
+	if err := c2.Send([]byte(wpaclient.CmdPing)); err != nil {
+		t.Fatalf("Send not expect an error, got %v", err)
+	}
+	if b, err := cmdSrv.Receive(); err != nil || string(b) != wpaclient.CmdPing {
+		t.Fatalf("expected %s to be forwarded, got %q, %v", wpaclient.CmdPing, b, err)
+	}
+	cmdSrv.Send([]byte("PONG\n"))
+	if res, err := c2.Receive(); err != nil || string(res) != "PONG\n" {
+		t.Errorf("expected PONG, got %q, %v", res, err)
+	}
+
+	// local monitor is attached again after it fails
+	for i := 0; i < 2; i++ {
+		c, err := Dial(l.Addr().String(), token, nil)
+		if err != nil {
+			t.Fatalf("Dial not expect an error, got %v", err)
+		}
+		defer c.Close()
+
+		c.SetDeadline(time.Now().Add(time.Second * 2))
+		c.Send([]byte(cmdAttach))
+		if res, err := c.Receive(); err != nil || string(res) != "OK\n" {
+			t.Fatalf("expected OK, got %q, %v", res, err)
+		}
+
+		select {
+		case <-attached:
+		case <-time.After(time.Second):
+			t.Fatalf("local monitor not attached %d", i+1)
+		}
+
+		evSrv.Send([]byte("BROKEN"))
+		for j := 0; j < 100; j++ {
+			p.mut.Lock()
+			a := p.attached
+			p.mut.Unlock()
+			if !a {
+				break
+			}
+			time.Sleep(time.Millisecond * 10)
+		}
+	}
+}
+
+func TestProxyAttachReply(t *testing.T) {
+	s, addr, cleanup := newProxy(t, nil)
+	defer cleanup()
+
+	attach := func() *Conn {
+		c, err := Dial(addr, token, nil)
+		if err != nil {
+			t.Fatalf("Dial not expect an error, got %v", err)
+		}
+
+		c.SetDeadline(time.Now().Add(time.Second * 2))
+		if err := c.Send([]byte(cmdAttach)); err != nil {
+			t.Fatalf("Send not expect an error, got %v", err)
+		}
+
+		return c
+	}
+
+	c := attach()
+	defer c.Close()
+	if res, err := c.Receive(); err != nil || string(res) != "OK\n" {
+		t.Fatalf("expected OK, got %q, %v", res, err)
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				s.SendEvent(wpatest.LevelInfo, wpaclient.WpaEventScanStarted)
+				time.Sleep(time.Millisecond)
+			}
+		}
+	}()
+
+	// events are relayed only after the reply of "ATTACH"
+	for i := 0; i < 20; i++ {
+		c := attach()
+		if res, err := c.Receive(); err != nil || string(res) != "OK\n" {
+			t.Errorf("expected OK first, got %q, %v", res, err)
+		}
+		c.Close()
+	}
+}
@@ -0,0 +1,369 @@
+package wpaproxy
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/brlbil/wpaclient"
+)
+
+// WebSocket opcodes, RFC 6455 section 5.2
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xa
+)
+
+// wsGUID is appended to the key of the handshake, RFC 6455 section 1.3
+const wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// WSConn is a wpaclient.Transport sending every control message as a binary
+// WebSocket message
+type WSConn struct {
+	c net.Conn
+	r *bufio.Reader
+	// frames of a client are masked
+	client bool
+
+	// protects writes, pongs are written by Receive
+	wmut sync.Mutex
+}
+
+// DialWebSocket connects to the Proxy served at WebSocket URL u, "ws://" or
+// "wss://", and authenticates with token. cfg is used for "wss://" URLs.
+func DialWebSocket(u, token string, cfg *tls.Config) (*WSConn, error) {
+	pu, err := url.Parse(u)
+	if err != nil {
+		return nil, fmt.Errorf("parse url: %w", err)
+	}
+
+	host := pu.Host
+	if pu.Port() == "" {
+		host = net.JoinHostPort(pu.Hostname(), map[string]string{"ws": "80", "wss": "443"}[pu.Scheme])
+	}
+
+	d := &net.Dialer{Timeout: DialTimeout}
+
+	var nc net.Conn
+	switch pu.Scheme {
+	case "ws":
+		nc, err = d.Dial("tcp", host)
+	case "wss":
+		nc, err = tls.DialWithDialer(d, "tcp", host, cfg)
+	default:
+		return nil, fmt.Errorf("unsupported scheme %q", pu.Scheme)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("dial failed: %w", err)
+	}
+
+	c, err := handshake(nc, pu)
+	if err != nil {
+		nc.Close()
+		return nil, err
+	}
+
+	if err := authenticate(c, token); err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// NewWebSocketClient returns a wpaclient.Client controlling the wpa_supplicant
+// behind the Proxy at WebSocket URL u, commands and events use separate connections
+func NewWebSocketClient(u, token string, cfg *tls.Config, opts ...wpaclient.Option) (*wpaclient.Client, error) {
+	return newClient(func() (wpaclient.Transport, error) { return DialWebSocket(u, token, cfg) }, opts)
+}
+
+// handshake sends the opening handshake of a client over nc
+func handshake(nc net.Conn, u *url.URL) (*WSConn, error) {
+	nc.SetDeadline(time.Now().Add(DialTimeout))
+	defer nc.SetDeadline(time.Time{})
+
+	k := make([]byte, 16)
+	if _, err := rand.Read(k); err != nil {
+		return nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(k)
+
+	if _, err := fmt.Fprintf(nc, "GET %s HTTP/1.1\r\nHost: %s\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+		"Sec-WebSocket-Key: %s\r\nSec-WebSocket-Version: 13\r\n\r\n", u.RequestURI(), u.Host, key); err != nil {
+		return nil, fmt.Errorf("write failed: %w", err)
+	}
+
+	r := bufio.NewReader(nc)
+	res, err := http.ReadResponse(r, &http.Request{Method: http.MethodGet})
+	if err != nil {
+		return nil, fmt.Errorf("read failed: %w", err)
+	}
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusSwitchingProtocols {
+		return nil, fmt.Errorf("handshake failed: %s", res.Status)
+	}
+
+	if res.Header.Get("Sec-WebSocket-Accept") != acceptKey(key) {
+		return nil, errors.New("handshake failed: invalid Sec-WebSocket-Accept")
+	}
+
+	return &WSConn{c: nc, r: r, client: true}, nil
+}
+
+// ServeHTTP upgrades r to a WebSocket connection and serves it like a TCP one.
+// Close closes served connections, but not the http server.
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mut.Lock()
+	closed := p.closed
+	p.mut.Unlock()
+
+	if closed {
+		http.Error(w, "proxy closed", http.StatusServiceUnavailable)
+		return
+	}
+
+	c, err := upgrade(w, r)
+	if err != nil {
+		return
+	}
+
+	p.handle(newRemote(c))
+}
+
+// upgrade answers the opening handshake of r, an error response is written
+// if r is not a valid one
+func upgrade(w http.ResponseWriter, r *http.Request) (*WSConn, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if r.Method != http.MethodGet || key == "" || r.Header.Get("Sec-WebSocket-Version") != "13" ||
+		!headerContains(r.Header, "Upgrade", "websocket") || !headerContains(r.Header, "Connection", "upgrade") {
+		http.Error(w, "websocket handshake expected", http.StatusBadRequest)
+		return nil, errors.New("invalid handshake")
+	}
+
+	h, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "websocket not supported", http.StatusInternalServerError)
+		return nil, errors.New("hijack not supported")
+	}
+
+	nc, rw, err := h.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n\r\n"); err != nil {
+		nc.Close()
+		return nil, err
+	}
+
+	if err := rw.Flush(); err != nil {
+		nc.Close()
+		return nil, err
+	}
+
+	return &WSConn{c: nc, r: rw.Reader}, nil
+}
+
+// headerContains reports whether comma separated values of header k contain v
+func headerContains(h http.Header, k, v string) bool {
+	for _, hv := range h[k] {
+		for _, t := range strings.Split(hv, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), v) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func acceptKey(key string) string {
+	h := sha1.Sum([]byte(key + wsGUID))
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+// Send writes b as a single binary message
+func (c *WSConn) Send(b []byte) error {
+	if len(b) > MaxFrameSize {
+		return fmt.Errorf("message size %d exceeds %d", len(b), MaxFrameSize)
+	}
+
+	return c.write(opBinary, b)
+}
+
+// Receive reads a text or binary message, pings are answered
+func (c *WSConn) Receive() ([]byte, error) {
+	var msg []byte
+	started := false
+
+	for {
+		fin, op, b, err := c.readFrame()
+		if err != nil {
+			return nil, err
+		}
+
+		switch op {
+		case opPing:
+			if err := c.write(opPong, b); err != nil {
+				return nil, err
+			}
+			continue
+		case opPong:
+			continue
+		case opClose:
+			c.write(opClose, b)
+			return nil, io.EOF
+		case opText, opBinary:
+			if started {
+				return nil, errors.New("message started before previous one finished")
+			}
+			started = true
+		case opContinuation:
+			if !started {
+				return nil, errors.New("continuation frame without a message")
+			}
+		default:
+			return nil, fmt.Errorf("unknown opcode %d", op)
+		}
+
+		if len(msg)+len(b) > MaxFrameSize {
+			return nil, fmt.Errorf("message size exceeds %d", MaxFrameSize)
+		}
+		msg = append(msg, b...)
+
+		if fin {
+			return msg, nil
+		}
+	}
+}
+
+// readFrame reads a frame and returns its payload unmasked
+func (c *WSConn) readFrame() (bool, byte, []byte, error) {
+	var h [2]byte
+	if _, err := io.ReadFull(c.r, h[:]); err != nil {
+		return false, 0, nil, err
+	}
+
+	fin, op := h[0]&0x80 != 0, h[0]&0x0f
+	masked, n := h[1]&0x80 != 0, uint64(h[1]&0x7f)
+
+	// frames of clients are masked, frames of servers are not
+	if masked == c.client {
+		return false, 0, nil, errors.New("invalid frame masking")
+	}
+
+	if op >= opClose && (!fin || n > 125) {
+		return false, 0, nil, errors.New("invalid control frame")
+	}
+
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.r, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.r, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+
+	if n > MaxFrameSize {
+		return false, 0, nil, fmt.Errorf("frame size %d exceeds %d", n, MaxFrameSize)
+	}
+
+	var mask [4]byte
+	if masked {
+		if _, err := io.ReadFull(c.r, mask[:]); err != nil {
+			return false, 0, nil, err
+		}
+	}
+
+	b := make([]byte, n)
+	if _, err := io.ReadFull(c.r, b); err != nil {
+		return false, 0, nil, err
+	}
+
+	if masked {
+		for i := range b {
+			b[i] ^= mask[i%4]
+		}
+	}
+
+	return fin, op, b, nil
+}
+
+// write writes b as a single frame with op, masked if c is a client
+func (c *WSConn) write(op byte, b []byte) error {
+	buf := []byte{0x80 | op, 0}
+	switch n := len(b); {
+	case n <= 125:
+		buf[1] = byte(n)
+	case n <= 0xffff:
+		buf[1] = 126
+		buf = append(buf, byte(n>>8), byte(n))
+	default:
+		buf[1] = 127
+		var ext [8]byte
+		binary.BigEndian.PutUint64(ext[:], uint64(n))
+		buf = append(buf, ext[:]...)
+	}
+
+	p := append([]byte{}, b...)
+	if c.client {
+		var mask [4]byte
+		if _, err := rand.Read(mask[:]); err != nil {
+			return err
+		}
+
+		buf[1] |= 0x80
+		buf = append(buf, mask[:]...)
+		for i := range p {
+			p[i] ^= mask[i%4]
+		}
+	}
+
+	c.wmut.Lock()
+	defer c.wmut.Unlock()
+
+	if _, err := c.c.Write(append(buf, p...)); err != nil {
+		return fmt.Errorf("write failed: %w", err)
+	}
+
+	return nil
+}
+
+// Close sends a close frame and closes the connection
+func (c *WSConn) Close() error {
+	c.c.SetWriteDeadline(time.Now().Add(time.Second))
+	c.write(opClose, []byte{0x03, 0xe8})
+
+	return c.c.Close()
+}
+
+// SetDeadline sets read and write deadline of the connection
+func (c *WSConn) SetDeadline(t time.Time) error {
+	return c.c.SetDeadline(t)
+}
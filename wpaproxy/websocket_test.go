@@ -0,0 +1,126 @@
+package wpaproxy
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brlbil/wpaclient"
+	"github.com/brlbil/wpaclient/wpatest"
+)
+
+func TestProxyWebSocket(t *testing.T) {
+	s, err := wpatest.NewServer()
+	if err != nil {
+		t.Fatalf("NewServer failed, %v", err)
+	}
+	defer s.Close()
+
+	p, err := New(s.Addr(), token)
+	if err != nil {
+		t.Fatalf("New not expect an error, got %v", err)
+	}
+	defer p.Close()
+
+	hs := httptest.NewTLSServer(p)
+	defer hs.Close()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(hs.Certificate())
+	u := "wss" + strings.TrimPrefix(hs.URL, "https")
+
+	c, err := NewWebSocketClient(u, token, &tls.Config{RootCAs: pool})
+	if err != nil {
+		t.Fatalf("NewWebSocketClient not expect an error, got %v", err)
+	}
+	defer c.Close()
+
+	if res, err := c.Execute(wpaclient.CmdPing); err != nil || string(res) != "PONG\n" {
+		t.Errorf("expected PONG, got %q, %v", res, err)
+	}
+
+	ch, err := c.Notify(wpaclient.WpaEventConnected)
+	if err != nil {
+		t.Fatalf("Notify not expect an error, got %v", err)
+	}
+
+	s.SendEvent(wpatest.LevelInfo, wpaclient.WpaEventConnected+"- Connection to 02:00:00:00:00:01 completed")
+	select {
+	case <-ch:
+	case <-time.After(time.Second * 2):
+		t.Fatal("event not received")
+	}
+
+	if _, err := DialWebSocket(u, "wrong", &tls.Config{RootCAs: pool}); err == nil {
+		t.Error("DialWebSocket expect an error with wrong token, got <nil>")
+	}
+
+	// plain http requests are rejected
+	res, err := hs.Client().Get(hs.URL)
+	if err != nil {
+		t.Fatalf("Get not expect an error, got %v", err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected %d, got %d", http.StatusBadRequest, res.StatusCode)
+	}
+}
+
+// frame returns a frame with payload b, masked with a zero key if mask is true
+func frame(op byte, fin, mask bool, b string) []byte {
+	h := []byte{op, byte(len(b))}
+	if fin {
+		h[0] |= 0x80
+	}
+	if mask {
+		h[1] |= 0x80
+		h = append(h, 0, 0, 0, 0)
+	}
+
+	return append(h, b...)
+}
+
+func TestWSConn(t *testing.T) {
+	a, b := net.Pipe()
+	client, server := &WSConn{c: a, r: bufio.NewReader(a), client: true}, &WSConn{c: b, r: bufio.NewReader(b)}
+	defer client.Close()
+	defer server.Close()
+
+	client.SetDeadline(time.Now().Add(time.Second * 2))
+	server.SetDeadline(time.Now().Add(time.Second * 2))
+
+	// fragmented message with a ping in between
+	go func() {
+		a.Write(frame(opText, false, true, "PI"))
+		a.Write(frame(opPing, true, true, "p"))
+		a.Write(frame(opContinuation, true, true, "NG"))
+	}()
+
+	pong := make(chan string, 1)
+	go func() {
+		_, op, b, err := client.readFrame()
+		if err != nil || op != opPong {
+			b = nil
+		}
+		pong <- string(b)
+	}()
+
+	if b, err := server.Receive(); err != nil || string(b) != "PING" {
+		t.Errorf("expected PING, got %q, %v", b, err)
+	}
+	if p := <-pong; p != "p" {
+		t.Errorf("expected pong with ping payload, got %q", p)
+	}
+
+	// frames of a client must be masked
+	go a.Write(frame(opBinary, true, false, "x"))
+	if _, err := server.Receive(); err == nil {
+		t.Error("Receive expect an error for unmasked frame, got <nil>")
+	}
+}
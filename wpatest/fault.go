@@ -0,0 +1,75 @@
+package wpatest
+
+import "time"
+
+// FaultKind is the type of an injected failure
+type FaultKind int
+
+// Fault kinds
+const (
+	// FaultFail responds with "FAIL"
+	FaultFail FaultKind = iota
+	// FaultBusy responds with "FAIL-BUSY"
+	FaultBusy
+	// FaultDelay delays the response for Delay
+	FaultDelay
+	// FaultDrop sends no response at all
+	FaultDrop
+	// FaultTruncate cuts the response to Size bytes
+	FaultTruncate
+)
+
+// Fault describes a failure injected into the next response of a command
+type Fault struct {
+	Kind  FaultKind
+	Delay time.Duration
+	Size  int
+}
+
+// Inject queues faults for cmd, every fault is applied to a single response.
+// An empty cmd applies the faults to any command.
+func (s *Server) Inject(cmd string, faults ...Fault) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	s.faults[cmd] = append(s.faults[cmd], faults...)
+}
+
+// fault pops the next fault queued for cmd
+func (s *Server) fault(cmd string) (Fault, bool) {
+	for _, c := range []string{cmd, ""} {
+		if fs := s.faults[c]; len(fs) > 0 {
+			s.faults[c] = fs[1:]
+			return fs[0], true
+		}
+	}
+
+	return Fault{}, false
+}
+
+// response returns the response replacing the one of the command
+func (f Fault) response() string {
+	switch f.Kind {
+	case FaultFail:
+		return "FAIL"
+	case FaultBusy:
+		return "FAIL-BUSY"
+	}
+
+	return ""
+}
+
+// apply applies the fault to res, returns false if response must be dropped.
+// A delayed response is sent by the caller.
+func (f Fault) apply(res string) (string, bool) {
+	switch f.Kind {
+	case FaultDrop:
+		return "", false
+	case FaultTruncate:
+		if f.Size < len(res) {
+			res = res[:f.Size]
+		}
+	}
+
+	return res, true
+}
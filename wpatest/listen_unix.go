@@ -0,0 +1,26 @@
+//go:build !windows
+// +build !windows
+
+package wpatest
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+)
+
+func listen() (net.PacketConn, func(), error) {
+	dir, err := ioutil.TempDir("", "wpatest")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	conn, err := net.ListenPacket("unixgram", filepath.Join(dir, "wpa_ctrl"))
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, nil, err
+	}
+
+	return conn, func() { os.RemoveAll(dir) }, nil
+}
@@ -0,0 +1,12 @@
+package wpatest
+
+import "net"
+
+func listen() (net.PacketConn, func(), error) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return conn, func() {}, nil
+}
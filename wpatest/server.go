@@ -0,0 +1,475 @@
+// Package wpatest provides a programmable fake wpa_supplicant control interface,
+// so applications built on wpaclient can be tested without hardware.
+package wpatest
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/brlbil/wpaclient"
+)
+
+const (
+	cmdAttach    = "ATTACH"
+	cmdDetach    = "DETACH"
+	cmdGetCookie = "GET_COOKIE"
+
+	cookie = "0123456789abcdef"
+)
+
+// Message levels, events with a lower level than the
+// one set by a monitor with "LEVEL" are not sent to it
+const (
+	LevelExcessive = iota
+	LevelMsgDump
+	LevelDebug
+	LevelInfo
+	LevelWarning
+	LevelError
+)
+
+// HandlerFunc handles a command, args are the space separated command arguments.
+// Returned string is sent as the response, a trailing newline is added if missing.
+type HandlerFunc func(args []string) string
+
+// BSS represents a scan result entry
+type BSS struct {
+	BSSID  string
+	SSID   string
+	Freq   int
+	Signal int
+	Flags  []string
+}
+
+// Network represents an entry of the network table
+type Network struct {
+	ID       int
+	Vars     map[string]string
+	Disabled bool
+	// TempDisabled is set after an authentication failure until the
+	// network is enabled or selected again
+	TempDisabled bool
+	Current      bool
+}
+
+// SSID returns the unquoted ssid of the network
+func (n *Network) SSID() string {
+	return strings.Trim(n.Vars["ssid"], "\"")
+}
+
+type monitor struct {
+	addr  net.Addr
+	level int
+}
+
+// Server is a fake wpa_supplicant control interface
+type Server struct {
+	conn net.PacketConn
+	fn   func()
+
+	mut      sync.Mutex
+	handlers map[string]HandlerFunc
+	monitors map[string]*monitor
+	networks []*Network
+	nextID   int
+	bss      []BSS
+	faults   map[string][]Fault
+	sta      *station
+
+	done chan struct{}
+}
+
+// NewServer starts a Server listening on a temporary control socket
+func NewServer() (*Server, error) {
+	conn, fn, err := listen()
+	if err != nil {
+		return nil, fmt.Errorf("listen failed: %w", err)
+	}
+
+	s := &Server{
+		conn:     conn,
+		fn:       fn,
+		handlers: make(map[string]HandlerFunc),
+		monitors: make(map[string]*monitor),
+		faults:   make(map[string][]Fault),
+		done:     make(chan struct{}),
+	}
+	s.sta = newStation(s)
+
+	go s.serve()
+
+	return s, nil
+}
+
+// Addr returns the address to pass to wpaclient.New
+func (s *Server) Addr() string {
+	return s.conn.LocalAddr().String()
+}
+
+// Close stops the server and removes the control socket
+func (s *Server) Close() error {
+	err := s.conn.Close()
+	<-s.done
+	s.fn()
+
+	return err
+}
+
+// Handle registers h for cmd, it overrides the built-in handler
+func (s *Server) Handle(cmd string, h HandlerFunc) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	s.handlers[cmd] = h
+}
+
+// SetResponse makes the server always respond to cmd with res
+func (s *Server) SetResponse(cmd, res string) {
+	s.Handle(cmd, func([]string) string { return res })
+}
+
+// SetScanResults sets the results returned by "SCAN_RESULTS"
+func (s *Server) SetScanResults(bss ...BSS) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	s.bss = append([]BSS{}, bss...)
+}
+
+// Networks returns a copy of the network table
+func (s *Server) Networks() []Network {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	ns := []Network{}
+	for _, n := range s.networks {
+		vars := make(map[string]string, len(n.Vars))
+		for k, v := range n.Vars {
+			vars[k] = v
+		}
+		ns = append(ns, Network{ID: n.ID, Vars: vars, Disabled: n.Disabled,
+			TempDisabled: n.TempDisabled, Current: n.Current})
+	}
+
+	return ns
+}
+
+// Monitors returns the number of attached monitors
+func (s *Server) Monitors() int {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	return len(s.monitors)
+}
+
+// SendEvent sends msg with level to every attached monitor
+func (s *Server) SendEvent(level int, msg string) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	s.sendEvent(level, msg)
+}
+
+func (s *Server) sendEvent(level int, msg string) {
+	b := []byte(fmt.Sprintf("<%d>%s", level, msg))
+	for _, m := range s.monitors {
+		if level < m.level {
+			continue
+		}
+		s.conn.WriteTo(b, m.addr)
+	}
+}
+
+func (s *Server) serve() {
+	defer close(s.done)
+
+	b := make([]byte, 16384)
+	for {
+		n, addr, err := s.conn.ReadFrom(b)
+		if err != nil {
+			if err == io.EOF || strings.Contains(err.Error(), "use of closed network connection") {
+				return
+			}
+			continue
+		}
+
+		s.process(string(b[:n]), addr)
+	}
+}
+
+func (s *Server) process(req string, addr net.Addr) {
+	// UDP control interface clients prefix requests with their cookie
+	if req == cmdGetCookie {
+		s.conn.WriteTo([]byte("COOKIE="+cookie+"\n"), addr)
+		return
+	}
+	req = strings.TrimPrefix(req, "COOKIE="+cookie+" ")
+
+	cmd, args := req, []string{}
+	if i := strings.Index(req, " "); i >= 0 {
+		cmd, args = req[:i], strings.Fields(req[i+1:])
+	}
+
+	s.mut.Lock()
+	f, faulted := s.fault(cmd)
+	h := s.handlers[cmd]
+	s.mut.Unlock()
+
+	var res string
+	switch {
+	case faulted && f.response() != "":
+		res = f.response()
+	case h != nil:
+		res = h(args)
+	default:
+		s.mut.Lock()
+		res = s.builtin(cmd, req, args, addr)
+		s.mut.Unlock()
+	}
+
+	if !strings.HasSuffix(res, "\n") {
+		res += "\n"
+	}
+
+	if faulted {
+		var ok bool
+		if res, ok = f.apply(res); !ok {
+			return
+		}
+
+		// the response is delayed without blocking other requests
+		if f.Kind == FaultDelay {
+			time.AfterFunc(f.Delay, func() { s.conn.WriteTo([]byte(res), addr) })
+			return
+		}
+	}
+
+	s.conn.WriteTo([]byte(res), addr)
+}
+
+func (s *Server) builtin(cmd, req string, args []string, addr net.Addr) string {
+	switch cmd {
+	case wpaclient.CmdPing:
+		return "PONG"
+	case cmdAttach:
+		s.monitors[addr.String()] = &monitor{addr: addr, level: LevelDebug}
+		return "OK"
+	case cmdDetach:
+		if _, ok := s.monitors[addr.String()]; !ok {
+			return "FAIL"
+		}
+		delete(s.monitors, addr.String())
+		return "OK"
+	case wpaclient.CmdLevel:
+		m, ok := s.monitors[addr.String()]
+		if !ok || len(args) != 1 {
+			return "FAIL"
+		}
+		l, err := strconv.Atoi(args[0])
+		if err != nil {
+			return "FAIL"
+		}
+		m.level = l
+		return "OK"
+	case wpaclient.CmdScan:
+		s.sendEvent(LevelInfo, wpaclient.WpaEventScanStarted)
+		go s.scanDone()
+		return "OK"
+	case wpaclient.CmdScanResults:
+		return s.scanResults()
+	case wpaclient.CmdAddNetwork:
+		n := &Network{ID: s.nextID, Vars: make(map[string]string), Disabled: true}
+		s.nextID++
+		s.networks = append(s.networks, n)
+		return strconv.Itoa(n.ID)
+	case wpaclient.CmdRemoveNetwork:
+		if len(args) != 1 {
+			return "Invalid REMOVE_NETWORK command - at least 1 argument is required."
+		}
+		return s.removeNetwork(args[0])
+	case wpaclient.CmdSetNetwork:
+		a := strings.SplitN(strings.TrimPrefix(req, cmd+" "), " ", 3)
+		if len(a) != 3 {
+			return "Invalid SET_NETWORK command: needs three arguments\n" +
+				"(network id, variable name, and value)"
+		}
+		n := s.network(a[0])
+		if n == nil {
+			return "FAIL"
+		}
+		n.Vars[a[1]] = a[2]
+		return "OK"
+	case wpaclient.CmdGetNetwork:
+		if len(args) != 2 {
+			return "Invalid GET_NETWORK command: needs two arguments\n" +
+				"(network id and variable name)"
+		}
+		n := s.network(args[0])
+		if n == nil {
+			return "FAIL"
+		}
+		v, ok := n.Vars[args[1]]
+		if !ok {
+			return "FAIL"
+		}
+		return v
+	case wpaclient.CmdEnableNetwork, wpaclient.CmdDisableNetwork:
+		if len(args) < 1 {
+			return "FAIL"
+		}
+		return s.enableNetwork(args[0], cmd == wpaclient.CmdEnableNetwork)
+	case wpaclient.CmdSelectNetwork:
+		if len(args) < 1 {
+			return "FAIL"
+		}
+		return s.selectNetwork(args[0])
+	case wpaclient.CmdListNetworks:
+		return s.listNetworks()
+	}
+
+	if res, ok := s.sta.process(cmd, args); ok {
+		return res
+	}
+
+	return "UNKNOWN COMMAND"
+}
+
+func (s *Server) scanDone() {
+	time.Sleep(time.Millisecond)
+
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	s.sendEvent(LevelInfo, wpaclient.WpaEventScanResults)
+	for _, b := range s.bss {
+		for _, f := range b.Flags {
+			if f == "WPS" {
+				s.sendEvent(LevelInfo, wpaclient.WpsEventApAvailable)
+				return
+			}
+		}
+	}
+}
+
+func (s *Server) scanResults() string {
+	lines := []string{"bssid / frequency / signal level / flags / ssid"}
+	bss := append([]BSS{}, s.bss...)
+	for _, ap := range s.sta.aps {
+		bss = append(bss, ap.bss())
+	}
+
+	for _, b := range bss {
+		flags := ""
+		if len(b.Flags) > 0 {
+			flags = "[" + strings.Join(b.Flags, "][") + "]"
+		}
+		lines = append(lines, fmt.Sprintf("%s\t%d\t%d\t%s\t%s", b.BSSID, b.Freq, b.Signal, flags, b.SSID))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func (s *Server) network(id string) *Network {
+	i, err := strconv.Atoi(id)
+	if err != nil {
+		return nil
+	}
+
+	for _, n := range s.networks {
+		if n.ID == i {
+			return n
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) removeNetwork(id string) string {
+	if id == "all" {
+		s.networks = nil
+		s.sta.networkRemoved(-1)
+		return "OK"
+	}
+
+	n := s.network(id)
+	if n == nil {
+		return "FAIL"
+	}
+
+	for i, nt := range s.networks {
+		if nt == n {
+			s.networks = append(s.networks[:i], s.networks[i+1:]...)
+			break
+		}
+	}
+	s.sta.networkRemoved(n.ID)
+
+	return "OK"
+}
+
+func (s *Server) enableNetwork(id string, enable bool) string {
+	if id == "all" {
+		for _, n := range s.networks {
+			n.Disabled = !enable
+			n.TempDisabled = n.TempDisabled && !enable
+		}
+		return "OK"
+	}
+
+	n := s.network(id)
+	if n == nil {
+		return "FAIL"
+	}
+	n.Disabled = !enable
+	n.TempDisabled = n.TempDisabled && !enable
+
+	return "OK"
+}
+
+func (s *Server) selectNetwork(id string) string {
+	n := s.network(id)
+	if n == nil {
+		return "FAIL"
+	}
+
+	for _, nt := range s.networks {
+		nt.Disabled = nt != n
+	}
+	n.TempDisabled = false
+	s.sta.selected(n)
+
+	return "OK"
+}
+
+func (s *Server) listNetworks() string {
+	sort.Slice(s.networks, func(i, j int) bool { return s.networks[i].ID < s.networks[j].ID })
+
+	lines := []string{"network id / ssid / bssid / flags"}
+	for _, n := range s.networks {
+		bssid := n.Vars["bssid"]
+		if bssid == "" {
+			bssid = "any"
+		}
+
+		flags := ""
+		if n.Disabled {
+			flags = "[DISABLED]"
+		}
+		if n.TempDisabled {
+			flags += "[TEMP-DISABLED]"
+		}
+		if n.Current {
+			flags += "[CURRENT]"
+		}
+
+		lines = append(lines, fmt.Sprintf("%d\t%s\t%s\t%s", n.ID, n.SSID(), bssid, flags))
+	}
+
+	return strings.Join(lines, "\n")
+}
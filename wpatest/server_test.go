@@ -0,0 +1,238 @@
+package wpatest_test
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brlbil/wpaclient"
+	"github.com/brlbil/wpaclient/wpatest"
+)
+
+func newClient(t *testing.T) (*wpatest.Server, *wpaclient.Client, func()) {
+	s, err := wpatest.NewServer()
+	if err != nil {
+		t.Fatalf("NewServer failed, %v", err)
+	}
+
+	c, err := wpaclient.New(s.Addr())
+	if err != nil {
+		s.Close()
+		t.Fatalf("New Client failed, %v", err)
+	}
+
+	return s, c, func() {
+		c.Close()
+		s.Close()
+	}
+}
+
+func TestNetworkTable(t *testing.T) {
+	s, c, close := newClient(t)
+	defer close()
+
+	tests := []struct {
+		cmd  string
+		args []string
+		exp  string
+		err  error
+	}{
+		{cmd: wpaclient.CmdAddNetwork, exp: "0"},
+		{cmd: wpaclient.CmdAddNetwork, exp: "1"},
+		{cmd: wpaclient.CmdSetNetwork, args: []string{"0", "ssid", `"my net"`}, exp: "OK"},
+		{cmd: wpaclient.CmdSetNetwork, args: []string{"0", "psk", `"secret"`}, exp: "OK"},
+		{cmd: wpaclient.CmdSetNetwork, args: []string{"5", "ssid", `"x"`}, err: wpaclient.ErrCmdFailed},
+		{cmd: wpaclient.CmdGetNetwork, args: []string{"0", "ssid"}, exp: `"my net"`},
+		{cmd: wpaclient.CmdGetNetwork, args: []string{"0", "bssid"}, err: wpaclient.ErrCmdFailed},
+		{cmd: wpaclient.CmdEnableNetwork, args: []string{"0"}, exp: "OK"},
+		{cmd: wpaclient.CmdRemoveNetwork, args: []string{"1"}, exp: "OK"},
+		{cmd: wpaclient.CmdRemoveNetwork, args: []string{"1"}, err: wpaclient.ErrCmdFailed},
+	}
+
+	for _, tt := range tests {
+		res, err := c.Execute(tt.cmd, tt.args...)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("Execute(%s %v) expect error %v, got %v", tt.cmd, tt.args, tt.err, err)
+		}
+
+		if tt.exp != "" && string(res) != tt.exp+"\n" {
+			t.Errorf("Execute(%s %v) expect %q, got %q", tt.cmd, tt.args, tt.exp, res)
+		}
+	}
+
+	ns, err := c.ListNetworks()
+	if err != nil {
+		t.Fatalf("ListNetworks not expect an error, got %v", err)
+	}
+
+	exp := []wpaclient.Network{{ID: 0, SSID: "my net", BSSID: "any", Flags: []string{""}}}
+	if !reflect.DeepEqual(ns, exp) {
+		t.Errorf("ListNetworks expect %#v, got %#v", exp, ns)
+	}
+
+	if n := s.Networks(); len(n) != 1 || n[0].Vars["psk"] != `"secret"` {
+		t.Errorf("Networks expect psk to be set, got %#v", n)
+	}
+}
+
+func TestScanResults(t *testing.T) {
+	s, c, close := newClient(t)
+	defer close()
+
+	s.SetScanResults(wpatest.BSS{
+		BSSID:  "00:1f:1f:37:42:d9",
+		SSID:   "AP1",
+		Freq:   2442,
+		Signal: -37,
+		Flags:  []string{"WPA2-PSK-CCMP", "WPS", "ESS"},
+	})
+
+	aps, err := c.Scan()
+	if err != nil {
+		t.Fatalf("Scan not expect an error, got %v", err)
+	}
+
+	if len(aps) != 1 || aps[0].SSID != "AP1" || aps[0].SignalStrength != -37 {
+		t.Errorf("Scan returned unexpected result %#v", aps)
+	}
+}
+
+func TestHandlerAndEvents(t *testing.T) {
+	s, c, close := newClient(t)
+	defer close()
+
+	s.Handle(wpaclient.CmdStatus, func(args []string) string {
+		return "wpa_state=COMPLETED\nssid=" + strings.Join(args, "")
+	})
+
+	res, err := c.Execute(wpaclient.CmdStatus, "AP0")
+	if err != nil || string(res) != "wpa_state=COMPLETED\nssid=AP0\n" {
+		t.Errorf("Execute returned %q, %v", res, err)
+	}
+
+	ch, err := c.Notify(wpaclient.WpaEventConnected)
+	if err != nil {
+		t.Fatalf("Notify not expect an error, got %v", err)
+	}
+
+	// second monitor on a raw socket, a client uses both of its local socket files
+	mon, err := net.DialUnix("unixgram", &net.UnixAddr{Name: s.Addr() + "_mon", Net: "unixgram"},
+		&net.UnixAddr{Name: s.Addr(), Net: "unixgram"})
+	if err != nil {
+		t.Fatalf("Dial failed, %v", err)
+	}
+	defer mon.Close()
+
+	b := make([]byte, 4096)
+	mon.SetDeadline(time.Now().Add(time.Second))
+	if _, err := mon.Write([]byte("ATTACH")); err != nil {
+		t.Fatalf("Write failed, %v", err)
+	}
+	if n, err := mon.Read(b); err != nil || string(b[:n]) != "OK\n" {
+		t.Fatalf("ATTACH expect OK, got %q, %v", b[:n], err)
+	}
+
+	if s.Monitors() != 2 {
+		t.Errorf("expect 2 monitors, got %d", s.Monitors())
+	}
+
+	s.SendEvent(wpatest.LevelInfo, wpaclient.WpaEventConnected+"- Connection to 00:1f:1f:37:42:d9 completed")
+
+	msg := wpaclient.WpaEventConnected + "- Connection to 00:1f:1f:37:42:d9 completed"
+	select {
+	case ev := <-ch:
+		if ev.Sev != wpatest.LevelInfo || ev.Message != msg {
+			t.Errorf("unexpected event %#v", ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("event not received")
+	}
+
+	if n, err := mon.Read(b); err != nil || string(b[:n]) != "<3>"+msg {
+		t.Errorf("monitor expect event, got %q, %v", b[:n], err)
+	}
+
+	// filtered by level
+	if _, err := mon.Write([]byte("LEVEL 4")); err != nil {
+		t.Fatalf("Write failed, %v", err)
+	}
+	if n, err := mon.Read(b); err != nil || string(b[:n]) != "OK\n" {
+		t.Fatalf("LEVEL expect OK, got %q, %v", b[:n], err)
+	}
+
+	s.SendEvent(wpatest.LevelInfo, msg)
+	s.SendEvent(wpatest.LevelError, msg)
+	if n, err := mon.Read(b); err != nil || string(b[:n]) != "<5>"+msg {
+		t.Errorf("monitor expect event, got %q, %v", b[:n], err)
+	}
+}
+
+func TestInject(t *testing.T) {
+	s, c, cleanup := newClient(t)
+	defer cleanup()
+
+	s.Inject(wpaclient.CmdPing, wpatest.Fault{Kind: wpatest.FaultFail})
+	s.Inject("", wpatest.Fault{Kind: wpatest.FaultBusy})
+	s.Inject(wpaclient.CmdListNetworks, wpatest.Fault{Kind: wpatest.FaultTruncate, Size: 7})
+	s.Inject(wpaclient.CmdPing, wpatest.Fault{Kind: wpatest.FaultDelay, Delay: time.Millisecond * 20})
+
+	if _, err := c.Execute(wpaclient.CmdPing); !errors.Is(err, wpaclient.ErrCmdFailed) {
+		t.Errorf("expect error %v, got %v", wpaclient.ErrCmdFailed, err)
+	}
+
+	if res, _ := c.Execute(wpaclient.CmdStatus); string(res) != "FAIL-BUSY\n" {
+		t.Errorf("expect FAIL-BUSY, got %q", res)
+	}
+
+	if res, _ := c.Execute(wpaclient.CmdListNetworks); string(res) != "network" {
+		t.Errorf("expect truncated response, got %q", res)
+	}
+
+	st := time.Now()
+	if _, err := c.Execute(wpaclient.CmdPing); err != nil {
+		t.Errorf("Execute not expect an error, got %v", err)
+	}
+	if d := time.Since(st); d < time.Millisecond*20 {
+		t.Errorf("expect response to be delayed, took %s", d)
+	}
+
+	// a delayed response does not block other clients
+	c2, err := wpaclient.New(s.Addr())
+	if err != nil {
+		t.Fatalf("New Client failed, %v", err)
+	}
+	defer c2.Close()
+
+	s.Inject(wpaclient.CmdPing, wpatest.Fault{Kind: wpatest.FaultDelay, Delay: time.Millisecond * 200})
+	delayed := make(chan struct{})
+	go func() {
+		c.Execute(wpaclient.CmdPing)
+		close(delayed)
+	}()
+
+	time.Sleep(time.Millisecond * 20)
+	st = time.Now()
+	if _, err := c2.Execute(wpaclient.CmdPing); err != nil {
+		t.Errorf("Execute not expect an error, got %v", err)
+	}
+	if d := time.Since(st); d >= time.Millisecond*100 {
+		t.Errorf("expect response not to wait for the delayed one, took %s", d)
+	}
+	<-delayed
+
+	s.Inject(wpaclient.CmdPing, wpatest.Fault{Kind: wpatest.FaultDrop})
+	done := make(chan struct{})
+	go func() {
+		c.Execute(wpaclient.CmdPing)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Error("expect response to be dropped")
+	case <-time.After(time.Millisecond * 20):
+	}
+}
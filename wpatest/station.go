@@ -0,0 +1,496 @@
+package wpatest
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brlbil/wpaclient"
+)
+
+// Station states as reported in "wpa_state" of STATUS
+const (
+	StateDisconnected   = "DISCONNECTED"
+	StateInactive       = "INACTIVE"
+	StateScanning       = "SCANNING"
+	StateAssociating    = "ASSOCIATING"
+	StateAssociated     = "ASSOCIATED"
+	State4WayHandshake  = "4WAY_HANDSHAKE"
+	StateGroupHandshake = "GROUP_HANDSHAKE"
+	StateCompleted      = "COMPLETED"
+)
+
+// numeric values used in CTRL-EVENT-STATE-CHANGE
+var stateNum = map[string]int{
+	StateDisconnected:   0,
+	StateInactive:       2,
+	StateScanning:       3,
+	StateAssociating:    5,
+	StateAssociated:     6,
+	State4WayHandshake:  7,
+	StateGroupHandshake: 8,
+	StateCompleted:      9,
+}
+
+// Disconnect reason codes used by the simulated station
+const (
+	ReasonUnspecified        = 1
+	ReasonDeauthLeaving      = 3
+	ReasonDisassocInactivity = 4
+	Reason4WayTimeout        = 15
+)
+
+// StepDelay is the delay between the events of a simulated state transition
+var StepDelay = time.Millisecond
+
+// Address is the MAC address reported by the simulated station
+var Address = "02:00:00:00:01:00"
+
+// AP represents a virtual access point the simulated station can connect to,
+// an empty PSK makes it an open network. Networks may set psk to the quoted
+// PSK or to the hex key derived from it.
+type AP struct {
+	BSSID  string
+	SSID   string
+	Freq   int
+	Signal int
+	PSK    string
+}
+
+func (ap *AP) bss() BSS {
+	flags := []string{"ESS"}
+	if ap.PSK != "" {
+		flags = []string{"WPA2-PSK-CCMP", "ESS"}
+	}
+
+	return BSS{BSSID: ap.BSSID, SSID: ap.SSID, Freq: ap.Freq, Signal: ap.Signal, Flags: flags}
+}
+
+// match reports whether psk, the value of network psk parameter, is the key of ap.
+// It is either the quoted passphrase or the 64 hex digit key derived from it.
+func (ap *AP) match(psk string) bool {
+	if psk == `"`+ap.PSK+`"` {
+		return true
+	}
+
+	key, err := hex.DecodeString(psk)
+	if err != nil || len(key) != 32 {
+		return false
+	}
+
+	return hmac.Equal(key, pbkdf2([]byte(ap.PSK), []byte(ap.SSID), 4096, 32))
+}
+
+// pbkdf2 derives a key of size bytes with PBKDF2-HMAC-SHA1, as WPA does from a passphrase
+func pbkdf2(password, salt []byte, iter, size int) []byte {
+	prf := hmac.New(sha1.New, password)
+	key := []byte{}
+	for block := 1; len(key) < size; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		prf.Write([]byte{byte(block >> 24), byte(block >> 16), byte(block >> 8), byte(block)})
+		u := prf.Sum(nil)
+
+		t := append([]byte{}, u...)
+		for i := 1; i < iter; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		key = append(key, t...)
+	}
+
+	return key[:size]
+}
+
+// ErrUnknownAP returned when no virtual AP has the given BSSID
+var ErrUnknownAP = errors.New("unknown AP")
+
+// ErrNotConnected returned when station must be connected for the operation
+var ErrNotConnected = errors.New("not connected")
+
+type station struct {
+	srv   *Server
+	aps   []*AP
+	state string
+	ap    *AP
+	nt    *Network
+	// last selected network, used by RECONNECT
+	sel *Network
+	// incremented on every transition, stale transitions are abandoned
+	gen int
+}
+
+func newStation(s *Server) *station {
+	return &station{srv: s, state: StateDisconnected}
+}
+
+// AddAP adds virtual access points, they also appear in scan results
+func (s *Server) AddAP(aps ...AP) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	for i := range aps {
+		ap := aps[i]
+		s.sta.aps = append(s.sta.aps, &ap)
+	}
+}
+
+// RemoveAP removes a virtual access point,
+// station is disconnected if it is connected to it
+func (s *Server) RemoveAP(bssid string) error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	st := s.sta
+	for i, ap := range st.aps {
+		if !strings.EqualFold(ap.BSSID, bssid) {
+			continue
+		}
+
+		st.aps = append(st.aps[:i], st.aps[i+1:]...)
+		if st.ap == ap {
+			st.disconnect(ReasonDisassocInactivity, false)
+		}
+		return nil
+	}
+
+	return ErrUnknownAP
+}
+
+// SetSignal changes the signal level of an AP,
+// CTRL-EVENT-SIGNAL-CHANGE is sent if station is connected to it
+func (s *Server) SetSignal(bssid string, signal int) error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	ap := s.sta.find(bssid)
+	if ap == nil {
+		return ErrUnknownAP
+	}
+
+	above := 0
+	if signal > ap.Signal {
+		above = 1
+	}
+	ap.Signal = signal
+
+	if s.sta.state == StateCompleted && s.sta.ap == ap {
+		s.sendEvent(LevelInfo, fmt.Sprintf("%sabove=%d signal=%d noise=-95 txrate=65000",
+			wpaclient.WpaEventSignalChange, above, signal))
+	}
+
+	return nil
+}
+
+// Roam moves the connected station to the AP with bssid
+func (s *Server) Roam(bssid string) error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	return s.sta.roam(bssid)
+}
+
+// Disconnect simulates the AP disconnecting the station with reason
+func (s *Server) Disconnect(reason int) error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if s.sta.ap == nil {
+		return ErrNotConnected
+	}
+	s.sta.disconnect(reason, false)
+
+	return nil
+}
+
+// State returns the current state of the simulated station
+func (s *Server) State() string {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	return s.sta.state
+}
+
+func (st *station) find(bssid string) *AP {
+	for _, ap := range st.aps {
+		if strings.EqualFold(ap.BSSID, bssid) {
+			return ap
+		}
+	}
+
+	return nil
+}
+
+// process handles station related commands
+func (st *station) process(cmd string, args []string) (string, bool) {
+	switch cmd {
+	case wpaclient.CmdStatus:
+		return st.status(), true
+	case wpaclient.CmdSignalPoll:
+		if st.state != StateCompleted {
+			return "FAIL", true
+		}
+		return fmt.Sprintf("RSSI=%d\nLINKSPEED=65\nNOISE=9999\nFREQUENCY=%d", st.ap.Signal, st.ap.Freq), true
+	case wpaclient.CmdDisconnect:
+		if st.nt != nil {
+			st.disconnect(ReasonDeauthLeaving, true)
+		}
+		st.gen++
+		st.setState(StateDisconnected)
+		return "OK", true
+	case wpaclient.CmdReconnect:
+		if st.sel == nil || st.state != StateDisconnected {
+			return "OK", true
+		}
+		st.selected(st.sel)
+		return "OK", true
+	case wpaclient.CmdRoam:
+		if len(args) != 1 {
+			return "FAIL", true
+		}
+		if err := st.roam(args[0]); err != nil {
+			return "FAIL", true
+		}
+		return "OK", true
+	}
+
+	return "", false
+}
+
+func (st *station) status() string {
+	lines := []string{}
+	if st.ap != nil && st.nt != nil {
+		lines = append(lines,
+			"bssid="+st.ap.BSSID,
+			"freq="+strconv.Itoa(st.ap.Freq),
+			"ssid="+st.ap.SSID,
+			"id="+strconv.Itoa(st.nt.ID),
+			"mode=station",
+		)
+		if st.state == StateCompleted {
+			km := "NONE"
+			if st.ap.PSK != "" {
+				km = "WPA2-PSK"
+				lines = append(lines, "pairwise_cipher=CCMP", "group_cipher=CCMP")
+			}
+			lines = append(lines, "key_mgmt="+km)
+		}
+	}
+
+	return strings.Join(append(lines, "wpa_state="+st.state, "address="+Address), "\n")
+}
+
+func (st *station) setState(state string) {
+	if st.state == state {
+		return
+	}
+	st.state = state
+
+	id, bssid, ssid := -1, "00:00:00:00:00:00", ""
+	if st.nt != nil {
+		id = st.nt.ID
+	}
+	if st.ap != nil {
+		bssid, ssid = st.ap.BSSID, st.ap.SSID
+	}
+
+	st.srv.sendEvent(LevelDebug, fmt.Sprintf("%sid=%d state=%d BSSID=%s SSID=%s",
+		wpaclient.WpaEventStateChange, id, stateNum[state], bssid, ssid))
+}
+
+// selected starts connecting to network n
+func (st *station) selected(n *Network) {
+	if st.nt != nil && st.nt != n && st.ap != nil {
+		st.disconnect(ReasonDeauthLeaving, true)
+	}
+
+	st.sel = n
+	st.nt = n
+	st.ap = nil
+	st.gen++
+	gen := st.gen
+
+	st.setState(StateScanning)
+	st.srv.sendEvent(LevelInfo, wpaclient.WpaEventScanStarted)
+
+	go st.connect(gen, n)
+}
+
+// step waits for StepDelay and locks server,
+// returns false if the transition is abandoned
+func (st *station) step(gen int) bool {
+	time.Sleep(StepDelay)
+	st.srv.mut.Lock()
+
+	if gen != st.gen {
+		st.srv.mut.Unlock()
+		return false
+	}
+
+	return true
+}
+
+// candidate returns the strongest AP matching network n
+func (st *station) candidate(n *Network) *AP {
+	var best *AP
+
+	bssid := n.Vars["bssid"]
+	open := n.Vars["key_mgmt"] == "NONE"
+	for _, ap := range st.aps {
+		if ap.SSID != n.SSID() || (bssid != "" && !strings.EqualFold(bssid, ap.BSSID)) {
+			continue
+		}
+
+		if open != (ap.PSK == "") {
+			continue
+		}
+
+		if best == nil || ap.Signal > best.Signal {
+			best = ap
+		}
+	}
+
+	return best
+}
+
+func (st *station) connect(gen int, n *Network) {
+	if !st.step(gen) {
+		return
+	}
+
+	st.srv.sendEvent(LevelInfo, wpaclient.WpaEventScanResults)
+	ap := st.candidate(n)
+	if ap == nil {
+		st.srv.sendEvent(LevelInfo, wpaclient.WpaEventNetworkNotFound)
+		st.srv.mut.Unlock()
+		return
+	}
+
+	st.ap = ap
+	st.srv.sendEvent(LevelInfo, fmt.Sprintf("Trying to associate with %s (SSID='%s' freq=%d MHz)",
+		ap.BSSID, ap.SSID, ap.Freq))
+	st.setState(StateAssociating)
+	st.srv.mut.Unlock()
+
+	st.associate(gen, n, ap)
+}
+
+// associate runs association and handshake with ap
+func (st *station) associate(gen int, n *Network, ap *AP) {
+	if !st.step(gen) {
+		return
+	}
+
+	st.srv.sendEvent(LevelInfo, "Associated with "+ap.BSSID)
+	st.setState(StateAssociated)
+
+	if ap.PSK == "" {
+		st.completed(n, ap)
+		st.srv.mut.Unlock()
+		return
+	}
+
+	st.setState(State4WayHandshake)
+	st.srv.mut.Unlock()
+
+	if !st.step(gen) {
+		return
+	}
+	defer st.srv.mut.Unlock()
+
+	if !ap.match(n.Vars["psk"]) {
+		// like wpa_supplicant the network is disabled before disconnecting
+		st.srv.sendEvent(LevelInfo, "WPA: 4-Way Handshake failed - pre-shared key may be incorrect")
+		st.srv.sendEvent(LevelInfo, fmt.Sprintf("%sid=%d ssid=\"%s\" auth_failures=1 duration=10 reason=WRONG_KEY",
+			wpaclient.WpaEventTempDisabled, n.ID, n.SSID()))
+		n.TempDisabled = true
+		st.disconnect(Reason4WayTimeout, true)
+		return
+	}
+
+	st.setState(StateGroupHandshake)
+	st.completed(n, ap)
+}
+
+func (st *station) completed(n *Network, ap *AP) {
+	st.setState(StateCompleted)
+	n.Current = true
+	st.srv.sendEvent(LevelInfo, fmt.Sprintf("%s- Connection to %s completed [id=%d id_str=]",
+		wpaclient.WpaEventConnected, ap.BSSID, n.ID))
+}
+
+// disconnect sends CTRL-EVENT-DISCONNECTED and resets the connection
+func (st *station) disconnect(reason int, local bool) {
+	bssid := "00:00:00:00:00:00"
+	if st.ap != nil {
+		bssid = st.ap.BSSID
+	}
+
+	msg := fmt.Sprintf("%sbssid=%s reason=%d", wpaclient.WpaEventDisconnected, bssid, reason)
+	if local {
+		msg += " locally_generated=1"
+	}
+	st.srv.sendEvent(LevelInfo, msg)
+
+	if st.nt != nil {
+		st.nt.Current = false
+	}
+	st.gen++
+	st.setState(StateDisconnected)
+	st.ap = nil
+	st.nt = nil
+}
+
+func (st *station) roam(bssid string) error {
+	if st.state != StateCompleted {
+		return ErrNotConnected
+	}
+
+	ap := st.find(bssid)
+	if ap == nil || ap.SSID != st.ap.SSID {
+		return ErrUnknownAP
+	}
+
+	n := st.nt
+	st.ap = ap
+	st.gen++
+	gen := st.gen
+
+	st.srv.sendEvent(LevelInfo, fmt.Sprintf("Trying to associate with %s (SSID='%s' freq=%d MHz)",
+		ap.BSSID, ap.SSID, ap.Freq))
+	st.setState(StateAssociating)
+
+	go st.associate(gen, n, ap)
+
+	return nil
+}
+
+// networkRemoved disconnects station if connected network is removed,
+// id -1 means all networks are removed
+func (st *station) networkRemoved(id int) {
+	if st.sel != nil && (id < 0 || st.sel.ID == id) {
+		st.sel = nil
+	}
+
+	if st.nt == nil || (id >= 0 && st.nt.ID != id) {
+		return
+	}
+
+	if st.ap != nil {
+		st.disconnect(ReasonDeauthLeaving, true)
+		return
+	}
+
+	st.gen++
+	st.nt = nil
+	st.setState(StateDisconnected)
+}
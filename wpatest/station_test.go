@@ -0,0 +1,215 @@
+package wpatest_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brlbil/wpaclient"
+	"github.com/brlbil/wpaclient/wpatest"
+)
+
+// events collects event messages from ch until one starts with last
+func events(t *testing.T, ch <-chan wpaclient.Event, last string) []string {
+	t.Helper()
+
+	evs := []string{}
+	for {
+		select {
+		case ev := <-ch:
+			evs = append(evs, ev.Message)
+			if strings.HasPrefix(ev.Message, last) {
+				return evs
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%s not received, got %v", last, evs)
+		}
+	}
+}
+
+func addNetwork(t *testing.T, c *wpaclient.Client, ssid, psk string) string {
+	t.Helper()
+
+	res, err := c.Execute(wpaclient.CmdAddNetwork)
+	if err != nil {
+		t.Fatalf("ADD_NETWORK failed, %v", err)
+	}
+	id := strings.TrimSpace(string(res))
+
+	if _, err := c.Execute(wpaclient.CmdSetNetwork, id, "ssid", `"`+ssid+`"`); err != nil {
+		t.Fatalf("SET_NETWORK failed, %v", err)
+	}
+	if _, err := c.Execute(wpaclient.CmdSetNetwork, id, "psk", `"`+psk+`"`); err != nil {
+		t.Fatalf("SET_NETWORK failed, %v", err)
+	}
+
+	return id
+}
+
+func status(t *testing.T, c *wpaclient.Client) string {
+	t.Helper()
+
+	res, err := c.Execute(wpaclient.CmdStatus)
+	if err != nil {
+		t.Fatalf("STATUS failed, %v", err)
+	}
+
+	return string(res)
+}
+
+func TestStationConnect(t *testing.T) {
+	s, c, close := newClient(t)
+	defer close()
+
+	s.AddAP(
+		wpatest.AP{BSSID: "00:1f:1f:37:42:d9", SSID: "home", Freq: 2442, Signal: -60, PSK: "secret"},
+		wpatest.AP{BSSID: "00:1f:1f:37:42:da", SSID: "home", Freq: 5180, Signal: -40, PSK: "secret"},
+	)
+
+	ch, err := c.Notify()
+	if err != nil {
+		t.Fatalf("Notify not expect an error, got %v", err)
+	}
+
+	id := addNetwork(t, c, "home", "secret")
+	if _, err := c.Execute(wpaclient.CmdSelectNetwork, id); err != nil {
+		t.Fatalf("SELECT_NETWORK failed, %v", err)
+	}
+
+	evs := events(t, ch, wpaclient.WpaEventConnected)
+	states := []string{}
+	for _, ev := range evs {
+		if strings.HasPrefix(ev, wpaclient.WpaEventStateChange) {
+			states = append(states, strings.Fields(ev)[2])
+		}
+	}
+
+	exp := "state=3 state=5 state=6 state=7 state=8 state=9"
+	if strings.Join(states, " ") != exp {
+		t.Errorf("expect state changes %s, got %v", exp, states)
+	}
+
+	if !strings.Contains(evs[len(evs)-1], "00:1f:1f:37:42:da") {
+		t.Errorf("expect to connect strongest AP, got %s", evs[len(evs)-1])
+	}
+
+	st := status(t, c)
+	for _, l := range []string{"wpa_state=COMPLETED", "bssid=00:1f:1f:37:42:da", "ssid=home", "key_mgmt=WPA2-PSK"} {
+		if !strings.Contains(st, l+"\n") {
+			t.Errorf("expect STATUS to contain %s, got %s", l, st)
+		}
+	}
+
+	ns, _ := c.ListNetworks()
+	if len(ns) != 1 || ns[0].Flags[0] != "CURRENT" {
+		t.Errorf("expect network to be current, got %v", ns)
+	}
+
+	// roam and lose signal
+	if err := s.Roam("00:1f:1f:37:42:d9"); err != nil {
+		t.Fatalf("Roam not expect an error, got %v", err)
+	}
+	evs = events(t, ch, wpaclient.WpaEventConnected)
+	if !strings.Contains(evs[len(evs)-1], "00:1f:1f:37:42:d9") {
+		t.Errorf("expect to roam to 00:1f:1f:37:42:d9, got %s", evs[len(evs)-1])
+	}
+
+	if err := s.SetSignal("00:1f:1f:37:42:d9", -80); err != nil {
+		t.Fatalf("SetSignal not expect an error, got %v", err)
+	}
+	evs = events(t, ch, wpaclient.WpaEventSignalChange)
+	if !strings.Contains(evs[0], "above=0 signal=-80") {
+		t.Errorf("unexpected signal change event %s", evs[0])
+	}
+
+	if res, err := c.Execute(wpaclient.CmdSignalPoll); err != nil || !strings.HasPrefix(string(res), "RSSI=-80\n") {
+		t.Errorf("unexpected SIGNAL_POLL response %q, %v", res, err)
+	}
+
+	if err := s.Disconnect(wpatest.ReasonDisassocInactivity); err != nil {
+		t.Fatalf("Disconnect not expect an error, got %v", err)
+	}
+	evs = events(t, ch, wpaclient.WpaEventDisconnected)
+	if evs[0] != wpaclient.WpaEventDisconnected+"bssid=00:1f:1f:37:42:d9 reason=4" {
+		t.Errorf("unexpected disconnect event %s", evs[0])
+	}
+
+	if st := status(t, c); st != "wpa_state=DISCONNECTED\naddress="+wpatest.Address+"\n" {
+		t.Errorf("unexpected STATUS %q", st)
+	}
+
+	if _, err := c.Execute(wpaclient.CmdReconnect); err != nil {
+		t.Fatalf("RECONNECT failed, %v", err)
+	}
+	events(t, ch, wpaclient.WpaEventConnected)
+
+	if _, err := c.Execute(wpaclient.CmdDisconnect); err != nil {
+		t.Fatalf("DISCONNECT failed, %v", err)
+	}
+	evs = events(t, ch, wpaclient.WpaEventDisconnected)
+	if !strings.HasSuffix(evs[0], "reason=3 locally_generated=1") {
+		t.Errorf("unexpected disconnect event %s", evs[0])
+	}
+}
+
+func TestStationWrongPSK(t *testing.T) {
+	s, c, close := newClient(t)
+	defer close()
+
+	s.AddAP(wpatest.AP{BSSID: "00:1f:1f:37:42:d9", SSID: "home", Freq: 2442, Signal: -60, PSK: "secret"})
+
+	ch, err := c.Notify()
+	if err != nil {
+		t.Fatalf("Notify not expect an error, got %v", err)
+	}
+
+	id := addNetwork(t, c, "home", "wrong")
+	if _, err := c.Execute(wpaclient.CmdSelectNetwork, id); err != nil {
+		t.Fatalf("SELECT_NETWORK failed, %v", err)
+	}
+
+	evs := events(t, ch, wpaclient.WpaEventDisconnected)
+	exp := []string{
+		"WPA: 4-Way Handshake failed - pre-shared key may be incorrect",
+		wpaclient.WpaEventTempDisabled + "id=" + id + ` ssid="home" auth_failures=1 duration=10 reason=WRONG_KEY`,
+		wpaclient.WpaEventDisconnected + "bssid=00:1f:1f:37:42:d9 reason=15 locally_generated=1",
+	}
+	if len(evs) < len(exp) || !reflect.DeepEqual(evs[len(evs)-len(exp):], exp) {
+		t.Errorf("expect events %v, got %v", exp, evs)
+	}
+
+	if s.State() != wpatest.StateDisconnected {
+		t.Errorf("expect state %s, got %s", wpatest.StateDisconnected, s.State())
+	}
+
+	res, err := c.Execute(wpaclient.CmdListNetworks)
+	if err != nil || !strings.Contains(string(res), id+"\thome\tany\t[TEMP-DISABLED]") {
+		t.Errorf("expect network %s to be listed temp disabled, got %q, %v", id, res, err)
+	}
+
+	if _, err := c.Execute(wpaclient.CmdEnableNetwork, id); err != nil {
+		t.Fatalf("ENABLE_NETWORK failed, %v", err)
+	}
+	if ns := s.Networks(); ns[0].TempDisabled {
+		t.Errorf("expect enabling to clear temp disabled state, got %#v", ns[0])
+	}
+
+	// hex key derived from the passphrase
+	id = addNetwork(t, c, "home", "secret")
+	psk := "251898dff699b57d6bb0b08a1d6f56bf42d9a1cfc8ddef5648c25e03739f2b41"
+	if _, err := c.Execute(wpaclient.CmdSetNetwork, id, "psk", psk); err != nil {
+		t.Fatalf("SET_NETWORK failed, %v", err)
+	}
+	if _, err := c.Execute(wpaclient.CmdSelectNetwork, id); err != nil {
+		t.Fatalf("SELECT_NETWORK failed, %v", err)
+	}
+	events(t, ch, wpaclient.WpaEventConnected)
+
+	// no AP for ssid
+	id = addNetwork(t, c, "other", "secret")
+	if _, err := c.Execute(wpaclient.CmdSelectNetwork, id); err != nil {
+		t.Fatalf("SELECT_NETWORK failed, %v", err)
+	}
+	events(t, ch, wpaclient.WpaEventNetworkNotFound)
+}
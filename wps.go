@@ -0,0 +1,273 @@
+package wpaclient
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// ErrWPSTimeout returned when "WPS-TIMEOUT" received, no registrar
+// was found in walk time
+var ErrWPSTimeout = constError("wps timed out")
+
+// ErrWPSOverlap returned when "WPS-OVERLAP-DETECTED" received,
+// more than one AP is in push button mode
+var ErrWPSOverlap = constError("wps push button overlap detected")
+
+// ErrWPSPinChecksum returned when a WPS PIN has an invalid checksum digit
+var ErrWPSPinChecksum = constError("wps pin checksum failed")
+
+// wpsConfigErrors are the names of WPS configuration errors
+var wpsConfigErrors = []string{"no error", "OOB interface read error", "decryption CRC failure",
+	"2.4 GHz channel not supported", "5 GHz channel not supported", "signal too weak",
+	"network authentication failure", "network association failure", "no DHCP response",
+	"failed DHCP config", "IP address conflict", "could not connect to registrar",
+	"multiple PBC sessions detected", "rogue activity suspected", "device busy", "setup locked",
+	"message timeout", "registration session timeout", "device password authentication failure",
+	"60 GHz channel not supported", "public key hash mismatch"}
+
+// WPSError represents "WPS-FAIL" event, Msg is the WPS message the
+// failure occurred at, like 8 for M8
+type WPSError struct {
+	Msg         int
+	ConfigError int
+	Reason      int
+	Text        string
+}
+
+func (we *WPSError) Error() string {
+	s := "wps failed at M" + strconv.Itoa(we.Msg)
+	if we.ConfigError > 0 && we.ConfigError < len(wpsConfigErrors) {
+		s += ": " + wpsConfigErrors[we.ConfigError]
+	} else if we.ConfigError > 0 {
+		s += ": config error " + strconv.Itoa(we.ConfigError)
+	}
+
+	if we.Text != "" {
+		s += " (" + we.Text + ")"
+	}
+
+	return s
+}
+
+// ParseWPSFail parses a "WPS-FAIL msg=8 config_error=15 reason=2 (Setup locked)" event
+func ParseWPSFail(ev Event) (*WPSError, error) {
+	if !strings.HasPrefix(ev.Message, WpsEventFail) {
+		return nil, fmt.Errorf("not a %s event", strings.TrimSpace(WpsEventFail))
+	}
+
+	msg := strings.TrimPrefix(ev.Message, WpsEventFail)
+	we := &WPSError{}
+	if i, j := strings.IndexByte(msg, '('), strings.LastIndexByte(msg, ')'); i >= 0 && j > i {
+		we.Text, msg = msg[i+1:j], msg[:i]
+	}
+
+	for k, v := range parseQuotedParams(msg) {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("parse %s: %w", k, err)
+		}
+
+		switch k {
+		case "msg":
+			we.Msg = n
+		case "config_error":
+			we.ConfigError = n
+		case "reason":
+			we.Reason = n
+		}
+	}
+
+	return we, nil
+}
+
+// WPSCredential is a network credential received with "WPS-CRED-RECEIVED" event,
+// reported if wps_cred_processing is enabled
+type WPSCredential struct {
+	SSID string
+	// AuthType and EncrType are WPS flags, like 0x0020 for WPA2-Personal and 0x0008 for AES
+	AuthType uint16
+	EncrType uint16
+	Key      Secret
+	MAC      net.HardwareAddr
+}
+
+// WPS attribute types of a credential
+const (
+	wpsAttrAuthType   = 0x1003
+	wpsAttrCred       = 0x100e
+	wpsAttrEncrType   = 0x100f
+	wpsAttrMACAddr    = 0x1020
+	wpsAttrNetworkKey = 0x1027
+	wpsAttrSSID       = 0x1045
+)
+
+// ParseWPSCredential parses a "WPS-CRED-RECEIVED" event
+func ParseWPSCredential(ev Event) (*WPSCredential, error) {
+	if !strings.HasPrefix(ev.Message, WpsEventCredReceived) {
+		return nil, fmt.Errorf("not a %s event", strings.TrimSpace(WpsEventCredReceived))
+	}
+
+	b, err := hex.DecodeString(strings.TrimSpace(strings.TrimPrefix(ev.Message, WpsEventCredReceived)))
+	if err != nil {
+		return nil, fmt.Errorf("parse credential: %w", err)
+	}
+
+	cred := &WPSCredential{}
+	for len(b) > 0 {
+		if len(b) < 4 {
+			return nil, fmt.Errorf("parse credential: short attribute")
+		}
+
+		t, l := binary.BigEndian.Uint16(b), int(binary.BigEndian.Uint16(b[2:]))
+		if len(b) < 4+l {
+			return nil, fmt.Errorf("parse credential: attribute %#04x too long", t)
+		}
+		v := b[4 : 4+l]
+		b = b[4+l:]
+
+		switch t {
+		case wpsAttrCred:
+			// credential attributes are nested in a credential attribute
+			b = v
+		case wpsAttrSSID:
+			cred.SSID = string(v)
+		case wpsAttrAuthType, wpsAttrEncrType:
+			if l != 2 {
+				return nil, fmt.Errorf("parse credential: attribute %#04x length %d", t, l)
+			}
+			if t == wpsAttrAuthType {
+				cred.AuthType = binary.BigEndian.Uint16(v)
+			} else {
+				cred.EncrType = binary.BigEndian.Uint16(v)
+			}
+		case wpsAttrNetworkKey:
+			cred.Key = Secret(v)
+		case wpsAttrMACAddr:
+			cred.MAC = net.HardwareAddr(append([]byte{}, v...))
+		}
+	}
+
+	return cred, nil
+}
+
+// WPSCheckPin executes "WPS_CHECK_PIN" command and returns the normalized pin,
+// ErrWPSPinChecksum is returned if the checksum digit is invalid
+func (c *Client) WPSCheckPin(pin Secret) (Secret, error) {
+	res, err := c.Execute(CmdWpsCheckPin, pin.Reveal())
+	if err != nil {
+		return "", err
+	}
+
+	s := strings.TrimSpace(string(res))
+	if s == "FAIL-CHECKSUM" {
+		return "", ErrWPSPinChecksum
+	}
+
+	return Secret(s), nil
+}
+
+// WPSGeneratePin executes "WPS_PIN get" command and returns a random pin
+// with a valid checksum, to be entered on the registrar before WPSPin
+func (c *Client) WPSGeneratePin() (Secret, error) {
+	res, err := c.Execute(CmdWpsPin, "get")
+	if err != nil {
+		return "", err
+	}
+
+	return Secret(strings.TrimSpace(string(res))), nil
+}
+
+// WPSPushButton executes "WPS_PBC" command, starts a push button session with
+// AP bssid, or any AP in push button mode if bssid is empty. It waits for the
+// session to finish and the interface to connect, and returns the id of the
+// network added by WPS. The session is canceled when ctx is done.
+func (c *Client) WPSPushButton(ctx context.Context, bssid string) (int, error) {
+	return c.wps(ctx, CmdWpsPbc, orDefault(bssid, "any"))
+}
+
+// WPSPin executes "WPS_PIN" command, starts a PIN session with AP bssid,
+// or any AP if bssid is empty, pin is validated by WPSCheckPin first.
+// See WPSPushButton.
+func (c *Client) WPSPin(ctx context.Context, bssid string, pin Secret) (int, error) {
+	pin, err := c.WPSCheckPin(pin)
+	if err != nil {
+		return 0, err
+	}
+
+	return c.wps(ctx, CmdWpsPin, orDefault(bssid, "any"), pin.Reveal())
+}
+
+// wps executes cmd and waits for the WPS result and the connection
+func (c *Client) wps(ctx context.Context, cmd string, args ...string) (int, error) {
+	ch, err := c.Notify(WpsEventSuccess, WpsEventFail, WpsEventTimeout, WpsEventOverlap, WpaEventConnected)
+	if err != nil {
+		return 0, err
+	}
+	defer c.Stop(ch)
+
+	if _, err := c.Execute(cmd, args...); err != nil {
+		return 0, err
+	}
+
+	success := false
+	for {
+		select {
+		case <-ctx.Done():
+			c.Execute(CmdWpsCancel)
+			return 0, ctx.Err()
+		case ev, ok := <-ch:
+			if !ok {
+				return 0, fmt.Errorf("event channel closed")
+			}
+
+			switch {
+			case strings.HasPrefix(ev.Message, WpsEventSuccess):
+				success = true
+			case strings.HasPrefix(ev.Message, WpsEventFail):
+				c.Execute(CmdWpsCancel)
+				we, err := ParseWPSFail(ev)
+				if err != nil {
+					return 0, err
+				}
+				return 0, we
+			case strings.HasPrefix(ev.Message, WpsEventTimeout):
+				return 0, ErrWPSTimeout
+			case strings.HasPrefix(ev.Message, WpsEventOverlap):
+				c.Execute(CmdWpsCancel)
+				return 0, ErrWPSOverlap
+			case strings.HasPrefix(ev.Message, WpaEventConnected) && success:
+				if id, ok := connectedID(ev.Message); ok {
+					return id, nil
+				}
+
+				st, err := c.Status()
+				if err != nil {
+					return 0, err
+				}
+				return st.ID, nil
+			}
+		}
+	}
+}
+
+// connectedID returns the network id of a "CTRL-EVENT-CONNECTED" event,
+// "- Connection to 00:1f:1f:37:42:d9 completed [id=0 id_str=]"
+func connectedID(msg string) (int, bool) {
+	i := strings.Index(msg, "[id=")
+	if i < 0 {
+		return 0, false
+	}
+
+	s := msg[i+len("[id="):]
+	if j := strings.IndexAny(s, " ]"); j >= 0 {
+		s = s[:j]
+	}
+
+	id, err := strconv.Atoi(s)
+	return id, err == nil
+}
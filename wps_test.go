@@ -0,0 +1,156 @@
+package wpaclient
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWPS(t *testing.T) {
+	ts, cleanup := newTestServer(t)
+	defer cleanup()
+
+	for _, cmd := range []string{CmdWpsPbc, CmdWpsPin, CmdWpsCancel} {
+		ts.setCmd(cmd, "OK")
+	}
+	ts.setCmd(CmdWpsCheckPin, "12345670")
+	ts.setCmd(CmdStatus, "wpa_state=COMPLETED\nid=3")
+
+	c, err := New(ts.addr())
+	if err != nil {
+		t.Fatalf("New Client failed, %v", err)
+	}
+	defer c.Close()
+
+	pbc := func(ctx context.Context) (int, error) { return c.WPSPushButton(ctx, "") }
+	pin := func(ctx context.Context) (int, error) { return c.WPSPin(ctx, "00:1f:1f:37:42:d9", "1234-5670") }
+
+	tests := []struct {
+		name    string
+		fn      func(context.Context) (int, error)
+		started string
+		events  []string
+		id      int
+		err     error
+		last    string
+	}{
+		{name: "pbc", fn: pbc, started: CmdWpsPbc + " any",
+			events: []string{WpsEventSuccess, WpaEventConnected + "- Connection to 00:1f:1f:37:42:d9 completed [id=2 id_str=]"},
+			id:     2},
+		{name: "connected before success", fn: pbc, started: CmdWpsPbc + " any",
+			events: []string{WpaEventConnected + "- Connection to 00:1f:1f:37:42:d9 completed [id=0 id_str=]",
+				WpsEventSuccess, WpaEventConnected + "- Connection to 00:1f:1f:37:42:d9 completed"},
+			id: 3},
+		{name: "pin fail", fn: pin, started: CmdWpsPin + " 00:1f:1f:37:42:d9 12345670",
+			events: []string{WpsEventFail + "msg=8 config_error=18"},
+			err:    &WPSError{Msg: 8, ConfigError: 18}, last: CmdWpsCancel},
+		{name: "timeout", fn: pbc, started: CmdWpsPbc + " any",
+			events: []string{WpsEventTimeout + "Requested operation timed out"}, err: ErrWPSTimeout},
+		{name: "overlap", fn: pbc, started: CmdWpsPbc + " any",
+			events: []string{WpsEventOverlap + "PBC session overlap"}, err: ErrWPSOverlap, last: CmdWpsCancel},
+		{name: "canceled", fn: pbc, started: CmdWpsPbc + " any", err: context.Canceled, last: CmdWpsCancel},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts.mut.Lock()
+			ts.last = ""
+			ts.mut.Unlock()
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			type result struct {
+				id  int
+				err error
+			}
+			res := make(chan result, 1)
+			go func() {
+				id, err := tt.fn(ctx)
+				res <- result{id, err}
+			}()
+
+			for i := 0; i < 100 && ts.lastCmd() != tt.started; i++ {
+				time.Sleep(time.Millisecond * 10)
+			}
+			if ts.lastCmd() != tt.started {
+				t.Fatalf("expected %s to be sent, got %s", tt.started, ts.lastCmd())
+			}
+
+			if len(tt.events) == 0 {
+				cancel()
+			}
+			ts.sendMsg(3, tt.events...)
+
+			var r result
+			select {
+			case r = <-res:
+			case <-time.After(time.Second):
+				t.Fatal("wps not finished")
+			}
+
+			if r.id != tt.id || !reflect.DeepEqual(r.err, tt.err) {
+				t.Errorf("expected %d, %v, got %d, %v", tt.id, tt.err, r.id, r.err)
+			}
+
+			if tt.last != "" && ts.lastCmd() != tt.last {
+				t.Errorf("expected %s to be sent last, got %s", tt.last, ts.lastCmd())
+			}
+		})
+	}
+
+	ts.setCmd(CmdWpsCheckPin, "FAIL-CHECKSUM")
+	if _, err := c.WPSPin(context.Background(), "", "12345678"); !errors.Is(err, ErrWPSPinChecksum) {
+		t.Errorf("WPSPin expect error %v, got %v", ErrWPSPinChecksum, err)
+	}
+}
+
+func TestParseWPSFail(t *testing.T) {
+	we, err := ParseWPSFail(Event{Message: WpsEventFail + "msg=8 config_error=15 reason=2 (Setup locked)"})
+	if err != nil {
+		t.Fatalf("ParseWPSFail not expect an error, got %v", err)
+	}
+
+	exp := &WPSError{Msg: 8, ConfigError: 15, Reason: 2, Text: "Setup locked"}
+	if !reflect.DeepEqual(we, exp) {
+		t.Errorf("expected %+v, got %+v", exp, we)
+	}
+
+	if s := we.Error(); s != "wps failed at M8: setup locked (Setup locked)" {
+		t.Errorf("unexpected error message %s", s)
+	}
+
+	if _, err := ParseWPSFail(Event{Message: WpsEventFail + "msg=x"}); err == nil {
+		t.Error("ParseWPSFail expect an error")
+	}
+}
+
+func TestParseWPSCredential(t *testing.T) {
+	attrs := "1026000101" + // network index
+		"10450004686f6d65" + // ssid
+		"100300020020" + // auth type
+		"100f00020008" + // encr type
+		"10270006736563726574" + // network key
+		"10200006021f1f3742d9" // mac address
+	cred := hex.EncodeToString([]byte{0x10, 0x0e, 0, byte(len(attrs) / 2)}) + attrs
+
+	c, err := ParseWPSCredential(Event{Message: WpsEventCredReceived + cred})
+	if err != nil {
+		t.Fatalf("ParseWPSCredential not expect an error, got %v", err)
+	}
+
+	if c.SSID != "home" || c.AuthType != 0x20 || c.EncrType != 0x08 || c.Key.Reveal() != "secret" ||
+		c.MAC.String() != "02:1f:1f:37:42:d9" {
+		t.Errorf("unexpected credential %+v", c)
+	}
+
+	if _, err := ParseWPSCredential(Event{Message: WpsEventCredReceived + "10450010"}); err == nil {
+		t.Error("ParseWPSCredential expect an error for truncated attribute")
+	}
+
+	if s := redactEvent("<3>" + WpsEventCredReceived + cred); s != "<3>"+WpsEventCredReceived+Redacted {
+		t.Errorf("expected credential to be redacted, got %s", s)
+	}
+}
@@ -0,0 +1,380 @@
+package wpaclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ERAP is an AP discovered by ExternalRegistrar with "WPS-ER-AP-ADD" event
+type ERAP struct {
+	UUID             string
+	MAC              net.HardwareAddr
+	PriDevType       string
+	WPSState         int
+	FriendlyName     string
+	Manufacturer     string
+	ModelDescription string
+	ModelName        string
+	ModelNumber      string
+	SerialNumber     string
+}
+
+// EREnrollee is an enrollee discovered by ExternalRegistrar with "WPS-ER-ENROLLEE-ADD" event
+type EREnrollee struct {
+	UUID          string
+	MAC           net.HardwareAddr
+	M1            bool
+	ConfigMethods uint16
+	DevPasswdID   int
+	PriDevType    string
+	DevName       string
+	Manufacturer  string
+	ModelName     string
+	ModelNumber   string
+	SerialNumber  string
+}
+
+// WPSSettings are the network settings of an AP. Auth is one of OPEN, WPAPSK
+// and WPA2PSK, Encr is one of NONE, WEP, TKIP and CCMP.
+type WPSSettings struct {
+	SSID string
+	Auth string
+	Encr string
+	Key  Secret
+}
+
+// ExternalRegistrar is a WPS External Registrar, it provisions APs and
+// authorizes enrollees over UPnP. APs and enrollees are tracked from events.
+type ExternalRegistrar struct {
+	c  *Client
+	ch <-chan Event
+
+	mut       sync.Mutex
+	aps       map[string]ERAP
+	enrollees map[string]EREnrollee
+	done      chan struct{}
+}
+
+// NewExternalRegistrar executes "WPS_ER_START" command and starts tracking
+// discovered APs and enrollees, UPnP is used on the interface of ip if not empty
+func NewExternalRegistrar(c *Client, ip string) (*ExternalRegistrar, error) {
+	ch, err := c.Notify(WpsEventErApAdd, WpsEventErApRemove, WpsEventErEnrolleeAdd, WpsEventErEnrolleeRemove)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{}
+	if ip != "" {
+		args = append(args, ip)
+	}
+
+	if _, err := c.Execute(CmdWpsErStart, args...); err != nil {
+		c.Stop(ch)
+		return nil, err
+	}
+
+	er := &ExternalRegistrar{c: c, ch: ch, aps: map[string]ERAP{},
+		enrollees: map[string]EREnrollee{}, done: make(chan struct{})}
+	go er.track()
+
+	return er, nil
+}
+
+// Close executes "WPS_ER_STOP" command and stops tracking
+func (er *ExternalRegistrar) Close() error {
+	er.c.Stop(er.ch)
+	<-er.done
+
+	_, err := er.c.Execute(CmdWpsErStop)
+	return err
+}
+
+func (er *ExternalRegistrar) track() {
+	defer close(er.done)
+
+	for ev := range er.ch {
+		er.event(ev)
+	}
+}
+
+func (er *ExternalRegistrar) event(ev Event) {
+	er.mut.Lock()
+	defer er.mut.Unlock()
+
+	switch {
+	case strings.HasPrefix(ev.Message, WpsEventErApAdd):
+		if ap, err := parseERAP(strings.TrimPrefix(ev.Message, WpsEventErApAdd)); err == nil {
+			er.aps[ap.UUID] = *ap
+		}
+	case strings.HasPrefix(ev.Message, WpsEventErApRemove):
+		delete(er.aps, strings.TrimSpace(strings.TrimPrefix(ev.Message, WpsEventErApRemove)))
+	case strings.HasPrefix(ev.Message, WpsEventErEnrolleeAdd):
+		if en, err := parseEREnrollee(strings.TrimPrefix(ev.Message, WpsEventErEnrolleeAdd)); err == nil {
+			er.enrollees[en.UUID] = *en
+		}
+	case strings.HasPrefix(ev.Message, WpsEventErEnrolleeRemove):
+		if fs := strings.Fields(strings.TrimPrefix(ev.Message, WpsEventErEnrolleeRemove)); len(fs) > 0 {
+			delete(er.enrollees, fs[0])
+		}
+	}
+}
+
+// APs returns discovered APs sorted by UUID
+func (er *ExternalRegistrar) APs() []ERAP {
+	er.mut.Lock()
+	defer er.mut.Unlock()
+
+	aps := []ERAP{}
+	for _, ap := range er.aps {
+		aps = append(aps, ap)
+	}
+	sort.Slice(aps, func(i, j int) bool { return aps[i].UUID < aps[j].UUID })
+
+	return aps
+}
+
+// Enrollees returns discovered enrollees sorted by UUID
+func (er *ExternalRegistrar) Enrollees() []EREnrollee {
+	er.mut.Lock()
+	defer er.mut.Unlock()
+
+	ens := []EREnrollee{}
+	for _, en := range er.enrollees {
+		ens = append(ens, en)
+	}
+	sort.Slice(ens, func(i, j int) bool { return ens[i].UUID < ens[j].UUID })
+
+	return ens
+}
+
+// uuid returns the UUID of ap given by UUID or BSSID, a BSSID must be of a discovered AP
+func (er *ExternalRegistrar) uuid(ap string) (string, error) {
+	mac, err := net.ParseMAC(ap)
+	if err != nil {
+		return ap, nil
+	}
+
+	er.mut.Lock()
+	defer er.mut.Unlock()
+
+	for _, a := range er.aps {
+		if bytes.Equal(a.MAC, mac) {
+			return a.UUID, nil
+		}
+	}
+
+	return "", fmt.Errorf("ap %s not discovered", ap)
+}
+
+// Learn executes "WPS_ER_LEARN" command, learns the settings of ap given by
+// UUID or BSSID using its AP PIN and waits for them until ctx is done.
+// A BSSID must be of an AP in APs, the settings are reported by UUID.
+func (er *ExternalRegistrar) Learn(ctx context.Context, ap string, pin Secret) (*WPSSettings, error) {
+	ch, err := er.c.Notify(WpsEventErApSettings, WpsEventFail)
+	if err != nil {
+		return nil, err
+	}
+	defer er.c.Stop(ch)
+
+	uuid, err := er.uuid(ap)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := er.c.Execute(CmdWpsErLearn, ap, pin.Reveal()); err != nil {
+		return nil, err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case ev, ok := <-ch:
+			if !ok {
+				return nil, errors.New("event channel closed")
+			}
+
+			if strings.HasPrefix(ev.Message, WpsEventFail) {
+				we, err := ParseWPSFail(ev)
+				if err != nil {
+					return nil, err
+				}
+				return nil, we
+			}
+
+			id, st, err := parseERAPSettings(strings.TrimPrefix(ev.Message, WpsEventErApSettings))
+			if err != nil {
+				return nil, err
+			}
+			if id == uuid {
+				return st, nil
+			}
+		}
+	}
+}
+
+// Config executes "WPS_ER_CONFIG" command, configures ap given by UUID or BSSID
+// with settings using its AP PIN
+func (er *ExternalRegistrar) Config(ap string, pin Secret, settings WPSSettings) error {
+	_, err := er.c.Execute(CmdWpsErConfig, ap, pin.Reveal(), hex.EncodeToString([]byte(settings.SSID)),
+		settings.Auth, settings.Encr, hex.EncodeToString([]byte(settings.Key.Reveal())))
+	return err
+}
+
+// SetConfig executes "WPS_ER_SET_CONFIG" command, ap given by UUID or BSSID
+// is configured with the settings of network id when it is learned
+func (er *ExternalRegistrar) SetConfig(ap string, id int) error {
+	_, err := er.c.Execute(CmdWpsErSetConfig, ap, strconv.Itoa(id))
+	return err
+}
+
+// AuthorizeEnrollee executes "WPS_ER_PIN" command, authorizes enrollee uuid
+// to join with pin, any enrollee if uuid is empty
+func (er *ExternalRegistrar) AuthorizeEnrollee(uuid string, pin Secret) error {
+	_, err := er.c.Execute(CmdWpsErPin, orDefault(uuid, "any"), pin.Reveal())
+	return err
+}
+
+// PushButton executes "WPS_ER_PBC" command, enrollee given by UUID or MAC address
+// is allowed to join with push button method
+func (er *ExternalRegistrar) PushButton(enrollee string) error {
+	_, err := er.c.Execute(CmdWpsErPbc, enrollee)
+	return err
+}
+
+// parseERAP parses "<uuid> <mac> pri_dev_type=<type> wps_state=<n> |<friendly name>|
+// <manufacturer>|<model description>|<model name>|<model number>|<serial number>|"
+func parseERAP(msg string) (*ERAP, error) {
+	head, ds := splitDescription(msg)
+	fs := strings.Fields(head)
+	if len(fs) < 2 {
+		return nil, fmt.Errorf("parse ap: %s", msg)
+	}
+
+	mac, err := net.ParseMAC(fs[1])
+	if err != nil {
+		return nil, fmt.Errorf("parse mac: %w", err)
+	}
+
+	ps := parseQuotedParams(strings.Join(fs[2:], " "))
+	ap := &ERAP{UUID: fs[0], MAC: mac, PriDevType: ps["pri_dev_type"]}
+	if s, ok := ps["wps_state"]; ok {
+		if ap.WPSState, err = strconv.Atoi(s); err != nil {
+			return nil, fmt.Errorf("parse wps_state: %w", err)
+		}
+	}
+
+	ds = append(ds, make([]string, 6)...)
+	ap.FriendlyName, ap.Manufacturer, ap.ModelDescription = ds[0], ds[1], ds[2]
+	ap.ModelName, ap.ModelNumber, ap.SerialNumber = ds[3], ds[4], ds[5]
+
+	return ap, nil
+}
+
+// parseEREnrollee parses "<uuid> <mac> M1=<0|1> config_methods=0x<n> dev_passwd_id=<n>
+// pri_dev_type=<type> |<dev name>|<manufacturer>|<model name>|<model number>|<serial number>|"
+func parseEREnrollee(msg string) (*EREnrollee, error) {
+	head, ds := splitDescription(msg)
+	fs := strings.Fields(head)
+	if len(fs) < 2 {
+		return nil, fmt.Errorf("parse enrollee: %s", msg)
+	}
+
+	mac, err := net.ParseMAC(fs[1])
+	if err != nil {
+		return nil, fmt.Errorf("parse mac: %w", err)
+	}
+
+	ps := parseQuotedParams(strings.Join(fs[2:], " "))
+	en := &EREnrollee{UUID: fs[0], MAC: mac, M1: ps["M1"] == "1", PriDevType: ps["pri_dev_type"]}
+	if s, ok := ps["config_methods"]; ok {
+		cm, err := strconv.ParseUint(s, 0, 16)
+		if err != nil {
+			return nil, fmt.Errorf("parse config_methods: %w", err)
+		}
+		en.ConfigMethods = uint16(cm)
+	}
+	if s, ok := ps["dev_passwd_id"]; ok {
+		if en.DevPasswdID, err = strconv.Atoi(s); err != nil {
+			return nil, fmt.Errorf("parse dev_passwd_id: %w", err)
+		}
+	}
+
+	ds = append(ds, make([]string, 5)...)
+	en.DevName, en.Manufacturer, en.ModelName, en.ModelNumber, en.SerialNumber = ds[0], ds[1], ds[2], ds[3], ds[4]
+
+	return en, nil
+}
+
+// splitDescription splits "<params> |a|b|c|" into params and descriptions
+func splitDescription(msg string) (string, []string) {
+	i := strings.IndexByte(msg, '|')
+	if i < 0 {
+		return msg, nil
+	}
+
+	ds := strings.Split(strings.TrimSuffix(msg[i+1:], "|"), "|")
+	return msg[:i], ds
+}
+
+// wpsAuthTypes and wpsEncrTypes are WPS flags by name, stronger first
+var (
+	wpsAuthTypes = []struct {
+		flag uint16
+		name string
+	}{{0x0020, "WPA2PSK"}, {0x0002, "WPAPSK"}, {0x0001, "OPEN"}}
+	wpsEncrTypes = []struct {
+		flag uint16
+		name string
+	}{{0x0008, "CCMP"}, {0x0004, "TKIP"}, {0x0002, "WEP"}, {0x0001, "NONE"}}
+)
+
+// parseERAPSettings parses "uuid=<uuid> ssid=<ssid> auth_type=0x<n> encr_type=0x<n> key=<hex>",
+// ssid can hold spaces
+func parseERAPSettings(msg string) (string, *WPSSettings, error) {
+	i, j := strings.Index(msg, " ssid="), strings.Index(msg, " auth_type=")
+	if !strings.HasPrefix(msg, "uuid=") || i < 0 || j < i {
+		return "", nil, fmt.Errorf("parse ap settings: %s", msg)
+	}
+
+	uuid, st := msg[len("uuid="):i], &WPSSettings{SSID: msg[i+len(" ssid=") : j]}
+	ps := parseQuotedParams(msg[j:])
+
+	auth, err := strconv.ParseUint(ps["auth_type"], 0, 16)
+	if err != nil {
+		return "", nil, fmt.Errorf("parse auth_type: %w", err)
+	}
+	for _, t := range wpsAuthTypes {
+		if uint16(auth)&t.flag != 0 {
+			st.Auth = t.name
+			break
+		}
+	}
+
+	encr, err := strconv.ParseUint(ps["encr_type"], 0, 16)
+	if err != nil {
+		return "", nil, fmt.Errorf("parse encr_type: %w", err)
+	}
+	for _, t := range wpsEncrTypes {
+		if uint16(encr)&t.flag != 0 {
+			st.Encr = t.name
+			break
+		}
+	}
+
+	key, err := hex.DecodeString(ps["key"])
+	if err != nil {
+		return "", nil, fmt.Errorf("parse key: %w", err)
+	}
+	st.Key = Secret(key)
+
+	return uuid, st, nil
+}
@@ -0,0 +1,163 @@
+package wpaclient
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestExternalRegistrar(t *testing.T) {
+	ts, cleanup := newTestServer(t)
+	defer cleanup()
+
+	for _, cmd := range []string{CmdWpsErStart, CmdWpsErStop, CmdWpsErLearn, CmdWpsErConfig,
+		CmdWpsErSetConfig, CmdWpsErPin, CmdWpsErPbc} {
+		ts.setCmd(cmd, "OK")
+	}
+
+	c, err := New(ts.addr())
+	if err != nil {
+		t.Fatalf("New Client failed, %v", err)
+	}
+	defer c.Close()
+
+	er, err := NewExternalRegistrar(c, "")
+	if err != nil {
+		t.Fatalf("NewExternalRegistrar not expect an error, got %v", err)
+	}
+
+	apUUID := "a0b1c2d3-0000-1111-2222-333344445555"
+	enUUID := "e0e1e2e3-0000-1111-2222-333344445555"
+	ts.sendMsg(3,
+		WpsEventErApAdd+apUUID+" 00:1f:1f:37:42:d9 pri_dev_type=6-0050F204-1 wps_state=2 |Home AP|ACME|Router|R1|1.0|1234|",
+		WpsEventErApAdd+"b0b1b2b3-0000-1111-2222-333344445555 00:1f:1f:37:42:da pri_dev_type=6-0050F204-1 wps_state=1 |||||||",
+		WpsEventErEnrolleeAdd+enUUID+" 02:00:00:00:00:01 M1=1 config_methods=0x2388 dev_passwd_id=0 pri_dev_type=10-0050F204-5 |Printer|ACME|P1|2.0|5678|",
+		WpsEventErApRemove+"b0b1b2b3-0000-1111-2222-333344445555",
+	)
+
+	mac, _ := net.ParseMAC("00:1f:1f:37:42:d9")
+	expAPs := []ERAP{{UUID: apUUID, MAC: mac, PriDevType: "6-0050F204-1", WPSState: 2, FriendlyName: "Home AP",
+		Manufacturer: "ACME", ModelDescription: "Router", ModelName: "R1", ModelNumber: "1.0", SerialNumber: "1234"}}
+	for i := 0; i < 100 && !reflect.DeepEqual(er.APs(), expAPs); i++ {
+		time.Sleep(time.Millisecond * 10)
+	}
+	if aps := er.APs(); !reflect.DeepEqual(aps, expAPs) {
+		t.Errorf("expected aps %+v, got %+v", expAPs, aps)
+	}
+
+	mac, _ = net.ParseMAC("02:00:00:00:00:01")
+	expEns := []EREnrollee{{UUID: enUUID, MAC: mac, M1: true, ConfigMethods: 0x2388, PriDevType: "10-0050F204-5",
+		DevName: "Printer", Manufacturer: "ACME", ModelName: "P1", ModelNumber: "2.0", SerialNumber: "5678"}}
+	if ens := er.Enrollees(); !reflect.DeepEqual(ens, expEns) {
+		t.Errorf("expected enrollees %+v, got %+v", expEns, ens)
+	}
+
+	tests := []struct {
+		name string
+		fn   func() error
+		last string
+	}{
+		{name: "config", fn: func() error {
+			return er.Config(apUUID, "12345670", WPSSettings{SSID: "home", Auth: "WPA2PSK", Encr: "CCMP", Key: "secret"})
+		}, last: CmdWpsErConfig + " " + apUUID + " 12345670 686f6d65 WPA2PSK CCMP 736563726574"},
+		{name: "set config", fn: func() error { return er.SetConfig("00:1f:1f:37:42:d9", 2) },
+			last: CmdWpsErSetConfig + " 00:1f:1f:37:42:d9 2"},
+		{name: "authorize", fn: func() error { return er.AuthorizeEnrollee(enUUID, "12345670") },
+			last: CmdWpsErPin + " " + enUUID + " 12345670"},
+		{name: "authorize any", fn: func() error { return er.AuthorizeEnrollee("", "12345670") },
+			last: CmdWpsErPin + " any 12345670"},
+		{name: "push button", fn: func() error { return er.PushButton(enUUID) },
+			last: CmdWpsErPbc + " " + enUUID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.fn(); err != nil {
+				t.Fatalf("%s not expect an error, got %v", tt.name, err)
+			}
+			if ts.lastCmd() != tt.last {
+				t.Errorf("expected %s to be sent, got %s", tt.last, ts.lastCmd())
+			}
+		})
+	}
+
+	t.Run("learn", func(t *testing.T) {
+		ts.mut.Lock()
+		ts.last = ""
+		ts.mut.Unlock()
+		type result struct {
+			st  *WPSSettings
+			err error
+		}
+		res := make(chan result, 1)
+		go func() {
+			st, err := er.Learn(context.Background(), "00:1f:1f:37:42:d9", "12345670")
+			res <- result{st, err}
+		}()
+
+		started := CmdWpsErLearn + " 00:1f:1f:37:42:d9 12345670"
+		for i := 0; i < 100 && ts.lastCmd() != started; i++ {
+			time.Sleep(time.Millisecond * 10)
+		}
+		if ts.lastCmd() != started {
+			t.Fatalf("expected %s to be sent, got %s", started, ts.lastCmd())
+		}
+
+		ts.sendMsg(3,
+			WpsEventErApSettings+"uuid=other ssid=x auth_type=0x0001 encr_type=0x0001 key=",
+			WpsEventErApSettings+"uuid="+apUUID+" ssid=home net auth_type=0x0022 encr_type=0x000c key=736563726574")
+
+		var r result
+		select {
+		case r = <-res:
+		case <-time.After(time.Second):
+			t.Fatal("learn not finished")
+		}
+
+		exp := &WPSSettings{SSID: "home net", Auth: "WPA2PSK", Encr: "CCMP", Key: "secret"}
+		if r.err != nil || !reflect.DeepEqual(r.st, exp) {
+			t.Errorf("expected %+v, got %+v, %v", exp, r.st, r.err)
+		}
+	})
+
+	t.Run("learn unknown ap", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		if _, err := er.Learn(ctx, "02:00:00:00:00:09", "12345670"); err == nil || err == ctx.Err() {
+			t.Errorf("Learn expect an error, got %v", err)
+		}
+	})
+
+	if err := er.Close(); err != nil {
+		t.Errorf("Close not expect an error, got %v", err)
+	}
+	if ts.lastCmd() != CmdWpsErStop {
+		t.Errorf("expected %s to be sent, got %s", CmdWpsErStop, ts.lastCmd())
+	}
+}
+
+func TestRedactWPSER(t *testing.T) {
+	tests := []struct {
+		cmd  string
+		args []string
+		exp  []string
+	}{
+		{CmdWpsErLearn, []string{"00:1f:1f:37:42:d9", "12345670"}, []string{"00:1f:1f:37:42:d9", Redacted}},
+		{CmdWpsErConfig, []string{"00:1f:1f:37:42:d9", "12345670", "686f6d65", "WPA2PSK", "CCMP", "736563726574"},
+			[]string{"00:1f:1f:37:42:d9", Redacted}},
+	}
+
+	for _, tt := range tests {
+		if _, args := redact(tt.cmd, tt.args); !reflect.DeepEqual(args, tt.exp) {
+			t.Errorf("%s expected %v, got %v", tt.cmd, tt.exp, args)
+		}
+	}
+
+	ev := WpsEventErApSettings + "uuid=x ssid=home auth_type=0x0020 encr_type=0x0008 key=736563726574"
+	if s := redactEvent(ev); s != WpsEventErApSettings+Redacted {
+		t.Errorf("unexpected redacted event %s", s)
+	}
+}